@@ -143,7 +143,7 @@ func devEngineContainer() *Container {
 		WithExec([]string{
 			"apk", "add",
 			// for Buildkit
-			"git", "openssh", "pigz", "xz",
+			"git", "git-lfs", "openssh", "pigz", "xz",
 			// for CNI
 			"iptables", "ip6tables", "dnsmasq",
 		}).
@@ -2,15 +2,52 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	loadTag     string
+	searchQuery string
+	searchRegex bool
+)
+
 var callCmd = &FuncCommand{
 	Name:  "call",
 	Short: "Call a module function",
-	Long:  "Call a module function and print the result.\n\nOn a container, the stdout will be returned. On a directory, the list of entries, and on a file, its contents.",
+	Long:  "Call a module function and print the result.\n\nOn a container, the stdout will be returned. On a directory, the list of entries, or the lines matching --search, and on a file, its contents.",
+	Init: func(cmd *cobra.Command) {
+		cmd.PersistentFlags().StringVar(&loadTag, "load", "", "Load a returned container into the local Docker daemon with the given tag")
+		cmd.PersistentFlags().StringVar(&searchQuery, "search", "", "Print the lines of a returned directory's files matching the given string, like grep")
+		cmd.PersistentFlags().BoolVar(&searchRegex, "search-regex", false, "Treat the --search pattern as an RE2 regular expression")
+	},
+	Watchable: true,
 	OnSelectObjectLeaf: func(c *FuncCommand, name string) error {
+		if loadTag != "" {
+			if name != Container {
+				return fmt.Errorf("--load can only be used on a container")
+			}
+			sockPath, err := dockerSocketPath()
+			if err != nil {
+				return err
+			}
+			c.Select("exportToDocker")
+			c.Arg("tag", loadTag)
+			c.Arg("socket", c.c.Dagger().Host().UnixSocket(sockPath))
+			return nil
+		}
+		if searchQuery != "" {
+			if name != Directory {
+				return fmt.Errorf("--search can only be used on a directory")
+			}
+			c.Select("search")
+			c.Arg("pattern", searchQuery)
+			c.Arg("regex", searchRegex)
+			c.SelectFields("path", "lineNumber", "line")
+			return nil
+		}
 		switch name {
 		case Container:
 			// TODO: Combined `output` in the API. Querybuilder
@@ -27,6 +64,9 @@ var callCmd = &FuncCommand{
 		return nil
 	},
 	AfterResponse: func(_ *FuncCommand, cmd *cobra.Command, _ *modTypeDef, response any) error {
+		if searchQuery != "" {
+			return printSearchMatches(cmd, response)
+		}
 		return printResponse(cmd, response)
 	},
 }
@@ -52,3 +92,34 @@ func printResponse(cmd *cobra.Command, r any) error {
 	}
 	return nil
 }
+
+// printSearchMatches prints the lines matching --search like grep does, as
+// path:line:text.
+func printSearchMatches(cmd *cobra.Command, r any) error {
+	matches, ok := r.([]any)
+	if !ok {
+		return fmt.Errorf("unexpected response %T: %+v", r, r)
+	}
+	for _, m := range matches {
+		match, ok := m.(map[string]any)
+		if !ok {
+			return fmt.Errorf("unexpected match %T: %+v", m, m)
+		}
+		cmd.Printf("%v:%v:%v\n", match["path"], match["lineNumber"], match["line"])
+	}
+	return nil
+}
+
+// dockerSocketPath returns the path to the local Docker daemon's socket,
+// honoring DOCKER_HOST.
+func dockerSocketPath() (string, error) {
+	dockerHost := os.Getenv("DOCKER_HOST")
+	if dockerHost == "" {
+		return "/var/run/docker.sock", nil
+	}
+	sockPath, ok := strings.CutPrefix(dockerHost, "unix://")
+	if !ok {
+		return "", fmt.Errorf("--load requires DOCKER_HOST to be a unix socket, got %q", dockerHost)
+	}
+	return sockPath, nil
+}
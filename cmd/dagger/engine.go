@@ -59,18 +59,10 @@ func withEngineAndTUI(
 	params client.Params,
 	fn runClientCallback,
 ) error {
-	if params.RunnerHost == "" {
-		params.RunnerHost = engine.RunnerHost()
-	}
-
-	params.DisableHostRW = disableHostRW
-
-	if params.JournalFile == "" {
-		params.JournalFile = os.Getenv("_EXPERIMENTAL_DAGGER_JOURNAL")
-	}
+	params = defaultEngineParams(params)
 
 	if !silent {
-		if progress == "auto" && autoTTY || progress == "tty" {
+		if useTTY() {
 			if interactive {
 				return interactiveTUI(ctx, params, fn)
 			}
@@ -104,6 +96,24 @@ func withEngineAndTUI(
 	return fn(ctx, engineClient)
 }
 
+func defaultEngineParams(params client.Params) client.Params {
+	if params.RunnerHost == "" {
+		params.RunnerHost = engine.RunnerHost()
+	}
+
+	params.DisableHostRW = disableHostRW
+
+	if params.JournalFile == "" {
+		params.JournalFile = os.Getenv("_EXPERIMENTAL_DAGGER_JOURNAL")
+	}
+
+	return params
+}
+
+func useTTY() bool {
+	return progress == "auto" && autoTTY || progress == "tty"
+}
+
 func progrockTee(progW progrock.Writer) (progrock.Writer, error) {
 	if log := os.Getenv("_EXPERIMENTAL_DAGGER_PROGROCK_JOURNAL"); log != "" {
 		fileW, err := newProgrockWriter(log)
@@ -160,14 +170,7 @@ func inlineTUI(
 	params client.Params,
 	fn runClientCallback,
 ) error {
-	tape := progrock.NewTape()
-	tape.ShowInternal(debug)
-	tape.Focus(focus)
-	tape.RevealErrored(revealErrored)
-
-	if debug {
-		tape.MessageLevel(progrock.MessageLevel_DEBUG)
-	}
+	tape := newTape()
 
 	progW, engineErr := progrockTee(tape)
 	if engineErr != nil {
@@ -202,6 +205,19 @@ func inlineTUI(
 	})
 }
 
+func newTape() *progrock.Tape {
+	tape := progrock.NewTape()
+	tape.ShowInternal(debug)
+	tape.Focus(focus)
+	tape.RevealErrored(revealErrored)
+
+	if debug {
+		tape.MessageLevel(progrock.MessageLevel_DEBUG)
+	}
+
+	return tape
+}
+
 func newProgrockWriter(dest string) (progrock.Writer, error) {
 	f, err := os.Create(dest)
 	if err != nil {
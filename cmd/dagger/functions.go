@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"dagger.io/dagger"
 	"dagger.io/dagger/querybuilder"
@@ -198,6 +199,13 @@ type FuncCommand struct {
 	// AfterResponse is called when the query has completed and returned a result.
 	AfterResponse func(*FuncCommand, *cobra.Command, *modTypeDef, any) error
 
+	// Watchable adds a --watch flag, to run the command again on the same
+	// session whenever the host files and directories it loaded change.
+	Watchable bool
+
+	// watch is set by the --watch flag.
+	watch bool
+
 	// cmd is the parent cobra command.
 	cmd *cobra.Command
 
@@ -277,9 +285,26 @@ func (fc *FuncCommand) Command() *cobra.Command {
 
 			// Between PreRunE and RunE, flags are validated.
 			RunE: func(c *cobra.Command, a []string) error {
-				return withEngineAndTUI(c.Context(), client.Params{}, func(ctx context.Context, engineClient *client.Client) (rerr error) {
+				withEngine := withEngineAndTUI
+				if fc.watch {
+					withEngine = withEngineAndWatch
+				}
+
+				runs := 0
+				return withEngine(c.Context(), client.Params{}, func(ctx context.Context, engineClient *client.Client) (rerr error) {
 					fc.c = engineClient
 
+					if runs > 0 {
+						// Loading the module added the sub-commands and flags
+						// of its functions, which may have changed since.
+						var err error
+						c, err = fc.reload(c, a)
+						if err != nil {
+							return err
+						}
+					}
+					runs++
+
 					// withEngineAndTUI changes the context.
 					c.SetContext(ctx)
 
@@ -293,6 +318,10 @@ func (fc *FuncCommand) Command() *cobra.Command {
 			},
 		}
 
+		if fc.Watchable {
+			fc.cmd.PersistentFlags().BoolVar(&fc.watch, "watch", false, "Run again on the same session whenever the host files and directories loaded by the module change")
+		}
+
 		if fc.Init != nil {
 			fc.Init(fc.cmd)
 		}
@@ -300,6 +329,30 @@ func (fc *FuncCommand) Command() *cobra.Command {
 	return fc.cmd
 }
 
+// reload replaces the given command with a new one, without what loading the
+// module added to it, and parses the given args again.
+func (fc *FuncCommand) reload(c *cobra.Command, a []string) (*cobra.Command, error) {
+	fc.cmd = nil
+	fc.mod = nil
+	fc.q = nil
+	fc.showHelp = false
+	fc.showUsage = false
+
+	cmd := fc.Command()
+	cmd.PersistentFlags().AddFlagSet(c.PersistentFlags())
+	if parent := c.Parent(); parent != nil {
+		parent.RemoveCommand(c)
+		parent.AddCommand(cmd)
+	}
+	cmd.SetContext(c.Context())
+	cmd.InitDefaultHelpFlag()
+
+	if err := cmd.PreRunE(cmd, a); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
 func (fc *FuncCommand) execute(c *cobra.Command, a []string) (rerr error) {
 	ctx := c.Context()
 	rec := progrock.FromContext(ctx)
@@ -346,7 +399,10 @@ func (fc *FuncCommand) execute(c *cobra.Command, a []string) (rerr error) {
 	}
 
 	if fc.Execute != nil {
-		return fc.Execute(fc, cmd)
+		if err := fc.Execute(fc, cmd); err != nil {
+			return err
+		}
+		return saveModLockfile(ctx, fc.c.Dagger())
 	}
 
 	// No args to the parent command, default to showing help.
@@ -359,7 +415,7 @@ func (fc *FuncCommand) execute(c *cobra.Command, a []string) (rerr error) {
 		return err
 	}
 
-	return nil
+	return saveModLockfile(ctx, fc.c.Dagger())
 }
 
 func (fc *FuncCommand) load(c *cobra.Command, a []string, vtx *progrock.VertexRecorder) (cmd *cobra.Command, _ []string, rerr error) {
@@ -673,6 +729,18 @@ func (fc *FuncCommand) Select(name string) {
 	fc.q = fc.q.Select(gqlFieldName(name))
 }
 
+// SelectFields selects several sibling fields at once.
+func (fc *FuncCommand) SelectFields(names ...string) {
+	fields := make([]string, 0, len(names))
+	for _, name := range names {
+		fields = append(fields, gqlFieldName(name))
+	}
+	if fc.q == nil {
+		fc.q = querybuilder.Query()
+	}
+	fc.q = fc.q.Select(strings.Join(fields, " "))
+}
+
 func (fc *FuncCommand) Arg(name string, value any) {
 	fc.q = fc.q.Arg(gqlArgName(name), value)
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dagger.io/dagger"
+	"github.com/dagger/dagger/core/modules"
+	"github.com/dagger/dagger/engine/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// lockStrict makes references that aren't pinned in the lockfile fail to
+	// resolve.
+	lockStrict bool
+
+	// lockfile is the lockfile loaded into the session, if any.
+	lockfile *loadedLockfile
+)
+
+type loadedLockfile struct {
+	path     string
+	contents []byte
+}
+
+var lockCmd = &cobra.Command{
+	Use:    "lock",
+	Short:  "Manage the module's lockfile",
+	Long:   "Manage the module's lockfile (" + modules.LockFilename + "), which pins the container images and git refs it uses to the digests and commits they resolved to.",
+	Hidden: true, // for now, remove once we're ready for primetime
+}
+
+var lockUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update the pins in the module's lockfile",
+	Long:  "Re-resolve every container image and git ref pinned in the module's lockfile to its current digest or commit.\n\nIf the module has no lockfile yet, an empty one is created, in which subsequent commands record the references they resolve.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
+		return withEngineAndTUI(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) error {
+			dag := engineClient.Dagger()
+			mod, _, err := getModuleRef(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module: %w", err)
+			}
+			if !mod.Local {
+				return fmt.Errorf("lockfile can only be updated for local modules")
+			}
+
+			lockPath := filepath.Join(mod.Path, modules.LockFilename)
+			contents, err := os.ReadFile(lockPath)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to read lockfile: %w", err)
+			}
+			if _, err := dag.LoadLockfile(ctx, string(contents)); err != nil {
+				return fmt.Errorf("failed to load lockfile: %w", err)
+			}
+			updated, err := dag.Lockfile(ctx, dagger.LockfileOpts{Update: true})
+			if err != nil {
+				return fmt.Errorf("failed to update lockfile: %w", err)
+			}
+			return writeLockfile(lockPath, contents, []byte(updated))
+		})
+	},
+}
+
+func init() {
+	lockCmd.AddCommand(lockUpdateCmd)
+}
+
+// loadModLockfile loads the lockfile next to the given module's config into
+// the session, so that the references it pins are resolved the same way.
+func loadModLockfile(ctx context.Context, dag *dagger.Client, mod *modules.Ref) error {
+	if !mod.Local {
+		return nil
+	}
+
+	lockPath := filepath.Join(mod.Path, modules.LockFilename)
+	contents, err := os.ReadFile(lockPath)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		if !lockStrict {
+			// nothing is pinned and the module doesn't use a lockfile yet
+			return nil
+		}
+	default:
+		return fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	if _, err := dag.LoadLockfile(ctx, string(contents), dagger.LoadLockfileOpts{
+		Strict: lockStrict,
+	}); err != nil {
+		return fmt.Errorf("failed to load lockfile: %w", err)
+	}
+	lockfile = &loadedLockfile{
+		path:     lockPath,
+		contents: contents,
+	}
+	return nil
+}
+
+// saveModLockfile writes back any pin recorded during the session to the
+// lockfile that was loaded into it.
+func saveModLockfile(ctx context.Context, dag *dagger.Client) error {
+	if lockfile == nil || lockStrict {
+		return nil
+	}
+	updated, err := dag.Lockfile(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get lockfile: %w", err)
+	}
+	return writeLockfile(lockfile.path, lockfile.contents, []byte(updated))
+}
+
+func writeLockfile(lockPath string, original, updated []byte) error {
+	if bytes.Equal(original, updated) {
+		return nil
+	}
+	// nolint:gosec
+	if err := os.WriteFile(lockPath, updated, 0o644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,7 +17,9 @@ import (
 	"github.com/dagger/dagger/engine/client"
 	"github.com/go-git/go-git/v5"
 	"github.com/iancoleman/strcase"
+	"github.com/juju/ansiterm/tabwriter"
 	"github.com/moby/buildkit/util/gitutil"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/vito/progrock"
@@ -42,8 +45,10 @@ const (
 func init() {
 	moduleFlags.StringVarP(&moduleURL, "mod", "m", "", "Path to dagger.json config file for the module or a directory containing that file. Either local path (e.g. \"/path/to/some/dir\") or a github repo (e.g. \"github.com/dagger/dagger/path/to/some/subdir\").")
 	moduleFlags.BoolVar(&focus, "focus", true, "Only show output for focused commands.")
+	moduleFlags.BoolVar(&lockStrict, "locked", false, "Fail if a container image or git ref used by the module is not pinned in its lockfile.")
 
 	moduleCmd.PersistentFlags().AddFlagSet(moduleFlags)
+	lockCmd.PersistentFlags().AddFlagSet(moduleFlags)
 	listenCmd.PersistentFlags().AddFlagSet(moduleFlags)
 	queryCmd.PersistentFlags().AddFlagSet(moduleFlags)
 	funcCmds.AddFlagSet(moduleFlags)
@@ -61,6 +66,12 @@ func init() {
 
 	moduleCmd.AddCommand(moduleInitCmd)
 	moduleCmd.AddCommand(moduleInstallCmd)
+	moduleCmd.AddCommand(moduleUninstallCmd)
+	moduleCmd.AddCommand(moduleUpdateCmd)
+	moduleCmd.AddCommand(moduleGraphCmd)
+	moduleCmd.AddCommand(moduleVendorCmd)
+	moduleCmd.AddCommand(moduleDepsCmd)
+	moduleCmd.AddCommand(moduleInfoCmd)
 	moduleCmd.AddCommand(moduleSyncCmd)
 	moduleCmd.AddCommand(modulePublishCmd)
 }
@@ -160,11 +171,268 @@ var moduleInstallCmd = &cobra.Command{
 			if err := modCfg.Use(ctx, dag, ref, extraArgs...); err != nil {
 				return fmt.Errorf("failed to add module dependency: %w", err)
 			}
+			if _, err := modCfg.SelectVersions(ctx, dag, ref); err != nil {
+				return fmt.Errorf("failed to select dependency versions: %w", err)
+			}
+			return updateModuleConfig(ctx, dag, moduleDir, ref, modCfg, cmd)
+		})
+	},
+}
+
+var moduleUninstallCmd = &cobra.Command{
+	Use:     "uninstall DEPENDENCY...",
+	Aliases: []string{"remove"},
+	Short:   "Remove a dependency from a dagger module",
+	Long: `Remove a dependency from a dagger module.
+
+A dependency is given by its ref as installed, by its path without a
+version, or by the name of its module.`,
+	Args:   cobra.MinimumNArgs(1),
+	Hidden: false,
+	RunE: func(cmd *cobra.Command, extraArgs []string) (rerr error) {
+		ctx := cmd.Context()
+		return withEngineAndTUI(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) (err error) {
+			dag := engineClient.Dagger()
+			ref, _, err := getModuleRef(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module: %w", err)
+			}
+			moduleDir, err := ref.LocalSourcePath()
+			if err != nil {
+				return fmt.Errorf("module uninstall is only supported for local modules")
+			}
+			modCfg, err := ref.Config(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module config: %w", err)
+			}
+			if err := modCfg.Uninstall(ctx, dag, ref, extraArgs...); err != nil {
+				return fmt.Errorf("failed to remove module dependency: %w", err)
+			}
+			if _, err := modCfg.SelectVersions(ctx, dag, ref); err != nil {
+				return fmt.Errorf("failed to select dependency versions: %w", err)
+			}
+			return updateModuleConfig(ctx, dag, moduleDir, ref, modCfg, cmd)
+		})
+	},
+}
+
+var moduleUpdateCmd = &cobra.Command{
+	Use:   "update [DEPENDENCY...]",
+	Short: "Update the pins of a dagger module's dependencies",
+	Long: `Update the pins of a dagger module's dependencies.
+
+Each git dependency is pinned in dagger.json to the commit its version
+resolved to and to the digest of its source, which are verified whenever the
+module is loaded. This re-resolves the given dependencies, or all of them if
+none is given, and pins them to what they now resolve to.
+
+A dependency is given by its path, optionally with a new version or version
+constraint to switch to, e.g. github.com/foo/bar@v1.2.0 or
+github.com/foo/bar@^1.2.`,
+	Hidden: false,
+	RunE: func(cmd *cobra.Command, extraArgs []string) (rerr error) {
+		ctx := cmd.Context()
+		return withEngineAndTUI(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) (err error) {
+			dag := engineClient.Dagger()
+			ref, _, err := getModuleRef(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module: %w", err)
+			}
+			moduleDir, err := ref.LocalSourcePath()
+			if err != nil {
+				return fmt.Errorf("module update is only supported for local modules")
+			}
+			modCfg, err := ref.Config(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module config: %w", err)
+			}
+			if err := modCfg.Update(ctx, dag, ref, extraArgs...); err != nil {
+				return fmt.Errorf("failed to update module dependencies: %w", err)
+			}
+			if _, err := modCfg.SelectVersions(ctx, dag, ref); err != nil {
+				return fmt.Errorf("failed to select dependency versions: %w", err)
+			}
 			return updateModuleConfig(ctx, dag, moduleDir, ref, modCfg, cmd)
 		})
 	},
 }
 
+var moduleGraphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Print the dependency graph of a dagger module",
+	Long: `Print the dependency graph of a dagger module.
+
+Each git dependency is printed with the version and commit selected for it
+across the graph, which it's loaded at.
+
+Versions are selected like Go's minimal version selection: the highest
+version a dependency is pinned to across the graph must satisfy every version
+constraint on it, e.g. github.com/foo/bar@^1.2. "dagger mod install" and
+"dagger mod update" raise the module's own dependencies to it, and record it
+in dagger.json for the modules its dependencies depend on.`,
+	Args:   cobra.NoArgs,
+	Hidden: false,
+	RunE: func(cmd *cobra.Command, _ []string) (rerr error) {
+		ctx := cmd.Context()
+		return withEngineAndTUI(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) (err error) {
+			dag := engineClient.Dagger()
+			ref, _, err := getModuleRef(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module: %w", err)
+			}
+			modCfg, err := ref.Config(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module config: %w", err)
+			}
+			graph, err := modules.ResolveDependencyGraph(ctx, dag, ref, modCfg)
+			if err != nil {
+				return fmt.Errorf("failed to resolve module dependencies: %w", err)
+			}
+			printDependencyGraph(cmd.OutOrStdout(), graph)
+			return nil
+		})
+	},
+}
+
+var moduleVendorCmd = &cobra.Command{
+	Use:   "vendor",
+	Short: "Vendor the git dependencies of a dagger module",
+	Long: `Vendor the git dependencies of a dagger module.
+
+The source of each git dependency of the module, and of theirs, is copied at
+the commit it's pinned to in the ` + modules.VendorDirname + ` directory next to dagger.json,
+along with a manifest recording their commits and digests.
+
+Whenever the directory exists, the dependencies vendored in it are loaded
+from it instead of fetched, after verifying them against the manifest, so
+that the module can be loaded offline. Run this again after installing or
+updating dependencies.`,
+	Args:   cobra.NoArgs,
+	Hidden: false,
+	RunE: func(cmd *cobra.Command, _ []string) (rerr error) {
+		ctx := cmd.Context()
+		return withEngineAndTUI(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) (err error) {
+			dag := engineClient.Dagger()
+			ref, _, err := getModuleRef(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module: %w", err)
+			}
+			moduleDir, err := ref.LocalSourcePath()
+			if err != nil {
+				return fmt.Errorf("module vendor is only supported for local modules")
+			}
+			modCfg, err := ref.Config(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module config: %w", err)
+			}
+			graph, err := modules.ResolveDependencyGraph(ctx, dag, ref, modCfg)
+			if err != nil {
+				return fmt.Errorf("failed to resolve module dependencies: %w", err)
+			}
+			vendorDir, err := modules.Vendor(ctx, dag, graph)
+			if err != nil {
+				return err
+			}
+			return exportVendorDir(ctx, vendorDir, filepath.Join(moduleDir, modules.VendorDirname))
+		})
+	},
+}
+
+var moduleDepsCmd = &cobra.Command{
+	Use:    "deps",
+	Short:  "List the dependencies of a dagger module",
+	Long:   "List the dependencies of a dagger module, with the name and SDK of their modules and, for git ones, the commit they're pinned to.",
+	Args:   cobra.NoArgs,
+	Hidden: false,
+	RunE: func(cmd *cobra.Command, _ []string) (rerr error) {
+		ctx := cmd.Context()
+		return withEngineAndTUI(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) (err error) {
+			dag := engineClient.Dagger()
+			ref, _, err := getModuleRef(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module: %w", err)
+			}
+			modCfg, err := ref.Config(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to get module config: %w", err)
+			}
+			deps, err := modules.ResolveDependencies(ctx, dag, ref, modCfg)
+			if err != nil {
+				return fmt.Errorf("failed to resolve module dependencies: %w", err)
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+				termenv.String("name").Bold(),
+				termenv.String("ref").Bold(),
+				termenv.String("commit").Bold(),
+				termenv.String("sdk").Bold(),
+			)
+			for _, dep := range deps {
+				commit := "-"
+				if dep.Ref.Git != nil {
+					commit = dep.Ref.Git.Commit
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+					dep.Config.Name,
+					dep.Dependency,
+					commit,
+					dep.Config.SDK,
+				)
+			}
+			return tw.Flush()
+		})
+	},
+}
+
+var moduleInfoCmd = &cobra.Command{
+	Use:    "info",
+	Short:  "Show the objects and functions exported by a dagger module",
+	Args:   cobra.NoArgs,
+	Hidden: false,
+	RunE: loadModCmdWrapper(func(ctx context.Context, engineClient *client.Client, mod *dagger.Module, cmd *cobra.Command, _ []string) error {
+		if mod == nil {
+			return fmt.Errorf("no module specified and no default module found in current directory")
+		}
+		dag := engineClient.Dagger()
+		ref, _, err := getModuleRef(ctx, dag)
+		if err != nil {
+			return fmt.Errorf("failed to get module: %w", err)
+		}
+		modCfg, err := ref.Config(ctx, dag)
+		if err != nil {
+			return fmt.Errorf("failed to get module config: %w", err)
+		}
+		modDef, err := loadModObjects(ctx, dag, mod)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		fmt.Fprintf(tw, "%s\t%s\n", termenv.String("name").Bold(), modDef.Name)
+		fmt.Fprintf(tw, "%s\t%s\n", termenv.String("sdk").Bold(), modCfg.SDK)
+		fmt.Fprintf(tw, "%s\t%s\n", termenv.String("dependencies").Bold(), strings.Join(modCfg.Dependencies, ", "))
+		fmt.Fprintln(tw)
+
+		fmt.Fprintf(tw, "%s\t%s\n",
+			termenv.String("object name").Bold(),
+			termenv.String("functions").Bold(),
+		)
+		for _, obj := range modDef.AsObjects() {
+			objName := obj.Name
+			if gqlObjectName(objName) == gqlObjectName(modDef.Name) {
+				objName = "*" + objName
+			}
+			fnNames := []string{}
+			for _, fn := range obj.GetFunctions() {
+				fnNames = append(fnNames, fn.Name)
+			}
+			fmt.Fprintf(tw, "%s\t%s\n", objName, strings.Join(fnNames, ", "))
+		}
+		return tw.Flush()
+	}, ""),
+}
+
 var moduleSyncCmd = &cobra.Command{
 	Use:    "sync",
 	Short:  "Synchronize a dagger module with the latest version of its extensions",
@@ -293,6 +561,58 @@ var modulePublishCmd = &cobra.Command{
 	},
 }
 
+// exportVendorDir replaces the vendor directory at the given path with the
+// given one, leaving it untouched if the export fails.
+func exportVendorDir(ctx context.Context, vendorDir *dagger.Directory, vendorPath string) error {
+	tmpPath := vendorPath + ".tmp"
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return fmt.Errorf("failed to remove temporary vendor directory: %w", err)
+	}
+	if _, err := vendorDir.Export(ctx, tmpPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to export vendor directory: %w", err)
+	}
+	if err := os.RemoveAll(vendorPath); err != nil {
+		return fmt.Errorf("failed to remove vendor directory: %w", err)
+	}
+	if err := os.Rename(tmpPath, vendorPath); err != nil {
+		return fmt.Errorf("failed to move vendor directory: %w", err)
+	}
+	return nil
+}
+
+// printDependencyGraph prints the dependency graph as a tree, each
+// dependency with the version and commit selected for it across the graph.
+func printDependencyGraph(w io.Writer, graph *modules.DependencyGraph) {
+	fmt.Fprintln(w, graph.Root.Config.Name)
+	var printDeps func(node *modules.DependencyNode, indent string)
+	printDeps = func(node *modules.DependencyNode, indent string) {
+		for i, dep := range node.Dependencies {
+			branch, nextIndent := "├── ", "│   "
+			if i == len(node.Dependencies)-1 {
+				branch, nextIndent = "└── ", "    "
+			}
+			line := fmt.Sprintf("%s (%s)", dep.Config.Name, dep.Dependency)
+			if pin := graph.SelectedPin(dep); pin != nil {
+				if pin.Version != "" {
+					line += " " + pin.Version
+				}
+				line += " " + shortCommit(pin.Commit)
+			}
+			fmt.Fprintln(w, indent+branch+line)
+			printDeps(dep, indent+nextIndent)
+		}
+	}
+	printDeps(graph.Root, "")
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
 func originToPath(origin string) (string, error) {
 	url, err := gitutil.ParseURL(origin)
 	if err != nil {
@@ -432,7 +752,10 @@ func loadModCmdWrapper(
 				return err
 			}
 
-			return fn(ctx, engineClient, loadedMod, cmd, cmdArgs)
+			if err := fn(ctx, engineClient, loadedMod, cmd, cmdArgs); err != nil {
+				return err
+			}
+			return saveModLockfile(ctx, engineClient.Dagger())
 		})
 	}
 }
@@ -453,6 +776,10 @@ func loadMod(ctx context.Context, c *dagger.Client) (*dagger.Module, error) {
 		return nil, fmt.Errorf("failed to load module config: %w", err)
 	}
 
+	if err := loadModLockfile(ctx, c, mod); err != nil {
+		return nil, err
+	}
+
 	loadedMod, err := mod.AsModule(ctx, c)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load module: %w", err)
@@ -2,8 +2,10 @@ package main
 
 import (
 	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/dagger/dagger/core/modules"
 	"github.com/moby/buildkit/util/gitutil"
 	"github.com/stretchr/testify/require"
 )
@@ -222,3 +224,46 @@ func TestParseGit(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintDependencyGraph(t *testing.T) {
+	gitNode := func(name, dep, version string, deps ...*modules.DependencyNode) *modules.DependencyNode {
+		modPath, _, _ := strings.Cut(dep, "@")
+		ref, err := modules.ResolveStableRef(modPath + "@" + version)
+		require.NoError(t, err)
+		return &modules.DependencyNode{
+			Dependency:   dep,
+			Ref:          ref,
+			Pin:          &modules.DependencyPin{Commit: strings.Repeat(version[3:4], 40), Version: version},
+			Config:       &modules.Config{Name: name},
+			Dependencies: deps,
+		}
+	}
+
+	a := gitNode("a", "github.com/foo/a@^1.0", "v1.0.0",
+		gitNode("c", "github.com/foo/c@^1.2", "v1.2.0"),
+	)
+	b := gitNode("b", "github.com/foo/b@v1.0.0", "v1.0.0",
+		gitNode("c", "github.com/foo/c@^1.4", "v1.4.0"),
+	)
+	graph := &modules.DependencyGraph{
+		Root: &modules.DependencyNode{
+			Ref:          &modules.Ref{Path: ".", Local: true},
+			Config:       &modules.Config{Name: "root"},
+			Dependencies: []*modules.DependencyNode{a, b},
+		},
+		Selected: map[string]*modules.DependencyPin{
+			a.Ref.Symbolic():                 a.Pin,
+			b.Ref.Symbolic():                 b.Pin,
+			b.Dependencies[0].Ref.Symbolic(): b.Dependencies[0].Pin,
+		},
+	}
+
+	var out strings.Builder
+	printDependencyGraph(&out, graph)
+	require.Equal(t, `root
+├── a (github.com/foo/a@^1.0) v1.0.0 000000000000
+│   └── c (github.com/foo/c@^1.2) v1.4.0 444444444444
+└── b (github.com/foo/b@v1.0.0) v1.0.0 000000000000
+    └── c (github.com/foo/c@^1.4) v1.4.0 444444444444
+`, out.String())
+}
@@ -35,6 +35,9 @@ DAGGER_SESSION_PORT and DAGGER_SESSION_TOKEN will be convieniently injected auto
   Run a Dagger pipeline written in Python:
     dagger run python main.py
 
+  Run a Dagger pipeline written in Go again whenever the host files it loads change:
+    dagger run --watch go run main.go
+
   Run a Dagger API request directly:
     jq -n '{query:"{container{id}}"}' | \
       dagger run sh -c 'curl -s \
@@ -49,6 +52,7 @@ DAGGER_SESSION_PORT and DAGGER_SESSION_TOKEN will be convieniently injected auto
 
 var waitDelay time.Duration
 var runFocus bool
+var runWatch bool
 
 func init() {
 	// don't require -- to disambiguate subcommand flags
@@ -62,6 +66,8 @@ func init() {
 	)
 
 	runCmd.Flags().BoolVar(&runFocus, "focus", false, "Only show output for focused commands.")
+
+	runCmd.Flags().BoolVar(&runWatch, "watch", false, "Run the command again on the same session whenever the host files and directories it loaded change.")
 }
 
 func Run(cmd *cobra.Command, args []string) {
@@ -90,7 +96,13 @@ func run(ctx context.Context, args []string) error {
 	sessionToken := u.String()
 
 	focus = runFocus
-	return withEngineAndTUI(ctx, client.Params{
+
+	withEngine := withEngineAndTUI
+	if runWatch {
+		withEngine = withEngineAndWatch
+	}
+
+	return withEngine(ctx, client.Params{
 		SecretToken: sessionToken,
 	}, func(ctx context.Context, engineClient *client.Client) error {
 		sessionL, err := net.Listen("tcp", "127.0.0.1:0")
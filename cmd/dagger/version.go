@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 
 	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/client"
 	"github.com/spf13/cobra"
 )
 
@@ -15,11 +17,58 @@ var versionCmd = &cobra.Command{
 	PersistentPreRun:  func(*cobra.Command, []string) {},
 	PersistentPostRun: func(*cobra.Command, []string) {},
 	Args:              cobra.NoArgs,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println(long())
+		if !debug {
+			return nil
+		}
+		return printEngineRegistryMirrors(cmd.Context())
 	},
 }
 
+// printEngineRegistryMirrors prints the registry mirrors configured in the
+// engine.
+func printEngineRegistryMirrors(ctx context.Context) error {
+	var mirrors []string
+	err := withEngineAndTUI(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) error {
+		registryMirrors, err := engineClient.Dagger().RegistryMirrors(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get registry mirrors: %w", err)
+		}
+		for _, m := range registryMirrors {
+			registry, err := m.Registry(ctx)
+			if err != nil {
+				return err
+			}
+			mirror, err := m.Mirror(ctx)
+			if err != nil {
+				return err
+			}
+			insecure, err := m.Insecure(ctx)
+			if err != nil {
+				return err
+			}
+			if insecure {
+				mirror += " (insecure)"
+			}
+			mirrors = append(mirrors, fmt.Sprintf("%s => %s", registry, mirror))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(mirrors) == 0 {
+		fmt.Println("registry mirrors: none")
+		return nil
+	}
+	fmt.Println("registry mirrors:")
+	for _, mirror := range mirrors {
+		fmt.Println("  " + mirror)
+	}
+	return nil
+}
+
 func short() string {
 	return fmt.Sprintf("dagger %s (%s)", engine.Version, engine.EngineImageRepo)
 }
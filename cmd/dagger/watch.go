@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/client"
+	"github.com/fsnotify/fsnotify"
+	"github.com/vito/progrock"
+)
+
+// watchDebounce is how long changes need to settle before running again, so
+// that e.g. saving several files at once only triggers one run.
+const watchDebounce = 300 * time.Millisecond
+
+// withEngineAndWatch is like withEngineAndTUI, but calls fn again on the same
+// session each time the host files and directories loaded by its previous
+// call change, until interrupted.
+func withEngineAndWatch(
+	ctx context.Context,
+	params client.Params,
+	fn runClientCallback,
+) error {
+	if !silent && !interactive && useTTY() {
+		return inlineWatchTUI(ctx, params, fn)
+	}
+
+	return withEngineAndTUI(ctx, params, func(ctx context.Context, engineClient *client.Client) error {
+		for {
+			runErr := fn(ctx, engineClient)
+			rerun, err := waitForChanges(ctx, engineClient, runErr)
+			if !rerun {
+				return err
+			}
+		}
+	})
+}
+
+// inlineWatchTUI runs each iteration of watch mode in a new inline TUI, so
+// that every run starts from a clean screen.
+func inlineWatchTUI(
+	ctx context.Context,
+	params client.Params,
+	fn runClientCallback,
+) error {
+	params = defaultEngineParams(params)
+
+	tapes := &tapeSwitch{groups: map[string]*progrock.Group{}}
+	progW, err := progrockTee(tapes)
+	if err != nil {
+		return err
+	}
+	params.ProgrockWriter = progW
+
+	var sess *client.Client
+	var sessCtx context.Context
+	defer func() {
+		if sess != nil {
+			sess.Close()
+		}
+	}()
+
+	// the status infos are only reported when connecting, so keep them for
+	// the TUIs of the next runs
+	var statusMu sync.Mutex
+	var statusInfos []progrock.StatusInfo
+	setStatusInfo := func(ui progrock.UIClient, info progrock.StatusInfo) {
+		statusMu.Lock()
+		statusInfos = append(statusInfos, info)
+		statusMu.Unlock()
+		ui.SetStatusInfo(info)
+	}
+
+	for {
+		tape := newTape()
+		if err := tapes.Switch(tape); err != nil {
+			return err
+		}
+
+		runErr := progrock.DefaultUI().Run(ctx, tape, func(ctx context.Context, ui progrock.UIClient) error {
+			if sess == nil {
+				params.CloudURLCallback = func(cloudURL string) {
+					setStatusInfo(ui, progrock.StatusInfo{
+						Name:  "Cloud URL",
+						Value: cloudURL,
+						Order: 1,
+					})
+				}
+
+				params.EngineNameCallback = func(name string) {
+					setStatusInfo(ui, progrock.StatusInfo{
+						Name:  "Engine",
+						Value: name,
+						Order: 2,
+					})
+				}
+
+				var err error
+				sess, sessCtx, err = client.Connect(ctx, params)
+				if err != nil {
+					sess = nil
+					return err
+				}
+				return fn(sessCtx, sess)
+			}
+
+			statusMu.Lock()
+			for _, info := range statusInfos {
+				ui.SetStatusInfo(info)
+			}
+			statusMu.Unlock()
+
+			// interrupting this TUI only interrupts this run, not the session
+			runCtx, cancel := context.WithCancel(sessCtx)
+			defer cancel()
+			stop := context.AfterFunc(ctx, cancel)
+			defer stop()
+
+			return fn(runCtx, sess)
+		})
+		if sess == nil {
+			return runErr
+		}
+
+		rerun, err := waitForChanges(sessCtx, sess, runErr)
+		if !rerun {
+			return err
+		}
+	}
+}
+
+// waitForChanges reports the result of a run, and waits for the host files
+// and directories it loaded to change. It returns false when the run was
+// interrupted or when interrupted while waiting, along with the error to
+// exit with.
+func waitForChanges(ctx context.Context, engineClient *client.Client, runErr error) (bool, error) {
+	if errors.Is(runErr, context.Canceled) || ctx.Err() != nil {
+		return false, runErr
+	}
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, "Error:", runErr)
+	}
+
+	imports := engineClient.LocalImports.Take()
+	if len(imports) == 0 {
+		if runErr != nil {
+			return false, runErr
+		}
+		return false, fmt.Errorf("nothing to watch: no host files or directories were loaded")
+	}
+
+	watcher, err := newHostWatcher(imports)
+	if err != nil {
+		return false, err
+	}
+	defer watcher.Close()
+
+	waitCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Watching %s for changes (press Ctrl+C to stop)\n", pluralize(len(watcher.roots), "host path", "host paths"))
+
+	changed, err := watcher.Wait(waitCtx, watchDebounce)
+	if err != nil {
+		if ctx.Err() == nil && waitCtx.Err() != nil {
+			// interrupted by the user
+			return false, nil
+		}
+		return false, err
+	}
+
+	msg := changed[0]
+	if len(changed) > 1 {
+		msg += fmt.Sprintf(" and %s", pluralize(len(changed)-1, "other path", "other paths"))
+	}
+	fmt.Fprintf(os.Stderr, "Changed: %s, running again\n", msg)
+
+	return true, nil
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// tapeSwitch is a progrock writer that can be switched to a new tape, so that
+// each run of watch mode is rendered by its own TUI.
+type tapeSwitch struct {
+	mu   sync.Mutex
+	tape *progrock.Tape
+
+	// groups are the groups recorded so far, which the vertexes of the next
+	// runs can still belong to
+	groups map[string]*progrock.Group
+}
+
+var _ progrock.Writer = &tapeSwitch{}
+
+func (ts *tapeSwitch) Switch(tape *progrock.Tape) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.tape = tape
+
+	groups := make([]*progrock.Group, 0, len(ts.groups))
+	for _, g := range ts.groups {
+		groups = append(groups, g)
+	}
+	return tape.WriteStatus(&progrock.StatusUpdate{Groups: groups})
+}
+
+func (ts *tapeSwitch) WriteStatus(update *progrock.StatusUpdate) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, g := range update.Groups {
+		ts.groups[g.Id] = g
+	}
+	return ts.tape.WriteStatus(update)
+}
+
+func (ts *tapeSwitch) Close() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.tape.Close()
+}
+
+// hostWatcher watches the host files and directories loaded by a run.
+type hostWatcher struct {
+	watcher *fsnotify.Watcher
+	roots   []*watchRoot
+}
+
+// watchRoot is a host file or directory loaded by a run.
+type watchRoot struct {
+	path string
+
+	// filter tells which paths of a directory were loaded. It's nil for a
+	// single file.
+	filter *client.LocalImportFilter
+}
+
+func newHostWatcher(imports []engine.LocalImportOpts) (*hostWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create watcher: %w", err)
+	}
+	hw := &hostWatcher{watcher: watcher}
+
+	seen := map[string]struct{}{}
+	for _, opts := range imports {
+		key, err := json.Marshal(opts)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		if _, ok := seen[string(key)]; ok {
+			continue
+		}
+		seen[string(key)] = struct{}{}
+
+		if err := hw.add(opts); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	return hw, nil
+}
+
+func (hw *hostWatcher) add(opts engine.LocalImportOpts) error {
+	path, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return err
+	}
+
+	if opts.ReadSingleFileOnly {
+		hw.roots = append(hw.roots, &watchRoot{path: path})
+		// watch the parent directory, so that the file is still watched after
+		// an editor replaces it on save
+		if err := hw.watcher.Add(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("watch %s: %w", filepath.Dir(path), err)
+		}
+		return nil
+	}
+
+	filter, err := client.NewLocalImportFilter(opts)
+	if err != nil {
+		return fmt.Errorf("watch %s: %w", path, err)
+	}
+	root := &watchRoot{path: path, filter: filter}
+	hw.roots = append(hw.roots, root)
+	return hw.addDir(root, path)
+}
+
+// addDir watches the given directory of a root and its subdirectories, except
+// the ones nothing was loaded from.
+func (hw *hostWatcher) addDir(root *watchRoot, dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// removed in the meantime
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root.path {
+			rel, err := filepath.Rel(root.path, p)
+			if err != nil {
+				return err
+			}
+			if root.filter.SkipDir(rel) {
+				return filepath.SkipDir
+			}
+		}
+		if err := hw.watcher.Add(p); err != nil {
+			return fmt.Errorf("watch %s: %w", p, err)
+		}
+		return nil
+	})
+}
+
+// match reports whether the event changed a loaded path.
+func (hw *hostWatcher) match(event fsnotify.Event) (bool, error) {
+	var isDir bool
+	if fi, err := os.Lstat(event.Name); err == nil {
+		isDir = fi.IsDir()
+	}
+
+	matched := false
+	for _, root := range hw.roots {
+		if root.filter == nil {
+			if event.Name == root.path {
+				matched = true
+			}
+			continue
+		}
+
+		rel, err := filepath.Rel(root.path, event.Name)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if isDir && event.Has(fsnotify.Create) && !root.filter.SkipDir(rel) {
+			// watch new directories too
+			if err := hw.addDir(root, event.Name); err != nil {
+				return false, err
+			}
+		}
+		if root.filter.Match(rel, isDir) {
+			matched = true
+		}
+	}
+	return matched, nil
+}
+
+// Wait waits for loaded paths to change, and returns them once no other
+// change happened during the given debounce duration.
+func (hw *hostWatcher) Wait(ctx context.Context, debounce time.Duration) ([]string, error) {
+	var changed []string
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case err, ok := <-hw.watcher.Errors:
+			if !ok {
+				return nil, errors.New("watcher closed")
+			}
+			return nil, err
+		case event, ok := <-hw.watcher.Events:
+			if !ok {
+				return nil, errors.New("watcher closed")
+			}
+			matched, err := hw.match(event)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+			if !slices.Contains(changed, event.Name) {
+				changed = append(changed, event.Name)
+			}
+			settled = time.After(debounce)
+		case <-settled:
+			return changed, nil
+		}
+	}
+}
+
+func (hw *hostWatcher) Close() error {
+	return hw.watcher.Close()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/engine"
+)
+
+func TestHostWatcher(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "build"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte("{}"), 0o600))
+
+	watcher, err := newHostWatcher([]engine.LocalImportOpts{
+		{Path: filepath.Join(dir, "src")},
+		{Path: dir, ExcludePatterns: []string{"build"}},
+		{Path: filepath.Join(dir, "config.json"), ReadSingleFileOnly: true},
+		{Path: filepath.Join(dir, "config.json"), ReadSingleFileOnly: true},
+	})
+	require.NoError(t, err)
+	defer watcher.Close()
+	require.Len(t, watcher.roots, 3)
+
+	wait := func() ([]string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		return watcher.Wait(ctx, 50*time.Millisecond)
+	}
+
+	// excluded paths don't trigger runs
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "build", "out"), []byte("out"), 0o600))
+	_, err = wait()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// changes are reported once they settle
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "a.go"), []byte("a"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "b.go"), []byte("b"), 0o600))
+	changed, err := wait()
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "src", "a.go"), filepath.Join(dir, "src", "b.go")}, changed)
+
+	// new directories are watched too
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "sub"), 0o755))
+	_, err = wait()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "sub", "c.go"), []byte("c"), 0o600))
+	changed, err = wait()
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "src", "sub", "c.go")}, changed)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"a":1}`), 0o600))
+	changed, err = wait()
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "config.json")}, changed)
+}
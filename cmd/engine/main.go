@@ -21,6 +21,7 @@ import (
 	"github.com/containerd/containerd/remotes/docker"
 	"github.com/containerd/containerd/sys"
 	sddaemon "github.com/coreos/go-systemd/v22/daemon"
+	"github.com/dagger/dagger/engine/buildkit"
 	"github.com/dagger/dagger/engine/cache"
 	"github.com/dagger/dagger/engine/server"
 	"github.com/dagger/dagger/network"
@@ -53,7 +54,6 @@ import (
 	"github.com/moby/buildkit/util/bklog"
 	"github.com/moby/buildkit/util/grpcerrors"
 	"github.com/moby/buildkit/util/profiler"
-	"github.com/moby/buildkit/util/resolver"
 	"github.com/moby/buildkit/util/stack"
 	"github.com/moby/buildkit/util/tracing/detect"
 	_ "github.com/moby/buildkit/util/tracing/detect/jaeger"
@@ -99,6 +99,7 @@ type workerInitializerOpt struct {
 	config         *config.Config
 	sessionManager *session.Manager
 	traceSocket    string
+	registryHosts  docker.RegistryHosts
 }
 
 type workerInitializer struct {
@@ -732,10 +733,13 @@ func newController(ctx context.Context, c *cli.Context, cfg *config.Config) (*se
 		}
 	}
 
+	registryMirrors := buildkit.NewRegistryMirrors(cfg.Registries, filepath.Join(cfg.Root, "registry-mirror-certs"))
+
 	wc, err := newWorkerController(c, workerInitializerOpt{
 		config:         cfg,
 		sessionManager: sessionManager,
 		traceSocket:    traceSocket,
+		registryHosts:  registryMirrors.Hosts,
 	})
 	if err != nil {
 		return nil, nil, err
@@ -779,7 +783,7 @@ func newController(ctx context.Context, c *cli.Context, cfg *config.Config) (*se
 		return nil, nil, err
 	}
 
-	resolverFn := resolverFunc(cfg)
+	resolverFn := registryMirrors.Hosts
 	remoteCacheExporterFuncs := map[string]remotecache.ResolveCacheExporterFunc{
 		"registry": registryremotecache.ResolveCacheExporterFunc(sessionManager, resolverFn),
 		"local":    localremotecache.ResolveCacheExporterFunc(sessionManager),
@@ -818,6 +822,8 @@ func newController(ctx context.Context, c *cli.Context, cfg *config.Config) (*se
 		UpstreamCacheExporters: remoteCacheExporterFuncs,
 		UpstreamCacheImporters: remoteCacheImporterFuncs,
 		DNSConfig:              getDNSConfig(cfg.DNS),
+		RegistryHosts:          resolverFn,
+		RegistryMirrors:        registryMirrors,
 	})
 	if err != nil {
 		return nil, nil, err
@@ -826,10 +832,6 @@ func newController(ctx context.Context, c *cli.Context, cfg *config.Config) (*se
 	return ctrler, cacheManager, nil
 }
 
-func resolverFunc(cfg *config.Config) docker.RegistryHosts {
-	return resolver.NewRegistryConfig(cfg.Registries)
-}
-
 func newWorkerController(c *cli.Context, wiOpt workerInitializerOpt) (*worker.Controller, error) {
 	wc := &worker.Controller{}
 	nWorkers := 0
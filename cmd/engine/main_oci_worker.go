@@ -291,7 +291,7 @@ func ociWorkerInitializer(c *cli.Context, common workerInitializerOpt) ([]worker
 		return nil, err
 	}
 
-	hosts := resolverFunc(common.config)
+	hosts := common.registryHosts
 	snFactory, err := snapshotterFactory(common.config.Root, cfg, common.sessionManager, hosts)
 	if err != nil {
 		return nil, err
@@ -380,6 +380,7 @@ func registerDaggerCustomSources(worker *base.Worker, dns *oci.DNSConfig) error
 
 	bs, err := blob.NewSource(blob.Opt{
 		CacheAccessor: worker.CacheMgr,
+		RegistryHosts: worker.RegistryHosts,
 	})
 	if err != nil {
 		return err
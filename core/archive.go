@@ -0,0 +1,54 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+// ArchiveFormat is the format of an archive of a directory.
+type ArchiveFormat string
+
+const (
+	ArchiveTar     ArchiveFormat = "TAR"
+	ArchiveTgz     ArchiveFormat = "TGZ"
+	ArchiveTarZstd ArchiveFormat = "TAR_ZSTD"
+	ArchiveZip     ArchiveFormat = "ZIP"
+)
+
+// archiveHeaderSize is how many bytes of a file DetectArchiveFormat needs to
+// recognize its format.
+const archiveHeaderSize = 512
+
+var archiveFormats = map[ArchiveFormat]struct {
+	format    buildkit.ArchiveFormat
+	extension string
+}{
+	ArchiveTar:     {buildkit.ArchiveTar, ".tar"},
+	ArchiveTgz:     {buildkit.ArchiveTgz, ".tar.gz"},
+	ArchiveTarZstd: {buildkit.ArchiveZstd, ".tar.zst"},
+	ArchiveZip:     {buildkit.ArchiveZip, ".zip"},
+}
+
+// DetectArchiveFormat returns the format of the archive starting with the
+// given header, which should hold at least its first 512 bytes.
+//
+// Compressed archives are assumed to be compressed tarballs.
+func DetectArchiveFormat(header []byte) (ArchiveFormat, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return ArchiveTgz, nil
+	case bytes.HasPrefix(header, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return ArchiveTarZstd, nil
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")),
+		bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return ArchiveZip, nil
+	case len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar")):
+		return ArchiveTar, nil
+	case len(header) == archiveHeaderSize && bytes.Count(header, []byte{0}) == archiveHeaderSize:
+		// the end-of-archive marker of an empty tarball
+		return ArchiveTar, nil
+	}
+	return "", fmt.Errorf("not a TAR, TGZ, TAR_ZSTD or ZIP archive")
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectArchiveFormat(t *testing.T) {
+	tarball := func(t *testing.T, withEntry bool) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		if withEntry {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0o644, Size: 1}))
+			_, err := tw.Write([]byte("a"))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		return buf.Bytes()
+	}
+
+	var tgz bytes.Buffer
+	gw := gzip.NewWriter(&tgz)
+	_, err := gw.Write(tarball(t, true))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	var tzst bytes.Buffer
+	zw, err := zstd.NewWriter(&tzst)
+	require.NoError(t, err)
+	_, err = zw.Write(tarball(t, true))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	var zipped bytes.Buffer
+	zipw := zip.NewWriter(&zipped)
+	_, err = zipw.Create("a.txt")
+	require.NoError(t, err)
+	require.NoError(t, zipw.Close())
+
+	var emptyZip bytes.Buffer
+	require.NoError(t, zip.NewWriter(&emptyZip).Close())
+
+	for _, tc := range []struct {
+		name     string
+		contents []byte
+		format   ArchiveFormat
+	}{
+		{"tar", tarball(t, true), ArchiveTar},
+		{"empty tar", tarball(t, false), ArchiveTar},
+		{"tgz", tgz.Bytes(), ArchiveTgz},
+		{"zstd", tzst.Bytes(), ArchiveTarZstd},
+		{"zip", zipped.Bytes(), ArchiveZip},
+		{"empty zip", emptyZip.Bytes(), ArchiveZip},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			header := tc.contents
+			if len(header) > archiveHeaderSize {
+				header = header[:archiveHeaderSize]
+			}
+			format, err := DetectArchiveFormat(header)
+			require.NoError(t, err)
+			require.Equal(t, tc.format, format)
+		})
+	}
+
+	_, err = DetectArchiveFormat([]byte("hello world"))
+	require.ErrorContains(t, err, "not a TAR, TGZ, TAR_ZSTD or ZIP archive")
+}
@@ -0,0 +1,305 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/containerd/containerd/images"
+	"github.com/docker/distribution/reference"
+	"github.com/moby/buildkit/client/llb"
+	"github.com/moby/buildkit/identity"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/vito/progrock"
+
+	"github.com/dagger/dagger/core/pipeline"
+	"github.com/dagger/dagger/core/resourceid"
+	"github.com/dagger/dagger/engine/buildkit"
+	"github.com/dagger/dagger/engine/sources/blob"
+)
+
+// DefaultArtifactMediaType is the media type of the files of an artifact
+// published by Directory.publishArtifact, unless another one is given. It's
+// the one ORAS uses by default too.
+const DefaultArtifactMediaType = "application/vnd.oci.image.layer.v1.tar"
+
+// OCIArtifact is an artifact stored in an OCI registry whose layers are
+// plain files rather than filesystem changes, like a Helm chart or a WASM
+// module pushed with ORAS.
+type OCIArtifact struct {
+	Address string `json:"address"`
+
+	Pipeline pipeline.Path  `json:"pipeline"`
+	Platform specs.Platform `json:"platform,omitempty"`
+}
+
+// ArtifactAnnotation is an annotation of the manifest of an artifact.
+type ArtifactAnnotation struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func NewOCIArtifact(address string, pipeline pipeline.Path, platform specs.Platform) (*OCIArtifact, error) {
+	ref, err := normalizeArtifactAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return &OCIArtifact{
+		Address:  ref,
+		Pipeline: pipeline,
+		Platform: platform,
+	}, nil
+}
+
+func (artifact *OCIArtifact) ID() (OCIArtifactID, error) {
+	return resourceid.Encode(artifact)
+}
+
+// Files returns the files of the artifact's layers with any of the given
+// media types, or of all of its layers if none is given. Each file is named
+// after the title annotation of its layer.
+func (artifact *OCIArtifact) Files(ctx context.Context, bk *buildkit.Client, mediaTypes []string) (*Directory, error) {
+	ref, man, err := fetchArtifactManifest(ctx, bk, artifact.Address)
+	if err != nil {
+		return nil, err
+	}
+	files, err := artifactFiles(man, mediaTypes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", artifact.Address, err)
+	}
+	if len(files) == 0 {
+		return NewScratchDirectory(artifact.Pipeline, artifact.Platform), nil
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	st := llb.Scratch()
+	for _, name := range names {
+		layer := blob.FileLLB(files[name], ref, llb.WithCustomNamef("pull %s %s", artifact.Address, name))
+		st = st.File(llb.Copy(layer, blob.FileName, name, &llb.CopyInfo{
+			CreateDestPath: true,
+		}), llb.WithCustomNamef("%scopy %s", buildkit.InternalPrefix, name))
+	}
+
+	dir := NewScratchDirectory(artifact.Pipeline, artifact.Platform)
+	if err := dir.SetState(ctx, st); err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+// PublishArtifact pushes the files of the directory as the layers of an
+// artifact of the given type, each titled with its path in the directory,
+// and returns the address pinned to the digest of the artifact's manifest.
+func (dir *Directory) PublishArtifact(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	address string,
+	artifactType string,
+	mediaType string,
+	annotations []ArtifactAnnotation,
+) (_ string, rerr error) {
+	if artifactType == "" {
+		return "", errors.New("artifact type must not be empty")
+	}
+	if mediaType == "" {
+		mediaType = DefaultArtifactMediaType
+	}
+	ref, err := normalizeArtifactAddress(address)
+	if err != nil {
+		return "", err
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", err
+	}
+
+	rec := progrock.FromContext(ctx)
+	vtx := rec.Vertex(
+		digest.Digest(identity.NewID()),
+		fmt.Sprintf("publish artifact %s", ref),
+	)
+	defer func() { vtx.Done(rerr) }()
+
+	man := specs.Manifest{
+		MediaType:    specs.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Config:       specs.DescriptorEmptyJSON,
+	}
+	man.SchemaVersion = 2
+	if len(annotations) > 0 {
+		man.Annotations = map[string]string{}
+		for _, annotation := range annotations {
+			man.Annotations[annotation.Name] = annotation.Value
+		}
+	}
+
+	err = withMountedDirectory(ctx, bk, svcs, dir, func(root string) error {
+		return filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, filePath)
+			if err != nil {
+				return err
+			}
+			layer, err := pushArtifactFile(ctx, bk, ref, filePath, mediaType)
+			if err != nil {
+				return fmt.Errorf("push %s: %w", rel, err)
+			}
+			layer.Annotations = map[string]string{
+				specs.AnnotationTitle: filepath.ToSlash(rel),
+			}
+			man.Layers = append(man.Layers, layer)
+			return nil
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(man.Layers) == 0 {
+		return "", errors.New("no files to publish")
+	}
+
+	if err := bk.PushRegistryBlob(ctx, ref, man.Config, specs.DescriptorEmptyJSON.Data); err != nil {
+		return "", fmt.Errorf("push artifact config: %w", err)
+	}
+
+	manBytes, err := json.Marshal(man)
+	if err != nil {
+		return "", err
+	}
+	manDesc := specs.Descriptor{
+		MediaType:    specs.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Digest:       digest.FromBytes(manBytes),
+		Size:         int64(len(manBytes)),
+	}
+	if err := bk.PushRegistryBlob(ctx, ref, manDesc, manBytes); err != nil {
+		return "", fmt.Errorf("push artifact manifest: %w", err)
+	}
+
+	digested, err := reference.WithDigest(reference.TrimNamed(named), manDesc.Digest)
+	if err != nil {
+		return "", err
+	}
+	return digested.String(), nil
+}
+
+// pushArtifactFile pushes the file at the given path as a layer of an
+// artifact.
+func pushArtifactFile(ctx context.Context, bk *buildkit.Client, ref, filePath, mediaType string) (specs.Descriptor, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+	defer f.Close()
+
+	digester := digest.Canonical.Digester()
+	size, err := io.Copy(digester.Hash(), f)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return specs.Descriptor{}, err
+	}
+
+	desc := specs.Descriptor{
+		MediaType: mediaType,
+		Digest:    digester.Digest(),
+		Size:      size,
+	}
+	if err := bk.PushRegistryContent(ctx, ref, desc, f); err != nil {
+		return specs.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+// fetchArtifactManifest resolves the given address and returns it pinned to
+// the digest of its manifest, along with the manifest.
+func fetchArtifactManifest(ctx context.Context, bk *buildkit.Client, address string) (string, *specs.Manifest, error) {
+	desc, err := bk.ResolveRegistryDescriptor(ctx, address)
+	if err != nil {
+		return "", nil, err
+	}
+	switch desc.MediaType {
+	case specs.MediaTypeImageManifest, images.MediaTypeDockerSchema2Manifest:
+	case specs.MediaTypeImageIndex, images.MediaTypeDockerSchema2ManifestList:
+		return "", nil, fmt.Errorf("%s is an image index, not an artifact", address)
+	default:
+		return "", nil, fmt.Errorf("%s has unsupported media type %q", address, desc.MediaType)
+	}
+
+	named, err := reference.ParseNormalizedNamed(address)
+	if err != nil {
+		return "", nil, err
+	}
+	digested, err := reference.WithDigest(reference.TrimNamed(named), desc.Digest)
+	if err != nil {
+		return "", nil, err
+	}
+	ref := digested.String()
+
+	manBytes, err := bk.FetchRegistryBlob(ctx, ref, desc)
+	if err != nil {
+		return "", nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	var man specs.Manifest
+	if err := json.Unmarshal(manBytes, &man); err != nil {
+		return "", nil, fmt.Errorf("unmarshal manifest: %w", err)
+	}
+	return ref, &man, nil
+}
+
+// artifactFiles returns the layers of the manifest with any of the given
+// media types, or all of them if none is given, keyed by the path of the
+// file they hold. Layers without a title aren't files, so they're skipped,
+// like ORAS does.
+func artifactFiles(man *specs.Manifest, mediaTypes []string) (map[string]specs.Descriptor, error) {
+	files := map[string]specs.Descriptor{}
+	for _, layer := range man.Layers {
+		if len(mediaTypes) > 0 && !slices.Contains(mediaTypes, layer.MediaType) {
+			continue
+		}
+		title, ok := layer.Annotations[specs.AnnotationTitle]
+		if !ok {
+			continue
+		}
+		name := path.Clean(title)
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("invalid file name %q", title)
+		}
+		if _, ok := files[name]; ok {
+			return nil, fmt.Errorf("duplicate file name %q", title)
+		}
+		files[name] = layer
+	}
+	return files, nil
+}
+
+// normalizeArtifactAddress returns the fully qualified form of the given
+// address, with the latest tag if it has neither a tag nor a digest.
+func normalizeArtifactAddress(address string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	return reference.TagNameOnly(named).String(), nil
+}
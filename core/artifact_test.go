@@ -0,0 +1,65 @@
+package core
+
+import (
+	"testing"
+
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactFiles(t *testing.T) {
+	const chartType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	layer := func(mediaType, title string) specs.Descriptor {
+		desc := specs.Descriptor{MediaType: mediaType}
+		if title != "" {
+			desc.Annotations = map[string]string{specs.AnnotationTitle: title}
+		}
+		return desc
+	}
+
+	man := &specs.Manifest{
+		Layers: []specs.Descriptor{
+			layer(chartType, "chart.tgz"),
+			layer(DefaultArtifactMediaType, "docs/README.md"),
+			layer(DefaultArtifactMediaType, ""),
+		},
+	}
+
+	files, err := artifactFiles(man, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, chartType, files["chart.tgz"].MediaType)
+	require.Equal(t, DefaultArtifactMediaType, files["docs/README.md"].MediaType)
+
+	files, err = artifactFiles(man, []string{chartType})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Contains(t, files, "chart.tgz")
+
+	files, err = artifactFiles(man, []string{"application/unknown"})
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	_, err = artifactFiles(&specs.Manifest{
+		Layers: []specs.Descriptor{layer(chartType, "../chart.tgz")},
+	}, nil)
+	require.ErrorContains(t, err, "invalid file name")
+
+	_, err = artifactFiles(&specs.Manifest{
+		Layers: []specs.Descriptor{layer(chartType, "chart.tgz"), layer(chartType, "./chart.tgz")},
+	}, nil)
+	require.ErrorContains(t, err, "duplicate file name")
+}
+
+func TestNormalizeArtifactAddress(t *testing.T) {
+	ref, err := normalizeArtifactAddress("ghcr.io/org/chart")
+	require.NoError(t, err)
+	require.Equal(t, "ghcr.io/org/chart:latest", ref)
+
+	ref, err = normalizeArtifactAddress("org/chart:1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "docker.io/org/chart:1.0.0", ref)
+
+	_, err = normalizeArtifactAddress("Invalid:Address:")
+	require.Error(t, err)
+}
@@ -81,6 +81,11 @@ type Container struct {
 	// Focused indicates whether subsequent operations will be
 	// focused, i.e. shown more prominently in the UI.
 	Focused bool `json:"focused"`
+
+	// The root filesystem as of the last layer boundary. Operations performed
+	// since then are squashed into a single layer at the next boundary. It's
+	// reset whenever the root filesystem is replaced.
+	LayerBoundary *pb.Definition `json:"layer_boundary,omitempty"`
 }
 
 func (container *Container) PBDefinitions() ([]*pb.Definition, error) {
@@ -88,6 +93,9 @@ func (container *Container) PBDefinitions() ([]*pb.Definition, error) {
 	if container.FS != nil {
 		defs = append(defs, container.FS)
 	}
+	if container.LayerBoundary != nil {
+		defs = append(defs, container.LayerBoundary)
+	}
 	for _, mnt := range container.Mounts {
 		if mnt.Source != nil {
 			defs = append(defs, mnt.Source)
@@ -314,6 +322,7 @@ func (container *Container) From(ctx context.Context, bk *buildkit.Client, addr
 	}
 
 	container.FS = def.ToPB()
+	container.LayerBoundary = nil
 
 	// associate vertexes to the 'from' sub-pipeline
 	buildkit.RecordVertexes(subRecorder, container.FS)
@@ -459,6 +468,7 @@ func (container *Container) buildUncached(
 
 	container.FS = def.ToPB()
 	container.FS.Source = nil
+	container.LayerBoundary = nil
 
 	cfgBytes, found := res.Metadata[exptypes.ExporterImageConfigKey]
 	if found {
@@ -497,6 +507,7 @@ func (container *Container) WithRootFS(ctx context.Context, dir *Directory) (*Co
 	}
 
 	container.FS = def.ToPB()
+	container.LayerBoundary = nil
 
 	container.Services.Merge(dir.Services)
 
@@ -519,6 +530,16 @@ func (container *Container) WithDirectory(ctx context.Context, bk *buildkit.Clie
 	})
 }
 
+// WithDirectories writes the given directories at the given path, merged
+// with MergeDirectories.
+func (container *Container) WithDirectories(ctx context.Context, bk *buildkit.Client, svcs *Services, subdir string, dirs []*Directory, conflict MergeConflict, owner string) (*Container, error) {
+	merged, err := MergeDirectories(ctx, bk, svcs, dirs, conflict, container.Pipeline, container.Platform)
+	if err != nil {
+		return nil, err
+	}
+	return container.WithDirectory(ctx, bk, subdir, merged, CopyFilter{}, owner)
+}
+
 func (container *Container) WithFile(ctx context.Context, bk *buildkit.Client, destPath string, src *File, permissions fs.FileMode, owner string) (*Container, error) {
 	container = container.Clone()
 
@@ -1651,6 +1672,20 @@ func (container *Container) Import(
 		}
 	}
 
+	return container.withImportedManifest(ctx, bk, store, manifestDesc, release)
+}
+
+// withImportedManifest sets the container's root filesystem and config to
+// those of the given image manifest, imported in the OCI store. If release is
+// given, the image is evaluated before releasing the lease it was imported
+// with, so that buildkit holds onto it.
+func (container *Container) withImportedManifest(
+	ctx context.Context,
+	bk *buildkit.Client,
+	store content.Store,
+	manifestDesc *specs.Descriptor,
+	release func(context.Context) error,
+) (*Container, error) {
 	// NB: the repository portion of this ref doesn't actually matter, but it's
 	// pleasant to see something recognizable.
 	dummyRepo := "dagger/import"
@@ -1667,6 +1702,7 @@ func (container *Container) Import(
 	}
 
 	container.FS = execDef.ToPB()
+	container.LayerBoundary = nil
 
 	if release != nil {
 		// eagerly evaluate the OCI reference so Buildkit sets up a long-term lease
@@ -1710,6 +1746,302 @@ func (container *Container) Import(
 	return container, nil
 }
 
+// ExportToDocker loads the container's image into the Docker-compatible
+// daemon listening on the given host socket, tagged with the given name.
+func (container *Container) ExportToDocker(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	tag string,
+	socketID socket.ID,
+) error {
+	sock, err := socketID.Decode()
+	if err != nil {
+		return err
+	}
+	if !sock.IsHost() {
+		return fmt.Errorf("socket %s is not a host socket", socketID)
+	}
+
+	if container.FS == nil {
+		return errors.New("no container to export")
+	}
+	st, err := container.FSState()
+	if err != nil {
+		return err
+	}
+	def, err := st.Marshal(ctx, llb.Platform(container.Platform))
+	if err != nil {
+		return err
+	}
+	inputByPlatform := map[string]buildkit.ContainerExport{
+		platforms.Format(container.Platform): {
+			Definition: def.ToPB(),
+			Config:     container.Config,
+		},
+	}
+
+	opts := map[string]string{
+		"tar":                       strconv.FormatBool(true),
+		string(exptypes.OptKeyName): tag,
+	}
+
+	detach, _, err := svcs.StartBindings(ctx, bk, container.Services)
+	if err != nil {
+		return err
+	}
+	defer detach()
+
+	return bk.ContainerImageToDocker(ctx, socketID.String(), inputByPlatform, opts)
+}
+
+// FromRegistryMirror initializes the container from the image with the given
+// address like From, pulled through the registry mirrors configured by the
+// client into the OCI store, so they never apply to other clients' pulls.
+func (container *Container) FromRegistryMirror(
+	ctx context.Context,
+	bk *buildkit.Client,
+	store content.Store,
+	lm *leaseutil.Manager,
+	addr string,
+) (_ *Container, rerr error) {
+	container = container.Clone()
+
+	refName, err := reference.ParseNormalizedNamed(addr)
+	if err != nil {
+		return nil, err
+	}
+	ref := reference.TagNameOnly(refName).String()
+
+	ctx, release, err := leaseutil.WithLease(ctx, lm, leaseutil.MakeTemporary)
+	if err != nil {
+		return nil, err
+	}
+	var released bool
+	defer func() {
+		// don't pin the pulled content until GC if the pull failed
+		if rerr != nil && !released {
+			release(context.WithoutCancel(ctx))
+		}
+	}()
+
+	desc, err := bk.PullMirroredImage(ctx, ref, container.Platform, store)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := platforms.Only(container.Platform)
+	var manifestDesc *specs.Descriptor
+	findManifest := images.HandlerFunc(func(ctx context.Context, desc specs.Descriptor) ([]specs.Descriptor, error) {
+		if images.IsManifestType(desc.MediaType) {
+			manifestDesc = &desc
+			return nil, images.ErrStopHandler
+		}
+		return nil, nil
+	})
+	err = images.Walk(ctx, images.Handlers(
+		findManifest,
+		images.LimitManifests(images.FilterPlatforms(images.ChildrenHandler(store), matcher), matcher, 1),
+	), desc)
+	if err != nil {
+		return nil, err
+	}
+	if manifestDesc == nil {
+		return nil, fmt.Errorf("no manifest of %s for platform %s", ref, platforms.Format(container.Platform))
+	}
+
+	config := container.Config
+	container, err = container.withImportedManifest(ctx, bk, store, manifestDesc, func(ctx context.Context) error {
+		released = true
+		return release(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	container.Config = mergeImageConfig(config, container.Config)
+
+	digested, err := reference.WithDigest(refName, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	container.ImageRef = digested.String()
+
+	return container, nil
+}
+
+// FromDocker initializes the container from an image in the
+// Docker-compatible daemon listening on the given host socket. The image
+// tarball is streamed from the daemon into the OCI store.
+func (container *Container) FromDocker(
+	ctx context.Context,
+	tag string,
+	socketID socket.ID,
+	bk *buildkit.Client,
+	store content.Store,
+	lm *leaseutil.Manager,
+) (_ *Container, rerr error) {
+	sock, err := socketID.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if !sock.IsHost() {
+		return nil, fmt.Errorf("socket %s is not a host socket", socketID)
+	}
+
+	container = container.Clone()
+
+	src, err := bk.DockerImageStream(ctx, socketID.String(), tag)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	ctx, release, err := leaseutil.WithLease(ctx, lm, leaseutil.MakeTemporary)
+	if err != nil {
+		return nil, err
+	}
+	var released bool
+	defer func() {
+		// don't pin the imported content until GC if the import failed
+		if rerr != nil && !released {
+			release(context.WithoutCancel(ctx))
+		}
+	}()
+
+	desc, err := archive.NewImageImportStream(src, "").Import(ctx, store)
+	if err != nil {
+		return nil, fmt.Errorf("docker image import: %w", err)
+	}
+
+	manifestDesc, err := resolveIndex(ctx, store, desc, container.Platform, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return container.withImportedManifest(ctx, bk, store, manifestDesc, func(ctx context.Context) error {
+		released = true
+		return release(ctx)
+	})
+}
+
+// ContainerLayer describes a layer of the container's root filesystem as it
+// would appear in a published image.
+type ContainerLayer struct {
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	CreatedBy string `json:"createdBy"`
+}
+
+func (container *Container) Layers(ctx context.Context, bk *buildkit.Client, svcs *Services) ([]ContainerLayer, error) {
+	if container.FS == nil {
+		return []ContainerLayer{}, nil
+	}
+
+	detach, _, err := svcs.StartBindings(ctx, bk, container.Services)
+	if err != nil {
+		return nil, err
+	}
+	defer detach()
+
+	bkLayers, err := bk.ImageLayers(ctx, container.FS)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make([]ContainerLayer, 0, len(bkLayers))
+	for _, l := range bkLayers {
+		layers = append(layers, ContainerLayer{
+			Digest:    l.Descriptor.Digest.String(),
+			Size:      l.Descriptor.Size,
+			CreatedBy: l.Description,
+		})
+	}
+	return layers, nil
+}
+
+// WithSquashedLayers squashes the layers of the container's root filesystem
+// into a single layer. If base is given, only the layers added on top of
+// base's root filesystem are squashed, and base's layers are kept as-is.
+func (container *Container) WithSquashedLayers(ctx context.Context, base *Container) (*Container, error) {
+	container = container.Clone()
+
+	fsSt, err := container.FSState()
+	if err != nil {
+		return nil, err
+	}
+
+	var lowerSt *llb.State
+	if base != nil {
+		baseSt, err := base.FSState()
+		if err != nil {
+			return nil, err
+		}
+		lowerSt = &baseSt
+	}
+
+	def, err := squashLayers(lowerSt, fsSt).Marshal(ctx, llb.Platform(container.Platform))
+	if err != nil {
+		return nil, err
+	}
+	container.FS = def.ToPB()
+	// the previous boundary may have been squashed away
+	container.LayerBoundary = nil
+
+	return container, nil
+}
+
+// WithLayerBoundary squashes all operations performed since the previous
+// layer boundary into a single layer, and marks the current root filesystem
+// as the start of the next one.
+func (container *Container) WithLayerBoundary(ctx context.Context) (*Container, error) {
+	container = container.Clone()
+
+	fsSt, err := container.FSState()
+	if err != nil {
+		return nil, err
+	}
+
+	if container.LayerBoundary != nil {
+		boundarySt, err := defToState(container.LayerBoundary)
+		if err != nil {
+			return nil, err
+		}
+		fsSt = squashLayers(&boundarySt, fsSt)
+	}
+
+	def, err := fsSt.Marshal(ctx, llb.Platform(container.Platform))
+	if err != nil {
+		return nil, err
+	}
+	container.FS = def.ToPB()
+	container.LayerBoundary = container.FS
+
+	return container, nil
+}
+
+// squashLayers returns a state with the same contents as upper, but with all
+// the layers not in lower squashed into a single one. If lower is nil, the
+// whole filesystem is squashed.
+func squashLayers(lower *llb.State, upper llb.State) llb.State {
+	flat := llb.Scratch().File(
+		llb.Copy(upper, "/", "/", &llb.CopyInfo{
+			CopyDirContentsOnly: true,
+		}),
+		llb.WithCustomName(buildkit.InternalPrefix+"squash layers"),
+	)
+	if lower == nil {
+		return flat
+	}
+
+	// The flattened state shares no layers with lower, so diffing them makes
+	// buildkit compute a single layer holding every change, including
+	// whiteouts for anything removed since lower.
+	return llb.Merge(
+		[]llb.State{*lower, llb.Diff(*lower, flat)},
+		llb.WithCustomName(buildkit.InternalPrefix+"merge squashed layers"),
+	)
+}
+
 func (container *Container) WithExposedPort(port Port) (*Container, error) {
 	container = container.Clone()
 
@@ -22,6 +22,7 @@ import (
 	"github.com/vito/progrock"
 
 	"github.com/dagger/dagger/core/pipeline"
+	"github.com/dagger/dagger/core/reffs"
 	"github.com/dagger/dagger/core/resourceid"
 	"github.com/dagger/dagger/engine/buildkit"
 )
@@ -212,7 +213,69 @@ func (dir *Directory) Stat(ctx context.Context, bk *buildkit.Client, svcs *Servi
 	})
 }
 
-func (dir *Directory) Entries(ctx context.Context, bk *buildkit.Client, svcs *Services, src string) ([]string, error) {
+// FileInfo describes an entry of a directory.
+type FileInfo struct {
+	Path          string   `json:"path"`
+	Kind          FileKind `json:"kind"`
+	Size          int      `json:"size"`
+	Permissions   int      `json:"permissions"`
+	UID           int      `json:"uid"`
+	GID           int      `json:"gid"`
+	ModTime       int      `json:"modTime"`
+	SymlinkTarget string   `json:"symlinkTarget"`
+}
+
+// FileKind is the kind of a directory entry.
+type FileKind string
+
+const (
+	FileKindFile      FileKind = "ENTRY_FILE"
+	FileKindDirectory FileKind = "ENTRY_DIRECTORY"
+	FileKindSymlink   FileKind = "ENTRY_SYMLINK"
+	FileKindOther     FileKind = "ENTRY_OTHER"
+)
+
+func newFileInfo(entryPath string, stat *fstypes.Stat) FileInfo {
+	mode := fs.FileMode(stat.Mode)
+	kind := FileKindOther
+	switch {
+	case mode.IsRegular():
+		kind = FileKindFile
+	case mode.IsDir():
+		kind = FileKindDirectory
+	case mode&fs.ModeSymlink != 0:
+		kind = FileKindSymlink
+	}
+	return FileInfo{
+		Path:          entryPath,
+		Kind:          kind,
+		Size:          int(stat.Size_),
+		Permissions:   int(mode.Perm()),
+		UID:           int(stat.Uid),
+		GID:           int(stat.Gid),
+		ModTime:       int(time.Duration(stat.ModTime) / time.Second),
+		SymlinkTarget: stat.Linkname,
+	}
+}
+
+func (dir *Directory) Entries(ctx context.Context, bk *buildkit.Client, svcs *Services, src string, recursive bool) ([]string, error) {
+	infos, err := dir.EntryInfos(ctx, bk, svcs, src, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, info := range infos {
+		paths = append(paths, info.Path)
+	}
+
+	return paths, nil
+}
+
+// EntryInfos returns the entries of the given subdirectory along with their
+// metadata. Recursive listings include the entries of every subdirectory,
+// with paths relative to the given one.
+func (dir *Directory) EntryInfos(ctx context.Context, bk *buildkit.Client, svcs *Services, src string, recursive bool) ([]FileInfo, error) {
 	src = path.Join(dir.Dir, src)
 
 	detach, _, err := svcs.StartBindings(ctx, bk, dir.Services)
@@ -235,24 +298,193 @@ func (dir *Directory) Entries(ctx context.Context, bk *buildkit.Client, svcs *Se
 	// empty directory, i.e. llb.Scratch()
 	if ref == nil {
 		if clean := path.Clean(src); clean == "." || clean == "/" {
-			return []string{}, nil
+			return []FileInfo{}, nil
 		}
 		return nil, fmt.Errorf("%s: no such file or directory", src)
 	}
 
-	entries, err := ref.ReadDir(ctx, bkgw.ReadDirRequest{
-		Path: src,
+	infos := []FileInfo{}
+	var readDir func(rel string) error
+	readDir = func(rel string) error {
+		entries, err := ref.ReadDir(ctx, bkgw.ReadDirRequest{
+			Path: path.Join(src, rel),
+		})
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			entryPath := path.Join(rel, entry.GetPath())
+			infos = append(infos, newFileInfo(entryPath, entry))
+			if recursive && entry.IsDir() {
+				if err := readDir(entryPath); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := readDir(""); err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+// ContentDigest returns a hash of the directory's contents, which doesn't
+// depend on how they were produced, nor on their timestamps.
+func (dir *Directory) ContentDigest(ctx context.Context, bk *buildkit.Client, svcs *Services) (digest.Digest, error) {
+	detach, _, err := svcs.StartBindings(ctx, bk, dir.Services)
+	if err != nil {
+		return "", err
+	}
+	defer detach()
+
+	return bk.DirectoryDigest(ctx, dir.LLB, path.Join("/", dir.Dir))
+}
+
+// Search returns the lines of the directory's files that match the given
+// pattern, in the given paths (the whole directory by default). Files are
+// read through the buildkit ref without starting a container, and binary
+// files are skipped.
+func (dir *Directory) Search(ctx context.Context, bk *buildkit.Client, svcs *Services, pattern string, regex bool, paths []string, filter CopyFilter) ([]SearchMatch, error) {
+	match, err := newLineMatcher(pattern, regex)
+	if err != nil {
+		return nil, err
+	}
+
+	var includes, excludes *patternmatcher.PatternMatcher
+	if len(filter.Include) > 0 {
+		includes, err = patternmatcher.New(filter.Include)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include patterns: %w", err)
+		}
+	}
+	if len(filter.Exclude) > 0 {
+		excludes, err = patternmatcher.New(filter.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude patterns: %w", err)
+		}
+	}
+
+	detach, _, err := svcs.StartBindings(ctx, bk, dir.Services)
+	if err != nil {
+		return nil, err
+	}
+	defer detach()
+
+	res, err := bk.Solve(ctx, bkgw.SolveRequest{
+		Definition: dir.LLB,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	paths := []string{}
-	for _, entry := range entries {
-		paths = append(paths, entry.GetPath())
+	ref, err := res.SingleRef()
+	if err != nil {
+		return nil, err
 	}
 
-	return paths, nil
+	matches := []SearchMatch{}
+	// empty directory, i.e. llb.Scratch()
+	if ref == nil {
+		return matches, nil
+	}
+
+	excluded := func(rel string) (bool, error) {
+		if excludes == nil {
+			return false, nil
+		}
+		return excludes.MatchesOrParentMatches(rel)
+	}
+
+	searched := map[string]struct{}{}
+	searchFile := func(rel string) error {
+		if _, ok := searched[rel]; ok {
+			return nil
+		}
+		searched[rel] = struct{}{}
+
+		if includes != nil {
+			included, err := includes.MatchesOrParentMatches(rel)
+			if err != nil || !included {
+				return err
+			}
+		}
+		if skip, err := excluded(rel); err != nil || skip {
+			return err
+		}
+
+		f, err := reffs.OpenFile(ctx, ref, path.Join(dir.Dir, rel))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fileMatches, err := searchLines(rel, f, match)
+		if err != nil {
+			return fmt.Errorf("search %s: %w", rel, err)
+		}
+		matches = append(matches, fileMatches...)
+		return nil
+	}
+
+	var searchDir func(rel string) error
+	searchDir = func(rel string) error {
+		entries, err := ref.ReadDir(ctx, bkgw.ReadDirRequest{
+			Path: path.Join(dir.Dir, rel),
+		})
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			entryPath := path.Join(rel, entry.GetPath())
+			mode := fs.FileMode(entry.Mode)
+			switch {
+			case mode.IsDir():
+				if skip, err := excluded(entryPath); err != nil || skip {
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				if err := searchDir(entryPath); err != nil {
+					return err
+				}
+			case mode.IsRegular():
+				if err := searchFile(entryPath); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	for _, p := range paths {
+		rel := path.Clean(p)
+		stat, err := ref.StatFile(ctx, bkgw.StatRequest{
+			Path: path.Join(dir.Dir, rel),
+		})
+		if err != nil {
+			return nil, err
+		}
+		mode := fs.FileMode(stat.Mode)
+		switch {
+		case mode.IsDir():
+			err = searchDir(rel)
+		case mode.IsRegular():
+			err = searchFile(rel)
+		default:
+			err = fmt.Errorf("%s is neither a file nor a directory", p)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return matches, nil
 }
 
 // Glob returns a list of files that matches the given pattern.
@@ -545,6 +777,193 @@ func mergeStates(input mergeStateInput) llb.State {
 	return llb.Merge(mergeStates, llb.WithCustomName(buildkit.InternalPrefix+"merge"))
 }
 
+// MergeConflict tells what to do when more than one of the directories given
+// to MergeDirectories has something at the same path.
+type MergeConflict string
+
+const (
+	MergeConflictError    MergeConflict = "MERGE_ERROR"
+	MergeConflictLastWins MergeConflict = "MERGE_LAST_WINS"
+)
+
+// MergeDirectories merges the given directories into a new one with a single
+// MergeOp. Later directories win over earlier ones, unless conflict is
+// MergeConflictError, in which case any file written by more than one of
+// them is an error. Detecting conflicts requires evaluating every directory.
+func MergeDirectories(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	dirs []*Directory,
+	conflict MergeConflict,
+	pipeline pipeline.Path,
+	platform specs.Platform,
+) (*Directory, error) {
+	merged := NewScratchDirectory(pipeline, platform)
+	if len(dirs) == 0 {
+		return merged, nil
+	}
+
+	switch conflict {
+	case "", MergeConflictLastWins:
+	case MergeConflictError:
+		if err := checkMergeConflicts(ctx, bk, svcs, dirs); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown merge conflict mode %q", conflict)
+	}
+
+	states := make([]llb.State, 0, len(dirs))
+	for _, dir := range dirs {
+		st, err := dir.State()
+		if err != nil {
+			return nil, err
+		}
+		if path.Join("/", dir.Dir) != "/" {
+			// MergeOp only merges the "/" of states, so copy subdirectories
+			// to the root of a scratch state first, like mergeStates does
+			st = llb.Scratch().File(llb.Copy(st, dir.Dir, "/", &llb.CopyInfo{
+				CopyDirContentsOnly: true,
+			}))
+		}
+		states = append(states, st)
+		merged.Services.Merge(dir.Services)
+	}
+
+	if err := merged.SetState(ctx, llb.Merge(states, llb.WithCustomName(buildkit.InternalPrefix+"merge directories"))); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// checkMergeConflicts returns an error listing every path that more than one
+// of the given directories has something other than a directory at.
+func checkMergeConflicts(ctx context.Context, bk *buildkit.Client, svcs *Services, dirs []*Directory) error {
+	type owner struct {
+		index int
+		isDir bool
+	}
+	owners := map[string]owner{}
+
+	var conflicts []string
+	for i, dir := range dirs {
+		infos, err := dir.EntryInfos(ctx, bk, svcs, ".", true)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			isDir := info.Kind == FileKindDirectory
+			prev, ok := owners[info.Path]
+			if !ok {
+				owners[info.Path] = owner{index: i, isDir: isDir}
+				continue
+			}
+			if prev.isDir && isDir {
+				continue
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s (directories %d and %d)", info.Path, prev.index, i))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("merge conflict: %d paths written by more than one directory:\n%s", len(conflicts), strings.Join(conflicts, "\n"))
+	}
+	return nil
+}
+
+// WithSymlink returns the directory with a symlink at the given path pointing
+// to the given target, replacing anything already at that path.
+func (dir *Directory) WithSymlink(ctx context.Context, bk *buildkit.Client, target, linkName string) (*Directory, error) {
+	dir = dir.Clone()
+
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+
+	linkDef, err := bk.EngineContainerSymlink(ctx, dir.Platform, target)
+	if err != nil {
+		return nil, err
+	}
+	linkSt, err := defToState(linkDef)
+	if err != nil {
+		return nil, err
+	}
+
+	linkPath := path.Join(dir.Dir, linkName)
+	err = dir.SetState(ctx, st.File(
+		llb.Rm(linkPath, llb.WithAllowNotFound(true)).
+			Copy(linkSt, buildkit.SymlinkName, linkPath, &llb.CopyInfo{
+				CreateDestPath: true,
+			}),
+		llb.WithCustomNamef("%ssymlink %s -> %s", buildkit.InternalPrefix, linkName, target),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return dir, nil
+}
+
+// WithPermissions returns the directory with the permissions of the entry at
+// the given path changed, along with everything under it if recursive.
+func (dir *Directory) WithPermissions(ctx context.Context, subpath string, permissions fs.FileMode, recursive bool) (*Directory, error) {
+	return dir.withMetadata(ctx, subpath, recursive, &llb.CopyInfo{
+		Mode: &permissions,
+	})
+}
+
+// WithOwner returns the directory with the ownership of the entry at the
+// given path changed, along with everything under it if recursive.
+func (dir *Directory) WithOwner(ctx context.Context, subpath string, owner Ownership, recursive bool) (*Directory, error) {
+	copyInfo := &llb.CopyInfo{}
+	owner.Opt().SetCopyOption(copyInfo)
+	return dir.withMetadata(ctx, subpath, recursive, copyInfo)
+}
+
+// withMetadata copies the entry at the given path onto itself with the given
+// mode and ownership options, so that they apply to it, and to everything
+// under it if recursive.
+func (dir *Directory) withMetadata(ctx context.Context, subpath string, recursive bool, copyInfo *llb.CopyInfo) (*Directory, error) {
+	dir = dir.Clone()
+
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+
+	target := path.Join("/", dir.Dir, subpath)
+	if target == "/" {
+		if !recursive {
+			return nil, fmt.Errorf("cannot change the root directory itself, only its contents recursively")
+		}
+		// the root can't be removed and copied onto itself, so apply the
+		// options while copying its contents to scratch instead
+		copyInfo.CopyDirContentsOnly = true
+		st = llb.Scratch().File(llb.Copy(st, "/", "/", copyInfo))
+	} else {
+		if !recursive {
+			// only the entry itself gets the options; the contents of a
+			// directory are then copied back as they were
+			copyInfo.ExcludePatterns = []string{"*"}
+		}
+		action := llb.Rm(target).Copy(st, target, target, copyInfo)
+		if !recursive {
+			action = action.Copy(st, path.Join(target, "*"), target+"/", &llb.CopyInfo{
+				AllowWildcard:      true,
+				AllowEmptyWildcard: true,
+			})
+		}
+		st = st.File(action)
+	}
+
+	if err := dir.SetState(ctx, st); err != nil {
+		return nil, err
+	}
+
+	return dir, nil
+}
+
 func (dir *Directory) WithTimestamps(ctx context.Context, unix int) (*Directory, error) {
 	dir = dir.Clone()
 
@@ -632,6 +1051,208 @@ func (dir *Directory) Diff(ctx context.Context, other *Directory) (*Directory, e
 	return dir, nil
 }
 
+// WithPatch applies a unified diff to the directory, removing the given
+// number of leading components from the paths of the patch. Either every hunk
+// applies or none do, in which case the error reports each rejected hunk.
+func (dir *Directory) WithPatch(ctx context.Context, bk *buildkit.Client, svcs *Services, patch string, strip int) (*Directory, error) {
+	dir = dir.Clone()
+
+	if strip < 0 {
+		return nil, fmt.Errorf("strip must be positive, got %d", strip)
+	}
+
+	files, err := parsePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse patch: %w", err)
+	}
+
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+
+	var action *llb.FileAction
+	var rejects []string
+	for _, fp := range files {
+		oldPath, err := stripPatchPath(fp.OldPath, strip)
+		if err != nil {
+			return nil, err
+		}
+		newPath, err := stripPatchPath(fp.NewPath, strip)
+		if err != nil {
+			return nil, err
+		}
+
+		name := newPath
+		if name == "" {
+			name = oldPath
+		}
+		name = strings.TrimPrefix(name, "/")
+
+		var contents []byte
+		permissions := fs.FileMode(0o644)
+		var owner *Ownership
+		if oldPath != "" {
+			stat, err := dir.Stat(ctx, bk, svcs, oldPath)
+			if err != nil {
+				rejects = append(rejects, fmt.Sprintf("%s: file to patch does not exist", name))
+				continue
+			}
+			if stat.IsDir() {
+				rejects = append(rejects, fmt.Sprintf("%s: file to patch is a directory", name))
+				continue
+			}
+			file, err := dir.File(ctx, bk, svcs, oldPath)
+			if err != nil {
+				return nil, err
+			}
+			contents, err = file.Contents(ctx, bk, svcs)
+			if err != nil {
+				return nil, err
+			}
+			permissions = fs.FileMode(stat.Mode).Perm()
+			owner = &Ownership{UID: int(stat.Uid), GID: int(stat.Gid)}
+		} else if _, err := dir.Stat(ctx, bk, svcs, newPath); err == nil {
+			rejects = append(rejects, fmt.Sprintf("%s: file to create already exists", name))
+			continue
+		}
+
+		patched, fileRejects := fp.apply(string(contents))
+		for _, reject := range fileRejects {
+			rejects = append(rejects, fmt.Sprintf("%s: %s", name, reject))
+		}
+		if len(fileRejects) > 0 {
+			continue
+		}
+
+		if oldPath != "" {
+			action = action.Rm(path.Join(dir.Dir, oldPath))
+		}
+		if newPath == "" {
+			continue
+		}
+		if fp.NewMode != 0 {
+			permissions = fp.NewMode
+		}
+		dest := path.Join(dir.Dir, newPath)
+		if oldPath != newPath {
+			action = action.Mkdir(path.Dir(dest), 0o755, llb.WithParents(true))
+		}
+		opts := []llb.MkfileOption{}
+		if owner != nil {
+			opts = append(opts, owner.Opt())
+		}
+		action = action.Mkfile(dest, permissions, []byte(patched), opts...)
+	}
+
+	if len(rejects) > 0 {
+		return nil, fmt.Errorf("patch does not apply:\n\n%s", strings.Join(rejects, "\n\n"))
+	}
+	if action == nil {
+		return dir, nil
+	}
+
+	err = dir.SetState(ctx, st.File(action))
+	if err != nil {
+		return nil, err
+	}
+
+	return dir, nil
+}
+
+// WithTemplates renders the files matching the given pattern as Go
+// text/templates with the given data, in place. The given secrets can be read
+// by name from the templates, and are kept out of the resulting directory's
+// ID.
+func (dir *Directory) WithTemplates(ctx context.Context, bk *buildkit.Client, svcs *Services, pattern string, data any, delims []string, secrets map[string][]byte) (*Directory, error) {
+	dir = dir.Clone()
+
+	matcher, err := patternmatcher.New([]string{pattern})
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	infos, err := dir.EntryInfos(ctx, bk, svcs, ".", true)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &templateRender{Data: data, Delims: delims, Secrets: secrets}
+
+	// the templates are rendered to a separate state first, so that it can be
+	// turned into a blob when they read secrets
+	var rendered *llb.FileAction
+	var renderedPaths []string
+	for _, info := range infos {
+		if info.Kind != FileKindFile {
+			continue
+		}
+		match, err := matcher.MatchesOrParentMatches(info.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+
+		file, err := dir.File(ctx, bk, svcs, info.Path)
+		if err != nil {
+			return nil, err
+		}
+		contents, err := file.Contents(ctx, bk, svcs)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := r.render(info.Path, contents)
+		if err != nil {
+			return nil, fmt.Errorf("render %s: %w", info.Path, err)
+		}
+
+		dest := path.Join("/", info.Path)
+		if parent := path.Dir(dest); parent != "/" {
+			rendered = rendered.Mkdir(parent, 0o755, llb.WithParents(true))
+		}
+		rendered = rendered.Mkfile(dest, fs.FileMode(info.Permissions), out, llb.WithUIDGID(info.UID, info.GID))
+		renderedPaths = append(renderedPaths, dest)
+	}
+	if rendered == nil {
+		return dir, nil
+	}
+
+	renderedDef, err := llb.Scratch().File(rendered).Marshal(ctx, llb.Platform(dir.Platform))
+	if err != nil {
+		return nil, err
+	}
+	renderedPB := renderedDef.ToPB()
+	if r.usedSecrets {
+		renderedPB, err = bk.DefToBlob(ctx, renderedPB)
+		if err != nil {
+			return nil, err
+		}
+	}
+	renderedSt, err := defToState(renderedPB)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := dir.State()
+	if err != nil {
+		return nil, err
+	}
+	var action *llb.FileAction
+	for _, p := range renderedPaths {
+		action = action.Copy(renderedSt, p, path.Join(dir.Dir, p))
+	}
+
+	err = dir.SetState(ctx, st.File(action))
+	if err != nil {
+		return nil, err
+	}
+
+	return dir, nil
+}
+
 func (dir *Directory) Without(ctx context.Context, path string) (*Directory, error) {
 	dir = dir.Clone()
 
@@ -691,6 +1312,36 @@ func (dir *Directory) Export(
 	return bk.LocalDirExport(ctx, defPB, destPath)
 }
 
+// AsArchive returns a file containing the directory archived in the given
+// format. In reproducible mode, the same contents always produce the same
+// archive.
+func (dir *Directory) AsArchive(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	engineHostPlatform specs.Platform,
+	format ArchiveFormat,
+	reproducible bool,
+) (*File, error) {
+	archiveFormat, ok := archiveFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+
+	detach, _, err := svcs.StartBindings(ctx, bk, dir.Services)
+	if err != nil {
+		return nil, err
+	}
+	defer detach()
+
+	fileName := "archive" + archiveFormat.extension
+	pbDef, err := bk.DirectoryToArchive(ctx, engineHostPlatform, dir.LLB, dir.Dir, fileName, archiveFormat.format, reproducible)
+	if err != nil {
+		return nil, fmt.Errorf("directory to archive conversion failed: %w", err)
+	}
+	return NewFile(ctx, pbDef, fileName, dir.Pipeline, engineHostPlatform, nil), nil
+}
+
 // Root removes any relative path from the directory.
 func (dir *Directory) Root() (*Directory, error) {
 	dir = dir.Clone()
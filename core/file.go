@@ -1,12 +1,12 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/fs"
-
-	"io"
 	"path"
+	"regexp"
 	"time"
 
 	"github.com/moby/buildkit/client/llb"
@@ -190,6 +190,41 @@ func (file *File) Contents(ctx context.Context, bk *buildkit.Client, svcs *Servi
 	return contents, nil
 }
 
+// Read returns at most length bytes of the file's contents, starting at the
+// given offset, so that files exceeding MaxFileContentsSize can be read in
+// chunks. The length is capped to MaxFileContentsChunkSize, and defaults to
+// it when zero. Fewer bytes are returned at the end of the file.
+func (file *File) Read(ctx context.Context, bk *buildkit.Client, svcs *Services, offset, length int) ([]byte, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("length must not be negative")
+	}
+	if length == 0 || length > buildkit.MaxFileContentsChunkSize {
+		length = buildkit.MaxFileContentsChunkSize
+	}
+
+	detach, _, err := svcs.StartBindings(ctx, bk, file.Services)
+	if err != nil {
+		return nil, err
+	}
+	defer detach()
+
+	ref, err := bkRef(ctx, bk, file.LLB)
+	if err != nil {
+		return nil, err
+	}
+
+	return ref.ReadFile(ctx, bkgw.ReadRequest{
+		Filename: file.File,
+		Range: &bkgw.FileRange{
+			Offset: offset,
+			Length: length,
+		},
+	})
+}
+
 func (file *File) Stat(ctx context.Context, bk *buildkit.Client, svcs *Services) (*fstypes.Stat, error) {
 	detach, _, err := svcs.StartBindings(ctx, bk, file.Services)
 	if err != nil {
@@ -207,6 +242,32 @@ func (file *File) Stat(ctx context.Context, bk *buildkit.Client, svcs *Services)
 	})
 }
 
+// Name returns the base name of the file.
+func (file *File) Name() string {
+	return path.Base(file.File)
+}
+
+// Permissions returns the Unix permission bits of the file.
+func (file *File) Permissions(ctx context.Context, bk *buildkit.Client, svcs *Services) (int, error) {
+	st, err := file.Stat(ctx, bk, svcs)
+	if err != nil {
+		return 0, err
+	}
+	return int(fs.FileMode(st.Mode).Perm()), nil
+}
+
+// ContentDigest returns the SHA-256 digest of the file's contents, which
+// doesn't depend on how they were produced.
+func (file *File) ContentDigest(ctx context.Context, bk *buildkit.Client, svcs *Services) (digest.Digest, error) {
+	detach, _, err := svcs.StartBindings(ctx, bk, file.Services)
+	if err != nil {
+		return "", err
+	}
+	defer detach()
+
+	return bk.FileDigest(ctx, file.LLB, file.File)
+}
+
 func (file *File) WithTimestamps(ctx context.Context, unix int) (*File, error) {
 	file = file.Clone()
 
@@ -229,19 +290,232 @@ func (file *File) WithTimestamps(ctx context.Context, unix int) (*File, error) {
 	return file, nil
 }
 
-func (file *File) Open(ctx context.Context, host *Host, bk *buildkit.Client, svcs *Services) (io.ReadCloser, error) {
+// Unpack returns a directory containing the extracted contents of the
+// archive. If no format is given, it is detected from the file's contents.
+func (file *File) Unpack(ctx context.Context, bk *buildkit.Client, svcs *Services, format ArchiveFormat) (*Directory, error) {
 	detach, _, err := svcs.StartBindings(ctx, bk, file.Services)
 	if err != nil {
 		return nil, err
 	}
 	defer detach()
 
-	fs, err := reffs.OpenDef(ctx, bk, file.LLB)
+	ref, err := bkRef(ctx, bk, file.LLB)
+	if err != nil {
+		return nil, err
+	}
+	header, err := ref.ReadFile(ctx, bkgw.ReadRequest{
+		Filename: file.File,
+		Range: &bkgw.FileRange{
+			Length: archiveHeaderSize,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	detected, err := DetectArchiveFormat(header)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.File, err)
+	}
+	if format != "" && format != detected {
+		return nil, fmt.Errorf("%s: expected a %s archive, got %s", file.File, format, detected)
+	}
+
+	if detected == ArchiveZip {
+		pbDef, err := bk.UnpackZip(ctx, file.Platform, file.LLB, file.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack zip archive: %w", err)
+		}
+		return NewDirectory(ctx, pbDef, "/", file.Pipeline, file.Platform, nil), nil
+	}
+
+	st, err := file.State()
+	if err != nil {
+		return nil, err
+	}
+	// buildkit decompresses and extracts tarballs natively when copying them
+	unpacked := llb.Scratch().File(llb.Copy(st, file.File, "/", &llb.CopyInfo{
+		AttemptUnpack:  true,
+		CreateDestPath: true,
+	}))
+	return NewDirectorySt(ctx, unpacked, "/", file.Pipeline, file.Platform, file.Services)
+}
+
+// WithPermissions returns the file with its permissions changed.
+func (file *File) WithPermissions(ctx context.Context, permissions fs.FileMode) (*File, error) {
+	file = file.Clone()
+
+	st, err := file.State()
+	if err != nil {
+		return nil, err
+	}
+
+	chmodded := llb.Scratch().File(llb.Copy(st, file.File, ".", &llb.CopyInfo{
+		Mode: &permissions,
+	}))
+
+	def, err := chmodded.Marshal(ctx, llb.Platform(file.Platform))
+	if err != nil {
+		return nil, err
+	}
+	file.LLB = def.ToPB()
+	file.File = path.Base(file.File)
+
+	return file, nil
+}
+
+// WithReplaced returns the file with occurrences of search replaced. With
+// regex, search is a regular expression and replace may refer to its
+// submatches like $1. It is an error for search not to match.
+func (file *File) WithReplaced(ctx context.Context, bk *buildkit.Client, svcs *Services, search, replace string, all, regex bool) (*File, error) {
+	file = file.Clone()
+
+	contents, err := file.Contents(ctx, bk, svcs)
 	if err != nil {
 		return nil, err
 	}
+	stat, err := file.Stat(ctx, bk, svcs)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced, err := replaceContents(contents, search, replace, all, regex)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.File, err)
+	}
+
+	name := path.Base(file.File)
+	st := llb.Scratch().File(llb.Mkfile(
+		name,
+		fs.FileMode(stat.Mode).Perm(),
+		replaced,
+		llb.WithUIDGID(int(stat.Uid), int(stat.Gid)),
+	))
+
+	def, err := st.Marshal(ctx, llb.Platform(file.Platform))
+	if err != nil {
+		return nil, err
+	}
+	file.LLB = def.ToPB()
+	file.File = name
+
+	return file, nil
+}
+
+// WithTemplate renders the file as a Go text/template with the given data.
+// The given secrets can be read by name from the template, and are kept out
+// of the resulting file's ID.
+func (file *File) WithTemplate(ctx context.Context, bk *buildkit.Client, svcs *Services, data any, delims []string, secrets map[string][]byte) (*File, error) {
+	file = file.Clone()
+
+	contents, err := file.Contents(ctx, bk, svcs)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat(ctx, bk, svcs)
+	if err != nil {
+		return nil, err
+	}
+
+	name := path.Base(file.File)
+	r := &templateRender{Data: data, Delims: delims, Secrets: secrets}
+	rendered, err := r.render(name, contents)
+	if err != nil {
+		return nil, fmt.Errorf("render %s: %w", file.File, err)
+	}
+
+	st := llb.Scratch().File(llb.Mkfile(
+		name,
+		fs.FileMode(stat.Mode).Perm(),
+		rendered,
+		llb.WithUIDGID(int(stat.Uid), int(stat.Gid)),
+	))
+
+	def, err := st.Marshal(ctx, llb.Platform(file.Platform))
+	if err != nil {
+		return nil, err
+	}
+	file.LLB = def.ToPB()
+	file.File = name
+
+	if r.usedSecrets {
+		file.LLB, err = bk.DefToBlob(ctx, file.LLB)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+func replaceContents(contents []byte, search, replace string, all, regex bool) ([]byte, error) {
+	if search == "" {
+		return nil, fmt.Errorf("search must not be empty")
+	}
+
+	if !regex {
+		if !bytes.Contains(contents, []byte(search)) {
+			return nil, fmt.Errorf("search %q not found", search)
+		}
+		n := 1
+		if all {
+			n = -1
+		}
+		return bytes.Replace(contents, []byte(search), []byte(replace), n), nil
+	}
+
+	re, err := regexp.Compile(search)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	match := re.FindSubmatchIndex(contents)
+	if match == nil {
+		return nil, fmt.Errorf("regex %q did not match", search)
+	}
+	if all {
+		return re.ReplaceAll(contents, []byte(replace)), nil
+	}
+	replaced := make([]byte, 0, len(contents))
+	replaced = append(replaced, contents[:match[0]]...)
+	replaced = re.Expand(replaced, []byte(replace), contents, match)
+	replaced = append(replaced, contents[match[1]:]...)
+	return replaced, nil
+}
+
+// Open returns a reader of the file's contents, which streams them in chunks
+// regardless of their size. Closing it releases the services the file depends
+// on.
+func (file *File) Open(ctx context.Context, host *Host, bk *buildkit.Client, svcs *Services) (*FileReader, error) {
+	detach, _, err := svcs.StartBindings(ctx, bk, file.Services)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := bkRef(ctx, bk, file.LLB)
+	if err != nil {
+		detach()
+		return nil, err
+	}
+
+	f, err := reffs.OpenFile(ctx, ref, file.File)
+	if err != nil {
+		detach()
+		return nil, err
+	}
+
+	return &FileReader{File: f, detach: detach}, nil
+}
+
+// FileReader reads the contents of a file, keeping the services it depends on
+// running until closed.
+type FileReader struct {
+	*reffs.File
+
+	detach func()
+}
 
-	return fs.Open(file.File)
+func (r *FileReader) Close() error {
+	r.detach()
+	return r.File.Close()
 }
 
 func (file *File) Export(
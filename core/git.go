@@ -2,14 +2,22 @@ package core
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dagger/dagger/core/pipeline"
+	"github.com/dagger/dagger/core/reffs"
 	"github.com/dagger/dagger/core/resourceid"
 	"github.com/dagger/dagger/core/socket"
 	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/engine/buildkit"
 	"github.com/dagger/dagger/engine/sources/gitdns"
 	"github.com/moby/buildkit/client/llb"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 )
@@ -22,6 +30,12 @@ type GitRepository struct {
 	SSHKnownHosts string    `json:"sshKnownHosts"`
 	SSHAuthSocket socket.ID `json:"sshAuthSocket"`
 
+	// HTTPAuthToken and HTTPAuthHeader are secrets to authenticate with over
+	// HTTPS, as a token or as a whole Authorization header. Only their IDs are
+	// ever recorded, never their plaintext.
+	HTTPAuthToken  SecretID `json:"httpAuthToken,omitempty"`
+	HTTPAuthHeader SecretID `json:"httpAuthHeader,omitempty"`
+
 	Services ServiceBindings `json:"services"`
 	Pipeline pipeline.Path   `json:"pipeline"`
 	Platform specs.Platform  `json:"platform,omitempty"`
@@ -31,22 +45,124 @@ func (repo *GitRepository) ID() (GitRepositoryID, error) {
 	return resourceid.Encode(repo)
 }
 
+// GitLogEntry is a commit listed by GitRef.log.
+type GitLogEntry struct {
+	Commit      string `json:"commit"`
+	AuthorName  string `json:"authorName"`
+	AuthorEmail string `json:"authorEmail"`
+	Date        string `json:"date"`
+	Subject     string `json:"subject"`
+	Message     string `json:"message"`
+}
+
+// parseGitLog parses the commits listed by a git log query.
+func parseGitLog(out []byte) ([]GitLogEntry, error) {
+	entries := []GitLogEntry{}
+	for _, record := range strings.Split(string(out), "\x00") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\n", 5)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("malformed git log entry %q", record)
+		}
+		date, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed git log date %q: %w", fields[3], err)
+		}
+		entry := GitLogEntry{
+			Commit:      fields[0],
+			AuthorName:  fields[1],
+			AuthorEmail: fields[2],
+			Date:        time.Unix(date, 0).UTC().Format(time.RFC3339),
+		}
+		if len(fields) == 5 {
+			entry.Message = strings.TrimRight(fields[4], "\n")
+			entry.Subject, _, _ = strings.Cut(entry.Message, "\n")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// gitRemoteRefs are the refs of a remote repository.
+type gitRemoteRefs struct {
+	// Head is the ref HEAD points to (e.g., "refs/heads/main").
+	Head string
+
+	// Refs are every ref but HEAD, with the commits they point to. Annotated
+	// tags point to the commit they're peeled to.
+	Refs []gitRemoteRef
+}
+
+type gitRemoteRef struct {
+	Name   string
+	Commit string
+}
+
+// parseGitRemoteRefs parses the output of "git ls-remote --symref".
+func parseGitRemoteRefs(out []byte) *gitRemoteRefs {
+	refs := &gitRemoteRefs{}
+	index := map[string]int{}
+	for _, line := range strings.Split(string(out), "\n") {
+		target, name, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		if symref, ok := strings.CutPrefix(target, "ref: "); ok {
+			if name == "HEAD" {
+				refs.Head = symref
+			}
+			continue
+		}
+		if name == "HEAD" {
+			continue
+		}
+		if peeled, ok := strings.CutSuffix(name, "^{}"); ok {
+			if i, ok := index[peeled]; ok {
+				refs.Refs[i].Commit = target
+			}
+			continue
+		}
+		index[name] = len(refs.Refs)
+		refs.Refs = append(refs.Refs, gitRemoteRef{Name: name, Commit: target})
+	}
+	return refs
+}
+
 type GitRef struct {
 	Ref  string         `json:"ref"`
 	Repo *GitRepository `json:"repository"`
+
+	// Local is set for commits created by Directory.asGitCommit, which aren't
+	// in any repository yet.
+	Local *GitLocalCommit `json:"local,omitempty"`
 }
 
 func (ref *GitRef) ID() (GitRefID, error) {
 	return resourceid.Encode(ref)
 }
 
-func (ref *GitRef) Tree(ctx context.Context, bk *buildkit.Client) (*Directory, error) {
-	st := ref.getState(ctx, bk)
+func (ref *GitRef) Tree(ctx context.Context, bk *buildkit.Client, checkout gitdns.Checkout) (*Directory, error) {
+	if ref.Local != nil {
+		return ref.Local.Directory, nil
+	}
+	st := ref.getState(ctx, bk, checkout)
 	return NewDirectorySt(ctx, *st, "", ref.Repo.Pipeline, ref.Repo.Platform, ref.Repo.Services)
 }
 
-func (ref *GitRef) Commit(ctx context.Context, bk *buildkit.Client) (string, error) {
-	st := ref.getState(ctx, bk)
+func (ref *GitRef) Commit(ctx context.Context, bk *buildkit.Client, svcs *Services) (string, error) {
+	if ref.Local != nil {
+		var commit string
+		err := ref.withGitDir(ctx, bk, svcs, func(_, localCommit string) error {
+			commit = localCommit
+			return nil
+		})
+		return commit, err
+	}
+
+	st := ref.getState(ctx, bk, gitdns.Checkout{})
 	p, err := resolveProvenance(ctx, bk, *st)
 	if err != nil {
 		return "", err
@@ -57,20 +173,186 @@ func (ref *GitRef) Commit(ctx context.Context, bk *buildkit.Client) (string, err
 	return p.Sources.Git[0].Commit, nil
 }
 
-func (ref *GitRef) getState(ctx context.Context, bk *buildkit.Client) *llb.State {
+// Log returns the commits reachable from the ref, newest first, excluding
+// the ones reachable from since, if set.
+func (ref *GitRef) Log(ctx context.Context, bk *buildkit.Client, svcs *Services, since string) ([]GitLogEntry, error) {
+	if ref.Local != nil {
+		return nil, fmt.Errorf("log is not supported on commits created with Directory.asGitCommit")
+	}
+	out, err := ref.Repo.query(ctx, bk, svcs, ref.Ref, gitdns.Query{
+		Kind:  gitdns.QueryLog,
+		Since: since,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseGitLog(out)
+}
+
+// TagsPointingAt returns the tags of the repository that point to the ref's
+// commit.
+func (ref *GitRef) TagsPointingAt(ctx context.Context, bk *buildkit.Client, svcs *Services) ([]string, error) {
+	commit, err := ref.Commit(ctx, bk, svcs)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := ref.Repo.remoteRefs(ctx, bk, svcs)
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	for _, r := range refs.Refs {
+		if tag, ok := strings.CutPrefix(r.Name, "refs/tags/"); ok && r.Commit == commit {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// Tags returns the tags of the repository matching any of the given
+// patterns, or all of them if there are none.
+func (repo *GitRepository) Tags(ctx context.Context, bk *buildkit.Client, svcs *Services, patterns []string) ([]string, error) {
+	return repo.refNames(ctx, bk, svcs, "refs/tags/", patterns)
+}
+
+// Branches returns the branches of the repository matching any of the given
+// patterns, or all of them if there are none.
+func (repo *GitRepository) Branches(ctx context.Context, bk *buildkit.Client, svcs *Services, patterns []string) ([]string, error) {
+	return repo.refNames(ctx, bk, svcs, "refs/heads/", patterns)
+}
+
+// DefaultBranch returns the branch the repository's HEAD points to.
+func (repo *GitRepository) DefaultBranch(ctx context.Context, bk *buildkit.Client, svcs *Services) (string, error) {
+	refs, err := repo.remoteRefs(ctx, bk, svcs)
+	if err != nil {
+		return "", err
+	}
+	branch, ok := strings.CutPrefix(refs.Head, "refs/heads/")
+	if !ok {
+		return "", fmt.Errorf("could not find the default branch of %s", repo.URL)
+	}
+	return branch, nil
+}
+
+func (repo *GitRepository) refNames(ctx context.Context, bk *buildkit.Client, svcs *Services, prefix string, patterns []string) ([]string, error) {
+	refs, err := repo.remoteRefs(ctx, bk, svcs)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, r := range refs.Refs {
+		name, ok := strings.CutPrefix(r.Name, prefix)
+		if !ok {
+			continue
+		}
+		match, err := matchesAnyPattern(name, patterns)
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func matchesAnyPattern(name string, patterns []string) (bool, error) {
+	if len(patterns) == 0 {
+		return true, nil
+	}
+	for _, pattern := range patterns {
+		match, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (repo *GitRepository) remoteRefs(ctx context.Context, bk *buildkit.Client, svcs *Services) (*gitRemoteRefs, error) {
+	out, err := repo.query(ctx, bk, svcs, "", gitdns.Query{Kind: gitdns.QueryRefs})
+	if err != nil {
+		return nil, err
+	}
+	return parseGitRemoteRefs(out), nil
+}
+
+// query runs the given query on the repository, with the git source, and
+// returns its result.
+func (repo *GitRepository) query(ctx context.Context, bk *buildkit.Client, svcs *Services, ref string, query gitdns.Query) ([]byte, error) {
+	if repo.URL == "" {
+		return nil, fmt.Errorf("repository has no URL")
+	}
+	detach, _, err := svcs.StartBindings(ctx, bk, repo.Services)
+	if err != nil {
+		return nil, err
+	}
+	defer detach()
+
+	var clientIDs []string
+	if clientMetadata, err := engine.ClientMetadataFromContext(ctx); err == nil {
+		clientIDs = clientMetadata.ClientIDs()
+	}
+
+	st := gitdns.QueryState(repo.URL, ref, clientIDs, query, repo.gitOptions()...)
+	def, err := st.Marshal(ctx, llb.Platform(repo.Platform))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := bk.Solve(ctx, bkgw.SolveRequest{
+		Definition: def.ToPB(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	resRef, err := res.SingleRef()
+	if err != nil {
+		return nil, err
+	}
+	if resRef == nil {
+		return nil, fmt.Errorf("git %s query returned no result", query.Kind)
+	}
+
+	f, err := reffs.OpenFile(ctx, resRef, gitdns.QueryResultFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func (repo *GitRepository) gitOptions() []llb.GitOption {
 	opts := []llb.GitOption{}
 
-	if ref.Repo.KeepGitDir {
+	if repo.KeepGitDir {
 		opts = append(opts, llb.KeepGitDir())
 	}
-	if ref.Repo.SSHKnownHosts != "" {
-		opts = append(opts, llb.KnownSSHHosts(ref.Repo.SSHKnownHosts))
+	if repo.SSHKnownHosts != "" {
+		opts = append(opts, llb.KnownSSHHosts(repo.SSHKnownHosts))
 	}
-	if ref.Repo.SSHAuthSocket != "" {
-		opts = append(opts, llb.MountSSHSock(string(ref.Repo.SSHAuthSocket)))
+	if repo.SSHAuthSocket != "" {
+		opts = append(opts, llb.MountSSHSock(string(repo.SSHAuthSocket)))
 	}
+	// the git sources look the secrets up by these names through the session's
+	// secret store, which resolves them from their IDs
+	if repo.HTTPAuthToken != "" {
+		opts = append(opts, llb.AuthTokenSecret(repo.HTTPAuthToken.String()))
+	}
+	if repo.HTTPAuthHeader != "" {
+		opts = append(opts, llb.AuthHeaderSecret(repo.HTTPAuthHeader.String()))
+	}
+	return opts
+}
+
+func (ref *GitRef) getState(ctx context.Context, bk *buildkit.Client, checkout gitdns.Checkout) *llb.State {
+	opts := ref.Repo.gitOptions()
 
-	useDNS := len(ref.Repo.Services) > 0
+	// llb.Git can't control the checkout
+	useDNS := len(ref.Repo.Services) > 0 || !checkout.IsZero()
 
 	clientMetadata, err := engine.ClientMetadataFromContext(ctx)
 	if err == nil && !useDNS {
@@ -87,7 +369,11 @@ func (ref *GitRef) getState(ctx context.Context, bk *buildkit.Client) *llb.State
 		// networks API cap.
 		//
 		// TODO: add API cap
-		st = gitdns.State(ref.Repo.URL, ref.Ref, clientMetadata.ClientIDs(), opts...)
+		var clientIDs []string
+		if clientMetadata != nil {
+			clientIDs = clientMetadata.ClientIDs()
+		}
+		st = gitdns.State(ref.Repo.URL, ref.Ref, clientIDs, checkout, opts...)
 	} else {
 		st = llb.Git(ref.Repo.URL, ref.Ref, opts...)
 	}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitRemoteRefs(t *testing.T) {
+	out := "ref: refs/heads/main\tHEAD\n" +
+		"1111111111111111111111111111111111111111\tHEAD\n" +
+		"1111111111111111111111111111111111111111\trefs/heads/main\n" +
+		"2222222222222222222222222222222222222222\trefs/heads/release/v1\n" +
+		"3333333333333333333333333333333333333333\trefs/tags/v1.0.0\n" +
+		"2222222222222222222222222222222222222222\trefs/tags/v1.0.0^{}\n" +
+		"1111111111111111111111111111111111111111\trefs/tags/v1.1.0\n"
+
+	refs := parseGitRemoteRefs([]byte(out))
+	require.Equal(t, "refs/heads/main", refs.Head)
+	require.Equal(t, []gitRemoteRef{
+		{Name: "refs/heads/main", Commit: "1111111111111111111111111111111111111111"},
+		{Name: "refs/heads/release/v1", Commit: "2222222222222222222222222222222222222222"},
+		{Name: "refs/tags/v1.0.0", Commit: "2222222222222222222222222222222222222222"},
+		{Name: "refs/tags/v1.1.0", Commit: "1111111111111111111111111111111111111111"},
+	}, refs.Refs)
+}
+
+func TestParseGitLog(t *testing.T) {
+	out := "2222222222222222222222222222222222222222\nJane Doe\njane@example.com\n1700000100\nfix: second\n\nWith a body.\n\x00" +
+		"\n1111111111111111111111111111111111111111\nJohn Doe\njohn@example.com\n1700000000\ninit\n"
+
+	entries, err := parseGitLog([]byte(out))
+	require.NoError(t, err)
+	require.Equal(t, []GitLogEntry{
+		{
+			Commit:      "2222222222222222222222222222222222222222",
+			AuthorName:  "Jane Doe",
+			AuthorEmail: "jane@example.com",
+			Date:        "2023-11-14T22:15:00Z",
+			Subject:     "fix: second",
+			Message:     "fix: second\n\nWith a body.",
+		},
+		{
+			Commit:      "1111111111111111111111111111111111111111",
+			AuthorName:  "John Doe",
+			AuthorEmail: "john@example.com",
+			Date:        "2023-11-14T22:13:20Z",
+			Subject:     "init",
+			Message:     "init",
+		},
+	}, entries)
+
+	empty, err := parseGitLog(nil)
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	_, err = parseGitLog([]byte("1111111111111111111111111111111111111111\nJohn Doe\njohn@example.com\nnot-a-date\ninit\n"))
+	require.ErrorContains(t, err, "malformed git log date")
+}
+
+func TestMatchesAnyPattern(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		patterns []string
+		expected bool
+	}{
+		{name: "v1.0.0", expected: true},
+		{name: "v1.0.0", patterns: []string{"v*"}, expected: true},
+		{name: "v1.0.0", patterns: []string{"release/*", "v1.*"}, expected: true},
+		{name: "sdk/go/v1.0.0", patterns: []string{"v*"}, expected: false},
+		{name: "sdk/go/v1.0.0", patterns: []string{"sdk/go/v*"}, expected: true},
+	} {
+		match, err := matchesAnyPattern(tc.name, tc.patterns)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, match, "%s %v", tc.name, tc.patterns)
+	}
+
+	_, err := matchesAnyPattern("v1", []string{"["})
+	require.ErrorContains(t, err, `invalid pattern "["`)
+}
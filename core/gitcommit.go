@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/containerd/continuity/fs"
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/buildkit"
+	"github.com/dagger/dagger/engine/sources/gitdns"
+)
+
+// GitLocalCommit is a commit of a directory, created by
+// Directory.asGitCommit. It's only created engine-side when needed, e.g., to
+// be pushed.
+type GitLocalCommit struct {
+	Directory *Directory `json:"directory"`
+
+	Message     string `json:"message"`
+	AuthorName  string `json:"authorName"`
+	AuthorEmail string `json:"authorEmail"`
+
+	// Date is the time of the commit, as a Unix timestamp, so that the same
+	// commit always has the same hash.
+	Date int64 `json:"date"`
+
+	Parent *GitRef `json:"parent,omitempty"`
+}
+
+// AsGitCommit returns a commit of the directory's files on top of the given
+// parent, or a root commit if there's none. The author is formatted like
+// "Name <email>".
+func (dir *Directory) AsGitCommit(message, author string, parent *GitRef, date time.Time) (*GitRef, error) {
+	if message == "" {
+		return nil, fmt.Errorf("commit message must not be empty")
+	}
+	addr, err := mail.ParseAddress(author)
+	if err != nil {
+		return nil, fmt.Errorf("invalid author %q, must be formatted like \"Name <email>\": %w", author, err)
+	}
+
+	repo := &GitRepository{
+		Pipeline: dir.Pipeline,
+		Platform: dir.Platform,
+	}
+	if parent != nil {
+		repo = parent.Repo
+	}
+	return &GitRef{
+		Repo: repo,
+		Local: &GitLocalCommit{
+			Directory:   dir,
+			Message:     message,
+			AuthorName:  addr.Name,
+			AuthorEmail: addr.Address,
+			Date:        date.Unix(),
+			Parent:      parent,
+		},
+	}, nil
+}
+
+// GitPushOpts are the options of GitRef.Push.
+type GitPushOpts struct {
+	Remote string
+	Branch string
+	Force  bool
+
+	// HTTPAuthToken and HTTPAuthHeader are the plaintexts of the secrets to
+	// authenticate with over HTTPS.
+	HTTPAuthToken  []byte
+	HTTPAuthHeader []byte
+
+	SSHAuthSocket string
+	SSHKnownHosts string
+
+	Services ServiceBindings
+}
+
+// Push pushes the commit of the ref to a branch of the remote, returning
+// the commit's hash.
+func (ref *GitRef) Push(ctx context.Context, bk *buildkit.Client, svcs *Services, opts GitPushOpts) (string, error) {
+	if opts.Remote == "" {
+		return "", fmt.Errorf("remote must not be empty")
+	}
+	if opts.Branch == "" {
+		return "", fmt.Errorf("branch must not be empty")
+	}
+
+	detach, _, err := svcs.StartBindings(ctx, bk, opts.Services)
+	if err != nil {
+		return "", err
+	}
+	defer detach()
+
+	var clientIDs []string
+	if clientMetadata, err := engine.ClientMetadataFromContext(ctx); err == nil {
+		clientIDs = clientMetadata.ClientIDs()
+	}
+
+	push := gitdns.Push{
+		Remote:        opts.Remote,
+		Branch:        opts.Branch,
+		Force:         opts.Force,
+		AuthToken:     opts.HTTPAuthToken,
+		AuthHeader:    opts.HTTPAuthHeader,
+		KnownSSHHosts: opts.SSHKnownHosts,
+		DNS:           bk.DNSConfig,
+		ClientIDs:     clientIDs,
+	}
+	if opts.SSHAuthSocket != "" {
+		sock, unmountSock, err := bk.MountSSHSocket(ctx, opts.SSHAuthSocket)
+		if err != nil {
+			return "", err
+		}
+		defer unmountSock()
+		push.SSHAuthSock = sock
+	}
+
+	err = ref.withGitDir(ctx, bk, svcs, func(gitDir, commit string) error {
+		push.Commit = commit
+		return gitdns.PushCommit(ctx, gitDir, push)
+	})
+	if err != nil {
+		return "", err
+	}
+	return push.Commit, nil
+}
+
+// withGitDir calls fn with a repository holding the commit of the ref, and
+// the commit's hash.
+func (ref *GitRef) withGitDir(ctx context.Context, bk *buildkit.Client, svcs *Services, fn func(gitDir, commit string) error) error {
+	if ref.Local == nil {
+		commit, err := ref.Commit(ctx, bk, svcs)
+		if err != nil {
+			return err
+		}
+		repo := *ref.Repo
+		repo.KeepGitDir = true
+		checkout := GitRef{Ref: commit, Repo: &repo}
+		tree, err := checkout.Tree(ctx, bk, gitdns.Checkout{})
+		if err != nil {
+			return err
+		}
+		return withMountedDirectory(ctx, bk, svcs, tree, func(root string) error {
+			return fn(filepath.Join(root, ".git"), commit)
+		})
+	}
+
+	create := func(parentGitDir, parent string) error {
+		return withMountedDirectory(ctx, bk, svcs, ref.Local.Directory, func(root string) error {
+			gitDir, err := os.MkdirTemp("", "dagger-git-commit")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(gitDir)
+
+			commit, err := gitdns.CreateCommit(ctx, gitDir, gitdns.Commit{
+				Dir:          root,
+				Message:      ref.Local.Message,
+				AuthorName:   ref.Local.AuthorName,
+				AuthorEmail:  ref.Local.AuthorEmail,
+				Date:         time.Unix(ref.Local.Date, 0),
+				Parent:       parent,
+				ParentGitDir: parentGitDir,
+			})
+			if err != nil {
+				return err
+			}
+			return fn(gitDir, commit)
+		})
+	}
+	if ref.Local.Parent == nil {
+		return create("", "")
+	}
+	return ref.Local.Parent.withGitDir(ctx, bk, svcs, create)
+}
+
+// withMountedDirectory calls fn with the path the directory is mounted
+// read-only at.
+func withMountedDirectory(ctx context.Context, bk *buildkit.Client, svcs *Services, dir *Directory, fn func(path string) error) error {
+	detach, _, err := svcs.StartBindings(ctx, bk, dir.Services)
+	if err != nil {
+		return err
+	}
+	defer detach()
+
+	return bk.WithMountedDef(ctx, dir.LLB, func(root string) error {
+		path, err := fs.RootPath(root, dir.Dir)
+		if err != nil {
+			return err
+		}
+		return fn(path)
+	})
+}
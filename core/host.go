@@ -24,6 +24,16 @@ type CopyFilter struct {
 	Include []string
 }
 
+// IgnoreFiles selects the ignore files whose patterns are excluded when
+// loading a host directory, on top of its CopyFilter.
+type IgnoreFiles struct {
+	// Gitignore respects the .gitignore files of the directory and its
+	// subdirectories, along with .git/info/exclude, and excludes .git itself.
+	Gitignore bool
+	// Dockerignore respects the .dockerignore file of the directory.
+	Dockerignore bool
+}
+
 func (host *Host) Directory(
 	ctx context.Context,
 	bk *buildkit.Client,
@@ -32,6 +42,7 @@ func (host *Host) Directory(
 	pipelineNamePrefix string,
 	platform specs.Platform,
 	filter CopyFilter,
+	ignore IgnoreFiles,
 ) (*Directory, error) {
 	// TODO: enforcement that requester session is granted access to source session at this path
 
@@ -39,7 +50,7 @@ func (host *Host) Directory(
 	pipelineName := fmt.Sprintf("%s %s", pipelineNamePrefix, dirPath)
 	ctx, subRecorder := progrock.WithGroup(ctx, pipelineName, progrock.Weak())
 
-	defPB, err := bk.LocalImport(ctx, subRecorder, platform, dirPath, filter.Exclude, filter.Include)
+	defPB, err := bk.LocalImport(ctx, subRecorder, platform, dirPath, filter.Exclude, filter.Include, ignore.Gitignore, ignore.Dockerignore)
 	if err != nil {
 		return nil, fmt.Errorf("host directory %s: %w", dirPath, err)
 	}
@@ -56,7 +67,7 @@ func (host *Host) File(
 ) (*File, error) {
 	parentDir, err := host.Directory(ctx, bk, filepath.Dir(path), p, "host.file", platform, CopyFilter{
 		Include: []string{filepath.Base(path)},
-	})
+	}, IgnoreFiles{})
 	if err != nil {
 		return nil, err
 	}
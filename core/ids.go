@@ -30,4 +30,6 @@ type GitRepositoryID = resourceid.ID[GitRepository]
 
 type GitRefID = resourceid.ID[GitRef]
 
+type OCIArtifactID = resourceid.ID[OCIArtifact]
+
 // SocketID is in the socket package (to avoid circular imports)
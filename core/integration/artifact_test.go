@@ -0,0 +1,109 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"dagger.io/dagger"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOCIArtifact(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	const (
+		artifactType   = "application/vnd.dagger.test.config.v1+json"
+		chartMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	)
+
+	files := c.Directory().
+		WithNewFile("README.md", "# readme\n").
+		WithNewFile("docs/usage.md", "usage\n")
+
+	testRef := registryRef("oci-artifact")
+	pushedRef, err := files.PublishArtifact(ctx, testRef, artifactType, dagger.DirectoryPublishArtifactOpts{
+		Annotations: []dagger.ArtifactAnnotation{
+			{Name: "org.opencontainers.image.source", Value: "https://github.com/dagger/dagger"},
+		},
+	})
+	require.NoError(t, err)
+	require.Contains(t, pushedRef, "@sha256:")
+
+	t.Run("manifest", func(t *testing.T) {
+		parsedRef, err := name.ParseReference(pushedRef, name.Insecure)
+		require.NoError(t, err)
+		desc, err := remote.Get(parsedRef, remote.WithTransport(http.DefaultTransport))
+		require.NoError(t, err)
+
+		var man ocispecs.Manifest
+		require.NoError(t, json.Unmarshal(desc.Manifest, &man))
+		require.Equal(t, artifactType, man.ArtifactType)
+		require.Equal(t, ocispecs.MediaTypeEmptyJSON, man.Config.MediaType)
+		require.Equal(t, "https://github.com/dagger/dagger", man.Annotations["org.opencontainers.image.source"])
+
+		titles := []string{}
+		for _, layer := range man.Layers {
+			require.Equal(t, "application/vnd.oci.image.layer.v1.tar", layer.MediaType)
+			titles = append(titles, layer.Annotations[ocispecs.AnnotationTitle])
+		}
+		require.ElementsMatch(t, []string{"README.md", "docs/usage.md"}, titles)
+	})
+
+	t.Run("pull files", func(t *testing.T) {
+		for _, ref := range []string{testRef, pushedRef} {
+			pulled := c.OCIArtifact(ref).Files()
+
+			entries, err := pulled.Entries(ctx)
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"README.md", "docs"}, entries)
+
+			contents, err := pulled.File("docs/usage.md").Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "usage\n", contents)
+		}
+	})
+
+	t.Run("pull files by media type", func(t *testing.T) {
+		chartRef := registryRef("oci-artifact-chart")
+		_, err := c.Directory().
+			WithNewFile("chart.tgz", "not really a chart").
+			PublishArtifact(ctx, chartRef, "application/vnd.cncf.helm.config.v1+json", dagger.DirectoryPublishArtifactOpts{
+				MediaType: chartMediaType,
+			})
+		require.NoError(t, err)
+
+		entries, err := c.OCIArtifact(chartRef).Files(dagger.OCIArtifactFilesOpts{
+			MediaTypes: []string{chartMediaType},
+		}).Entries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"chart.tgz"}, entries)
+
+		entries, err = c.OCIArtifact(chartRef).Files(dagger.OCIArtifactFilesOpts{
+			MediaTypes: []string{"application/vnd.oci.image.layer.v1.tar"},
+		}).Entries(ctx)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	t.Run("address is normalized", func(t *testing.T) {
+		addr, err := c.OCIArtifact("alpine").Address(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "docker.io/library/alpine:latest", addr)
+	})
+
+	t.Run("image index", func(t *testing.T) {
+		_, err := c.OCIArtifact(alpineImage).Files().Sync(ctx)
+		require.ErrorContains(t, err, "image index")
+	})
+
+	t.Run("empty directory", func(t *testing.T) {
+		_, err := c.Directory().PublishArtifact(ctx, registryRef("oci-artifact-empty"), artifactType)
+		require.ErrorContains(t, err, "no files to publish")
+	})
+}
@@ -1,17 +1,26 @@
 package core
 
 import (
+	"archive/tar"
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/containerd/containerd/platforms"
@@ -2513,6 +2522,271 @@ func TestContainerPublish(t *testing.T) {
 	require.Equal(t, "im-a-entrypoint\n", output)
 }
 
+func TestContainerPublishSigned(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	genKeyPair := func() (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		privDER, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		pubDER, err := x509.MarshalPKIXPublicKey(key.Public())
+		require.NoError(t, err)
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	}
+	privKey, pubKey := genKeyPair()
+	_, otherPubKey := genKeyPair()
+
+	testRef := registryRef("container-publish-signed")
+	pushedRef, err := c.Container().From(alpineImage).
+		Publish(ctx, testRef, dagger.ContainerPublishOpts{
+			SignWith: c.SetSecret("signing-key", privKey),
+		})
+	require.NoError(t, err)
+
+	t.Run("signature is stored with cosign layout", func(t *testing.T) {
+		parsedRef, err := name.ParseReference(pushedRef, name.Insecure)
+		require.NoError(t, err)
+		dgst := parsedRef.(name.Digest).DigestStr()
+		sigRef, err := name.ParseReference(
+			parsedRef.Context().String()+":"+strings.Replace(dgst, ":", "-", 1)+".sig",
+			name.Insecure,
+		)
+		require.NoError(t, err)
+
+		img, err := remote.Image(sigRef, remote.WithTransport(http.DefaultTransport))
+		require.NoError(t, err)
+		man, err := img.Manifest()
+		require.NoError(t, err)
+		require.Len(t, man.Layers, 1)
+		require.EqualValues(t, "application/vnd.dev.cosign.simplesigning.v1+json", man.Layers[0].MediaType)
+		require.NotEmpty(t, man.Layers[0].Annotations["dev.cosignproject.cosign/signature"])
+	})
+
+	t.Run("verify with signing key", func(t *testing.T) {
+		contents, err := c.Container().
+			From(testRef, dagger.ContainerFromOpts{
+				Verify: dagger.ImageVerification{PublicKeys: []string{otherPubKey, pubKey}},
+			}).
+			File("/etc/alpine-release").
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "3.18.2\n", contents)
+	})
+
+	t.Run("verify with other key", func(t *testing.T) {
+		_, err := c.Container().
+			From(testRef, dagger.ContainerFromOpts{
+				Verify: dagger.ImageVerification{PublicKeys: []string{otherPubKey}},
+			}).
+			Sync(ctx)
+		require.ErrorContains(t, err, "no valid signature found")
+	})
+
+	t.Run("verify unsigned image", func(t *testing.T) {
+		unsignedRef, err := c.Container().From(alpineImage).
+			Publish(ctx, registryRef("container-publish-unsigned"))
+		require.NoError(t, err)
+
+		_, err = c.Container().
+			From(unsignedRef, dagger.ContainerFromOpts{
+				Verify: dagger.ImageVerification{PublicKeys: []string{pubKey}},
+			}).
+			Sync(ctx)
+		require.ErrorContains(t, err, "no signatures found")
+	})
+}
+
+func TestContainerLayers(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	base := c.Container().From(alpineImage)
+	baseLayers, err := base.Layers(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, baseLayers)
+
+	ctr := base.
+		WithExec([]string{"sh", "-c", "echo one > /one"}).
+		WithExec([]string{"sh", "-c", "echo two > /two"}).
+		WithExec([]string{"rm", "/etc/alpine-release"})
+
+	layers, err := ctr.Layers(ctx)
+	require.NoError(t, err)
+	require.Len(t, layers, len(baseLayers)+3)
+	for _, layer := range layers {
+		dgst, err := layer.Digest(ctx)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(dgst, "sha256:"))
+		size, err := layer.Size(ctx)
+		require.NoError(t, err)
+		require.Greater(t, size, 0)
+	}
+
+	checkContents := func(t *testing.T, ctr *dagger.Container) {
+		t.Helper()
+		out, err := ctr.WithExec([]string{"cat", "/one", "/two"}).Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "one\ntwo\n", out)
+		_, err = ctr.WithExec([]string{"test", "!", "-e", "/etc/alpine-release"}).Sync(ctx)
+		require.NoError(t, err)
+	}
+
+	t.Run("squash all", func(t *testing.T) {
+		squashed := ctr.WithSquashedLayers()
+		layers, err := squashed.Layers(ctx)
+		require.NoError(t, err)
+		require.Len(t, layers, 1)
+		checkContents(t, squashed)
+	})
+
+	t.Run("squash from base", func(t *testing.T) {
+		squashed := ctr.WithSquashedLayers(dagger.ContainerWithSquashedLayersOpts{
+			From: base,
+		})
+		layers, err := squashed.Layers(ctx)
+		require.NoError(t, err)
+		require.Len(t, layers, len(baseLayers)+1)
+		checkContents(t, squashed)
+	})
+
+	t.Run("layer boundaries", func(t *testing.T) {
+		bounded := base.
+			WithLayerBoundary().
+			WithExec([]string{"sh", "-c", "echo one > /one"}).
+			WithExec([]string{"sh", "-c", "echo two > /two"}).
+			WithLayerBoundary().
+			WithExec([]string{"rm", "/etc/alpine-release"}).
+			WithLayerBoundary()
+		layers, err := bounded.Layers(ctx)
+		require.NoError(t, err)
+		require.Len(t, layers, len(baseLayers)+2)
+		checkContents(t, bounded)
+	})
+
+	t.Run("layer boundary reset by from", func(t *testing.T) {
+		golang := c.Container().From(golangImage)
+		golangLayers, err := golang.Layers(ctx)
+		require.NoError(t, err)
+
+		bounded := base.
+			WithLayerBoundary().
+			WithExec([]string{"sh", "-c", "echo one > /one"}).
+			From(golangImage).
+			WithLayerBoundary().
+			WithExec([]string{"sh", "-c", "echo two > /two"}).
+			WithLayerBoundary()
+		layers, err := bounded.Layers(ctx)
+		require.NoError(t, err)
+		require.Len(t, layers, len(golangLayers)+1)
+
+		_, err = bounded.WithExec([]string{"test", "!", "-e", "/one"}).Sync(ctx)
+		require.NoError(t, err)
+		_, err = bounded.WithExec([]string{"go", "version"}).Sync(ctx)
+		require.NoError(t, err)
+	})
+}
+
+func TestContainerDocker(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	// a minimal fake of the Docker API's image load and save endpoints
+	var mu sync.Mutex
+	images := map[string][]byte{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/images/load", func(w http.ResponseWriter, r *http.Request) {
+		tarball, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var manifest []struct {
+			RepoTags []string
+		}
+		tr := tar.NewReader(bytes.NewReader(tarball))
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				http.Error(w, "no manifest.json in tarball", http.StatusBadRequest)
+				return
+			}
+			if hdr.Name != "manifest.json" {
+				continue
+			}
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			break
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		defer mu.Unlock()
+		for _, m := range manifest {
+			for _, tag := range m.RepoTags {
+				images[tag] = tarball
+				fmt.Fprintf(w, "{\"stream\":\"Loaded image: %s\\n\"}\n", tag)
+			}
+		}
+	})
+	mux.HandleFunc("/images/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/images/"), "/get")
+		mu.Lock()
+		tarball, found := images[name]
+		mu.Unlock()
+		if !found {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "{\"message\":\"reference does not exist: %s\"}", name)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Write(tarball)
+	})
+
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	srv := &http.Server{Handler: mux} //nolint:gosec
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	daemon := c.Host().UnixSocket(sock)
+
+	ok, err := c.Container().From(alpineImage).
+		WithNewFile("/hello", dagger.ContainerWithNewFileOpts{Contents: "hello from docker"}).
+		WithEnvVariable("FOO", "bar").
+		ExportToDocker(ctx, "dagger-test:latest", daemon)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	mu.Lock()
+	require.Contains(t, images, "dagger-test:latest")
+	mu.Unlock()
+
+	ctr := c.Container().FromDocker("dagger-test:latest", daemon)
+
+	out, err := ctr.WithExec([]string{"cat", "/hello"}).Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "hello from docker", out)
+
+	env, err := ctr.EnvVariable(ctx, "FOO")
+	require.NoError(t, err)
+	require.Equal(t, "bar", env)
+
+	_, err = c.Container().FromDocker("missing:latest", daemon).Sync(ctx)
+	require.ErrorContains(t, err, "reference does not exist")
+}
+
 func TestExecFromScratch(t *testing.T) {
 	c, ctx := connect(t)
 
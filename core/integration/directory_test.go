@@ -991,3 +991,538 @@ func TestDirectoryGlob(t *testing.T) {
 		require.ElementsMatch(t, entries, []string{"foo/bar.md", "foo/bar.md/x.md"})
 	})
 }
+
+func TestDirectoryAsArchive(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	dir := c.Directory().
+		WithNewFile("README.md", "# hello").
+		WithNewFile("sub/dir/run.sh", "#!/bin/sh", dagger.DirectoryWithNewFileOpts{Permissions: 0o755})
+
+	for _, format := range []dagger.ArchiveFormat{dagger.Tar, dagger.Tgz, dagger.TarZstd, dagger.Zip} {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			t.Parallel()
+
+			unpacked := dir.AsArchive(format).Unpack()
+
+			contents, err := unpacked.File("README.md").Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "# hello", contents)
+
+			out, err := c.Container().From(alpineImage).
+				WithDirectory("/unpacked", unpacked).
+				WithExec([]string{"stat", "-c", "%a", "/unpacked/sub/dir/run.sh"}).
+				Stdout(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "755\n", out)
+
+			explicit, err := dir.AsArchive(format).Unpack(dagger.FileUnpackOpts{Format: format}).Entries(ctx)
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"README.md", "sub"}, explicit)
+		})
+	}
+
+	t.Run("reproducible", func(t *testing.T) {
+		t.Parallel()
+
+		first, err := dir.WithTimestamps(1000).
+			AsArchive(dagger.Tgz, dagger.DirectoryAsArchiveOpts{Reproducible: true}).
+			Contents(ctx)
+		require.NoError(t, err)
+		second, err := dir.WithTimestamps(2000).
+			AsArchive(dagger.Tgz, dagger.DirectoryAsArchiveOpts{Reproducible: true}).
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, first, second)
+	})
+
+	t.Run("unpack tarball built elsewhere", func(t *testing.T) {
+		t.Parallel()
+
+		tarball := c.Container().From(alpineImage).
+			WithNewFile("/src/hello.txt", dagger.ContainerWithNewFileOpts{Contents: "hello"}).
+			WithExec([]string{"tar", "czf", "/out.tar.gz", "-C", "/src", "."}).
+			File("/out.tar.gz")
+
+		contents, err := tarball.Unpack().File("hello.txt").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "hello", contents)
+	})
+
+	t.Run("format mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := dir.AsArchive(dagger.Zip).Unpack(dagger.FileUnpackOpts{Format: dagger.Tar}).Sync(ctx)
+		require.ErrorContains(t, err, "expected a TAR archive, got ZIP")
+	})
+
+	t.Run("not an archive", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := dir.File("README.md").Unpack().Sync(ctx)
+		require.ErrorContains(t, err, "not a TAR, TGZ, TAR_ZSTD or ZIP archive")
+	})
+}
+
+func TestDirectoryEntryInfos(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	dir := c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", strings.Join([]string{
+			"mkdir -p /src/sub",
+			"printf hello > /src/sub/hello.txt",
+			"chmod 640 /src/sub/hello.txt",
+			"chown 1000:1001 /src/sub/hello.txt",
+			"ln -s sub/hello.txt /src/link",
+			"touch -d @1700000000 /src/sub/hello.txt",
+		}, " && ")}).
+		Directory("/src")
+
+	t.Run("recursive entries", func(t *testing.T) {
+		entries, err := dir.Entries(ctx, dagger.DirectoryEntriesOpts{Recursive: true})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"link", "sub", "sub/hello.txt"}, entries)
+
+		entries, err = dir.Entries(ctx, dagger.DirectoryEntriesOpts{Path: "sub", Recursive: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"hello.txt"}, entries)
+	})
+
+	t.Run("entry infos", func(t *testing.T) {
+		infos, err := dir.EntryInfos(ctx, dagger.DirectoryEntryInfosOpts{Recursive: true})
+		require.NoError(t, err)
+
+		byPath := map[string]*dagger.FileInfo{}
+		for i := range infos {
+			path, err := infos[i].Path(ctx)
+			require.NoError(t, err)
+			byPath[path] = &infos[i]
+		}
+		require.Len(t, byPath, 3)
+
+		file := byPath["sub/hello.txt"]
+		kind, err := file.Kind(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dagger.EntryFile, kind)
+		size, err := file.Size(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 5, size)
+		perms, err := file.Permissions(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0o640, perms)
+		uid, err := file.Uid(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1000, uid)
+		gid, err := file.Gid(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1001, gid)
+		modTime, err := file.ModTime(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1700000000, modTime)
+
+		kind, err = byPath["sub"].Kind(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dagger.EntryDirectory, kind)
+
+		link := byPath["link"]
+		kind, err = link.Kind(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dagger.EntrySymlink, kind)
+		target, err := link.SymlinkTarget(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "sub/hello.txt", target)
+	})
+}
+
+func TestDirectoryDigest(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	dir := c.Directory().
+		WithNewFile("a.txt", "a").
+		WithNewFile("sub/b.txt", "b")
+
+	dgst, err := dir.Digest(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, dgst)
+
+	t.Run("independent of history", func(t *testing.T) {
+		reordered, err := c.Directory().
+			WithNewFile("sub/b.txt", "b").
+			WithNewFile("a.txt", "a").
+			Digest(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dgst, reordered)
+	})
+
+	t.Run("independent of timestamps", func(t *testing.T) {
+		stamped, err := dir.WithTimestamps(1234).Digest(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dgst, stamped)
+	})
+
+	t.Run("changes with contents", func(t *testing.T) {
+		changed, err := dir.WithNewFile("sub/b.txt", "B").Digest(ctx)
+		require.NoError(t, err)
+		require.NotEqual(t, dgst, changed)
+	})
+}
+
+func TestDirectoryWithSymlink(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	dir := c.Directory().
+		WithNewFile("lib/libfoo.so.1", "foo").
+		WithNewFile("lib/libfoo.so", "to be replaced").
+		WithSymlink("libfoo.so.1", "lib/libfoo.so").
+		WithSymlink("/lib/libfoo.so.1", "usr/lib/libfoo.so")
+
+	out, err := c.Container().From(alpineImage).
+		WithMountedDirectory("/mnt", dir).
+		WithExec([]string{"sh", "-c", "readlink /mnt/lib/libfoo.so && readlink /mnt/usr/lib/libfoo.so && cat /mnt/lib/libfoo.so"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "libfoo.so.1\n/lib/libfoo.so.1\nfoo", out)
+}
+
+func TestDirectoryWithPermissions(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	dir := c.Directory().
+		WithNewFile("bin/app", "#!/bin/sh").
+		WithNewFile("etc/app/config", "config")
+
+	stat := func(t *testing.T, dir *dagger.Directory, paths ...string) string {
+		t.Helper()
+		out, err := c.Container().From(alpineImage).
+			WithMountedDirectory("/mnt", dir).
+			WithWorkdir("/mnt").
+			WithExec(append([]string{"stat", "-c", "%n %a %u:%g"}, paths...)).
+			Stdout(ctx)
+		require.NoError(t, err)
+		return out
+	}
+
+	t.Run("file", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "bin/app 755 0:0\n", stat(t, dir.WithPermissions("bin/app", 0o755), "bin/app"))
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t,
+			"etc/app 700 0:0\netc/app/config 644 0:0\n",
+			stat(t, dir.WithPermissions("etc/app", 0o700), "etc/app", "etc/app/config"))
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t,
+			"etc 750 0:0\netc/app 750 0:0\netc/app/config 750 0:0\n",
+			stat(t, dir.WithPermissions("etc", 0o750, dagger.DirectoryWithPermissionsOpts{Recursive: true}), "etc", "etc/app", "etc/app/config"))
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t,
+			"etc/app 755 65532:65532\netc/app/config 644 0:0\n",
+			stat(t, dir.WithOwner(65532, 65532, "etc/app"), "etc/app", "etc/app/config"))
+		require.Equal(t,
+			"etc/app 755 65532:65532\netc/app/config 644 65532:65532\n",
+			stat(t, dir.WithOwner(65532, 65532, "etc/app", dagger.DirectoryWithOwnerOpts{Recursive: true}), "etc/app", "etc/app/config"))
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		t.Parallel()
+		_, err := dir.WithPermissions("nope", 0o755).Sync(ctx)
+		require.Error(t, err)
+	})
+}
+
+func TestDirectoryWithPatch(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	base := c.Directory().
+		WithNewFile("main.go", "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n").
+		WithNewFile("old.txt", "bye\n").
+		WithNewFile("unchanged.txt", "same\n")
+
+	t.Run("round trip", func(t *testing.T) {
+		changed := base.
+			WithNewFile("main.go", "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n").
+			WithoutFile("old.txt").
+			WithNewFile("sub/new.txt", "new\n")
+
+		patch := c.Container().From(alpineImage).
+			WithMountedDirectory("/a", base).
+			WithMountedDirectory("/b", changed).
+			WithWorkdir("/").
+			WithExec([]string{"sh", "-c", "diff -ruN a b > /patch.diff; test $? -le 1"}).
+			File("/patch.diff")
+
+		patched, err := base.WithPatch(dagger.DirectoryWithPatchOpts{
+			PatchFile: patch,
+			Strip:     1,
+		}).Digest(ctx)
+		require.NoError(t, err)
+
+		expected, err := changed.Digest(ctx)
+		require.NoError(t, err)
+		require.Equal(t, expected, patched)
+	})
+
+	t.Run("git diff", func(t *testing.T) {
+		contents, err := base.WithPatch(dagger.DirectoryWithPatchOpts{
+			Patch: `diff --git a/unchanged.txt b/unchanged.txt
+index 1111111..2222222 100644
+--- a/unchanged.txt
++++ b/unchanged.txt
+@@ -1 +1 @@
+-same
++different
+`,
+			Strip: 1,
+		}).File("unchanged.txt").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "different\n", contents)
+	})
+
+	t.Run("rejected hunks", func(t *testing.T) {
+		_, err := base.WithPatch(dagger.DirectoryWithPatchOpts{
+			Patch: "--- main.go\n+++ main.go\n@@ -4 +4 @@\n-\tprintln(\"bonjour\")\n+\tprintln(\"salut\")\n" +
+				"--- missing.txt\n+++ missing.txt\n@@ -1 +1 @@\n-a\n+b\n",
+		}).Sync(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "main.go: hunk #1 (@@ -4,1 +4,1 @@) does not apply")
+		require.Contains(t, err.Error(), "missing.txt: file to patch does not exist")
+	})
+
+	t.Run("requires a patch", func(t *testing.T) {
+		_, err := base.WithPatch().Sync(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "either patch or patchFile must be set")
+	})
+}
+
+func TestDirectoryWithTemplates(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	base := c.Directory().
+		WithNewFile("deploy/app.yaml", "name: {{ .name }}\nreplicas: {{ .replicas }}\n").
+		WithNewFile("deploy/run.sh", "#!/bin/sh\necho {{ .name }}\n", dagger.DirectoryWithNewFileOpts{
+			Permissions: 0o755,
+		}).
+		WithNewFile("README.md", "{{ not rendered }}\n")
+
+	data := dagger.JSON(`{"name": "app", "replicas": 3}`)
+
+	t.Run("renders matching files", func(t *testing.T) {
+		rendered := base.WithTemplates("deploy/*", dagger.DirectoryWithTemplatesOpts{Data: data})
+
+		contents, err := rendered.File("deploy/app.yaml").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "name: app\nreplicas: 3\n", contents)
+
+		contents, err = rendered.File("deploy/run.sh").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "#!/bin/sh\necho app\n", contents)
+
+		perms, err := rendered.File("deploy/run.sh").Permissions(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0o755, perms)
+
+		contents, err = rendered.File("README.md").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "{{ not rendered }}\n", contents)
+	})
+
+	t.Run("subdirectory", func(t *testing.T) {
+		contents, err := base.Directory("deploy").
+			WithTemplates("*.yaml", dagger.DirectoryWithTemplatesOpts{Data: data}).
+			File("app.yaml").
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "name: app\nreplicas: 3\n", contents)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		expected, err := base.Digest(ctx)
+		require.NoError(t, err)
+		actual, err := base.WithTemplates("*.txt").Digest(ctx)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("secret", func(t *testing.T) {
+		secret := c.SetSecret("templates-token", "topsecretvalue")
+		contents, err := c.Directory().
+			WithNewFile("config/.netrc", "password {{ secret \"templates-token\" }}\n").
+			WithTemplates("config/.netrc", dagger.DirectoryWithTemplatesOpts{
+				Secrets: []*dagger.Secret{secret},
+			}).
+			File("config/.netrc").
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "password topsecretvalue\n", contents)
+	})
+}
+
+func TestDirectoryMerge(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	a := c.Directory().
+		WithNewFile("a.txt", "a\n").
+		WithNewFile("shared/config.txt", "from a\n")
+	b := c.Directory().
+		WithNewFile("b.txt", "b\n").
+		WithNewFile("shared/config.txt", "from b\n")
+	sub := c.Directory().
+		WithNewFile("nested/c.txt", "c\n").
+		Directory("nested")
+
+	t.Run("last wins", func(t *testing.T) {
+		merged := c.MergeDirectories([]*dagger.Directory{a, b, sub})
+
+		entries, err := merged.Entries(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a.txt", "b.txt", "c.txt", "shared"}, entries)
+
+		contents, err := merged.File("shared/config.txt").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "from b\n", contents)
+	})
+
+	t.Run("conflict error", func(t *testing.T) {
+		_, err := c.MergeDirectories([]*dagger.Directory{a, b}, dagger.MergeDirectoriesOpts{
+			Conflict: dagger.MergeError,
+		}).Sync(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "shared/config.txt (directories 0 and 1)")
+
+		entries, err := c.MergeDirectories([]*dagger.Directory{a, sub}, dagger.MergeDirectoriesOpts{
+			Conflict: dagger.MergeError,
+		}).Entries(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a.txt", "c.txt", "shared"}, entries)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		entries, err := c.MergeDirectories(nil).Entries(ctx)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	t.Run("container", func(t *testing.T) {
+		out, err := c.Container().From(alpineImage).
+			WithDirectories("/src", []*dagger.Directory{a, b}, dagger.ContainerWithDirectoriesOpts{
+				Owner: "1000:1000",
+			}).
+			WithExec([]string{"sh", "-c", "cat /src/a.txt /src/b.txt /src/shared/config.txt && stat -c %u:%g /src/b.txt"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "a\nb\nfrom b\n1000:1000\n", out)
+	})
+}
+
+func TestDirectorySearch(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	dir := c.Directory().
+		WithNewFile("main.go", "package main\n\n// TODO: fix\nfunc main() {}\n").
+		WithNewFile("lib/lib.go", "package lib\n// TODO: document\n").
+		WithNewFile("lib/lib_test.go", "package lib\n// TODO: test\n").
+		WithNewFile("vendor/dep.go", "// TODO: upstream\n").
+		WithNewFile("README.md", "Nothing to do here.\n")
+
+	type match struct {
+		Path       string
+		LineNumber int
+		Line       string
+	}
+	search := func(t *testing.T, dir *dagger.Directory, pattern string, opts ...dagger.DirectorySearchOpts) []match {
+		t.Helper()
+		matches, err := dir.Search(ctx, pattern, opts...)
+		require.NoError(t, err)
+		res := []match{}
+		for _, m := range matches {
+			var r match
+			r.Path, err = m.Path(ctx)
+			require.NoError(t, err)
+			r.LineNumber, err = m.LineNumber(ctx)
+			require.NoError(t, err)
+			r.Line, err = m.Line(ctx)
+			require.NoError(t, err)
+			res = append(res, r)
+		}
+		return res
+	}
+
+	t.Run("whole directory", func(t *testing.T) {
+		require.ElementsMatch(t, []match{
+			{"main.go", 3, "// TODO: fix"},
+			{"lib/lib.go", 2, "// TODO: document"},
+			{"lib/lib_test.go", 2, "// TODO: test"},
+			{"vendor/dep.go", 1, "// TODO: upstream"},
+		}, search(t, dir, "TODO"))
+	})
+
+	t.Run("include and exclude", func(t *testing.T) {
+		require.ElementsMatch(t, []match{
+			{"main.go", 3, "// TODO: fix"},
+			{"lib/lib.go", 2, "// TODO: document"},
+		}, search(t, dir, "TODO", dagger.DirectorySearchOpts{
+			Include: []string{"**/*.go"},
+			Exclude: []string{"vendor/", "**/*_test.go"},
+		}))
+	})
+
+	t.Run("paths", func(t *testing.T) {
+		require.ElementsMatch(t, []match{
+			{"main.go", 1, "package main"},
+			{"lib/lib.go", 1, "package lib"},
+			{"lib/lib_test.go", 1, "package lib"},
+		}, search(t, dir, "package", dagger.DirectorySearchOpts{
+			Paths: []string{"main.go", "lib"},
+		}))
+	})
+
+	t.Run("regex", func(t *testing.T) {
+		require.ElementsMatch(t, []match{
+			{"README.md", 1, "Nothing to do here."},
+		}, search(t, dir, `(?i)^nothing\b`, dagger.DirectorySearchOpts{Regex: true}))
+	})
+
+	t.Run("subdirectory", func(t *testing.T) {
+		require.ElementsMatch(t, []match{
+			{"lib.go", 2, "// TODO: document"},
+			{"lib_test.go", 2, "// TODO: test"},
+		}, search(t, dir.Directory("lib"), "TODO"))
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		require.Empty(t, search(t, dir, "FIXME"))
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		_, err := dir.Search(ctx, "TODO", dagger.DirectorySearchOpts{Paths: []string{"nope"}})
+		require.Error(t, err)
+	})
+}
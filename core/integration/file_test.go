@@ -2,7 +2,10 @@ package core
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -15,6 +18,7 @@ import (
 	"github.com/dagger/dagger/engine/buildkit"
 	"github.com/dagger/dagger/internal/testutil"
 	"github.com/moby/buildkit/identity"
+	"github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -112,6 +116,54 @@ func TestFileSize(t *testing.T) {
 	require.Equal(t, len("some-content"), res.Directory.WithNewFile.File.Size)
 }
 
+func TestFileMetadata(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	file := c.Directory().
+		WithNewFile("sub/run.sh", "#!/bin/sh", dagger.DirectoryWithNewFileOpts{Permissions: 0o750}).
+		File("sub/run.sh")
+
+	name, err := file.Name(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "run.sh", name)
+
+	perms, err := file.Permissions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0o750, perms)
+
+	dgst, err := file.Digest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, digest.FromString("#!/bin/sh").String(), dgst)
+
+	t.Run("digest ignores history", func(t *testing.T) {
+		other, err := c.Container().From(alpineImage).
+			WithExec([]string{"sh", "-c", "printf '#!/bin/sh' > /out.sh"}).
+			File("/out.sh").
+			WithTimestamps(0).
+			Digest(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dgst, other)
+	})
+}
+
+func TestFileWithPermissions(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	file := c.Directory().WithNewFile("run.sh", "#!/bin/sh").File("run.sh").WithPermissions(0o700)
+
+	perms, err := file.Permissions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0o700, perms)
+
+	contents, err := file.Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "#!/bin/sh", contents)
+}
+
 func TestFileExport(t *testing.T) {
 	t.Parallel()
 
@@ -292,6 +344,77 @@ func TestFileContents(t *testing.T) {
 	}
 }
 
+func TestFileRead(t *testing.T) {
+	t.Parallel()
+	c, ctx := connect(t)
+
+	file := c.Container().From(alpineImage).
+		WithExec([]string{"sh", "-c", "seq 1 2000000 > /numbers.txt"}).
+		File("/numbers.txt")
+
+	expected, err := file.Contents(ctx)
+	require.NoError(t, err)
+
+	t.Run("chunks", func(t *testing.T) {
+		var read []byte
+		for {
+			chunk, err := file.Read(ctx, dagger.FileReadOpts{Offset: len(read), Length: 1 << 20})
+			require.NoError(t, err)
+			data, err := base64.StdEncoding.DecodeString(chunk)
+			require.NoError(t, err)
+			if len(data) == 0 {
+				break
+			}
+			require.LessOrEqual(t, len(data), 1<<20)
+			read = append(read, data...)
+		}
+		require.Equal(t, expected, string(read))
+
+		chunk, err := file.Read(ctx, dagger.FileReadOpts{Offset: 2, Length: 6})
+		require.NoError(t, err)
+		data, err := base64.StdEncoding.DecodeString(chunk)
+		require.NoError(t, err)
+		require.Equal(t, "2\n3\n4\n", string(data))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		r, err := file.Reader(ctx)
+		require.NoError(t, err)
+		defer r.Close()
+
+		read, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(read))
+	})
+
+	t.Run("stream larger than contents limit", func(t *testing.T) {
+		bigFile := c.Container().From(alpineImage).
+			WithExec([]string{"sh", "-c", fmt.Sprintf("head -c %d /dev/urandom > /big", buildkit.MaxFileContentsSize+1)}).
+			File("/big")
+
+		_, err := bigFile.Contents(ctx)
+		require.Error(t, err)
+
+		r, err := bigFile.Reader(ctx)
+		require.NoError(t, err)
+		defer r.Close()
+
+		h := sha256.New()
+		n, err := io.Copy(h, r)
+		require.NoError(t, err)
+		require.EqualValues(t, buildkit.MaxFileContentsSize+1, n)
+
+		dgst, err := bigFile.Digest(ctx)
+		require.NoError(t, err)
+		require.Equal(t, digest.NewDigest(digest.SHA256, h).String(), dgst)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		_, err := c.Directory().WithNewFile("dir/file", "").File("dir").Reader(ctx)
+		require.Error(t, err)
+	})
+}
+
 func TestFileSync(t *testing.T) {
 	t.Parallel()
 
@@ -316,3 +439,112 @@ func TestFileSync(t *testing.T) {
 		require.Equal(t, "bar", contents)
 	})
 }
+
+func TestFileWithReplaced(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	file := c.Directory().
+		WithNewFile("version.txt", "version: 1.0.0\nprevious: 1.0.0\n", dagger.DirectoryWithNewFileOpts{
+			Permissions: 0o600,
+		}).
+		File("version.txt")
+
+	t.Run("first occurrence", func(t *testing.T) {
+		contents, err := file.WithReplaced("1.0.0", "1.1.0").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "version: 1.1.0\nprevious: 1.0.0\n", contents)
+	})
+
+	t.Run("all occurrences", func(t *testing.T) {
+		contents, err := file.WithReplaced("1.0.0", "1.1.0", dagger.FileWithReplacedOpts{All: true}).Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "version: 1.1.0\nprevious: 1.1.0\n", contents)
+	})
+
+	t.Run("regex", func(t *testing.T) {
+		contents, err := file.WithReplaced(`version: (\d+)\.\d+\.\d+`, "version: $1.2.0", dagger.FileWithReplacedOpts{Regex: true}).Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "version: 1.2.0\nprevious: 1.0.0\n", contents)
+	})
+
+	t.Run("keeps name and permissions", func(t *testing.T) {
+		replaced := file.WithReplaced("1.0.0", "1.1.0")
+		name, err := replaced.Name(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "version.txt", name)
+		perms, err := replaced.Permissions(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0o600, perms)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		_, err := file.WithReplaced("2.0.0", "3.0.0").Sync(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `search "2.0.0" not found`)
+	})
+}
+
+func TestFileWithTemplate(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	t.Run("data", func(t *testing.T) {
+		contents, err := c.Directory().
+			WithNewFile("app.conf.tmpl", "name={{ .name | upper }}\nport={{ .port }}\n").
+			File("app.conf.tmpl").
+			WithTemplate(dagger.FileWithTemplateOpts{
+				Data: `{"name": "app", "port": 8080}`,
+			}).
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "name=APP\nport=8080\n", contents)
+	})
+
+	t.Run("delims", func(t *testing.T) {
+		contents, err := c.Directory().
+			WithNewFile("workflow.yml", "name: [[ .name ]]\nrun: ${{ github.sha }}\n").
+			File("workflow.yml").
+			WithTemplate(dagger.FileWithTemplateOpts{
+				Data:   `{"name": "ci"}`,
+				Delims: []string{"[[", "]]"},
+			}).
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "name: ci\nrun: ${{ github.sha }}\n", contents)
+	})
+
+	t.Run("missing data", func(t *testing.T) {
+		_, err := c.Directory().
+			WithNewFile("app.conf.tmpl", "name={{ .name }}\n").
+			File("app.conf.tmpl").
+			WithTemplate().
+			Sync(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "render app.conf.tmpl")
+	})
+
+	t.Run("secret", func(t *testing.T) {
+		secret := c.SetSecret("template-token", "topsecretvalue")
+		file := c.Directory().
+			WithNewFile("netrc", "password {{ secret \"template-token\" }}\n").
+			File("netrc").
+			WithTemplate(dagger.FileWithTemplateOpts{
+				Secrets: []*dagger.Secret{secret},
+			})
+
+		contents, err := file.Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "password topsecretvalue\n", contents)
+
+		id, err := file.ID(ctx)
+		require.NoError(t, err)
+		decoded, err := core.FileID(id).Decode()
+		require.NoError(t, err)
+		for _, def := range decoded.LLB.Def {
+			require.NotContains(t, string(def), "topsecretvalue")
+		}
+	})
+}
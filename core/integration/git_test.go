@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"testing"
 
 	"dagger.io/dagger"
+	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/internal/testutil"
 	"github.com/moby/buildkit/identity"
 	"github.com/stretchr/testify/require"
@@ -193,6 +195,91 @@ sleep infinity
 	require.Equal(t, []string{"README.md"}, entries)
 }
 
+func TestGitHTTPAuth(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	const token = "s3cr3t-token"
+	content := identity.NewID()
+	svc, url := gitHTTPAuthService(ctx, t, c,
+		c.Directory().WithNewFile("content", content),
+		"Basic "+base64.StdEncoding.EncodeToString([]byte("x-access-token:"+token)))
+
+	t.Run("token", func(t *testing.T) {
+		contents, err := c.Git(url, dagger.GitOpts{
+			ExperimentalServiceHost: svc,
+			HTTPAuthToken:           c.SetSecret("git-http-token", token),
+		}).Branch("main").Tree().File("content").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, content, contents)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		header := "Basic " + base64.StdEncoding.EncodeToString([]byte("x-access-token:"+token))
+		contents, err := c.Git(url, dagger.GitOpts{
+			ExperimentalServiceHost: svc,
+			HTTPAuthHeader:          c.SetSecret("git-http-header", header),
+		}).Branch("main").Tree().File("content").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, content, contents)
+	})
+
+	t.Run("token is not in the ID", func(t *testing.T) {
+		id, err := c.Git(url, dagger.GitOpts{
+			ExperimentalServiceHost: svc,
+			HTTPAuthToken:           c.SetSecret("git-http-token", token),
+		}).Branch("main").Tree().ID(ctx)
+		require.NoError(t, err)
+		decoded, err := core.DirectoryID(id).Decode()
+		require.NoError(t, err)
+		for _, def := range decoded.LLB.Def {
+			require.NotContains(t, string(def), token)
+		}
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		_, err := c.Git(url, dagger.GitOpts{
+			ExperimentalServiceHost: svc,
+		}).Branch("main").Tree().Sync(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("wrong token", func(t *testing.T) {
+		_, err := c.Git(url, dagger.GitOpts{
+			ExperimentalServiceHost: svc,
+			HTTPAuthToken:           c.SetSecret("git-http-wrong-token", "nope"),
+		}).Branch("main").Tree().Sync(ctx)
+		require.Error(t, err)
+	})
+}
+
+// gitHTTPAuthService serves the given content as a git repository over the
+// dumb HTTP protocol, only to requests with the given Authorization header.
+func gitHTTPAuthService(ctx context.Context, t testing.TB, c *dagger.Client, content *dagger.Directory, authorization string) (*dagger.Service, string) {
+	t.Helper()
+
+	const httpPort = 8080
+	srv := c.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "git", "python3"}).
+		WithDirectory("/root/repo", content).
+		With(withHTTPAuthServer(authorization)).
+		WithNewFile("/root/start.sh", dagger.ContainerWithNewFileOpts{
+			Contents: gitRepoScript("main", "") + fmt.Sprintf(`
+exec python3 /serve.py %d /root/srv
+`, httpPort),
+		}).
+		WithExposedPort(httpPort).
+		WithExec([]string{"sh", "/root/start.sh"}).
+		AsService()
+
+	host, err := srv.Hostname(ctx)
+	require.NoError(t, err)
+
+	return srv, fmt.Sprintf("http://%s:%d/repo.git", host, httpPort)
+}
+
 func TestGitKeepGitDir(t *testing.T) {
 	t.Parallel()
 
@@ -234,3 +321,346 @@ func TestGitServiceStableDigest(t *testing.T) {
 	c2, ctx2 := connect(t)
 	require.Equal(t, hostname(ctx1, c1), hostname(ctx2, c2))
 }
+
+func TestGitRefsAndLog(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	svc, url := gitHistoryService(ctx, t, c)
+	repo := c.Git(url, dagger.GitOpts{ExperimentalServiceHost: svc})
+
+	t.Run("branches", func(t *testing.T) {
+		branches, err := repo.Branches(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"main", "release/v1"}, branches)
+
+		branches, err = repo.Branches(ctx, dagger.GitRepositoryBranchesOpts{
+			Patterns: []string{"release/*"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"release/v1"}, branches)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		tags, err := repo.Tags(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"latest", "v1.0.0", "v1.1.0"}, tags)
+
+		tags, err = repo.Tags(ctx, dagger.GitRepositoryTagsOpts{
+			Patterns: []string{"v*"},
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"v1.0.0", "v1.1.0"}, tags)
+	})
+
+	t.Run("head", func(t *testing.T) {
+		head, err := repo.Head().Commit(ctx)
+		require.NoError(t, err)
+		main, err := repo.Branch("main").Commit(ctx)
+		require.NoError(t, err)
+		require.Equal(t, main, head)
+	})
+
+	t.Run("log", func(t *testing.T) {
+		entries, err := repo.Branch("main").Log(ctx)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+
+		subjects := make([]string, len(entries))
+		for i, entry := range entries {
+			subjects[i], err = entry.Subject(ctx)
+			require.NoError(t, err)
+		}
+		require.Equal(t, []string{"third", "second", "init"}, subjects)
+
+		message, err := entries[0].Message(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "third\n\nWith a body.", message)
+
+		author, err := entries[0].AuthorName(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Test User", author)
+
+		date, err := entries[0].Date(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "2023-11-14T22:16:40Z", date)
+
+		commit, err := entries[0].Commit(ctx)
+		require.NoError(t, err)
+		main, err := repo.Branch("main").Commit(ctx)
+		require.NoError(t, err)
+		require.Equal(t, main, commit)
+	})
+
+	t.Run("log since", func(t *testing.T) {
+		entries, err := repo.Branch("main").Log(ctx, dagger.GitRefLogOpts{
+			Since: "v1.0.0",
+		})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+
+		entries, err = repo.Tag("v1.1.0").Log(ctx, dagger.GitRefLogOpts{
+			Since: "release/v1",
+		})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		subject, err := entries[0].Subject(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "third", subject)
+	})
+
+	t.Run("tags pointing at", func(t *testing.T) {
+		tags, err := repo.Branch("main").TagsPointingAt(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"latest", "v1.1.0"}, tags)
+
+		// annotated tags point to the commit they're peeled to
+		tags, err = repo.Tag("v1.0.0").TagsPointingAt(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v1.0.0"}, tags)
+
+		tags, err = repo.Branch("release/v1").TagsPointingAt(ctx)
+		require.NoError(t, err)
+		require.Empty(t, tags)
+	})
+}
+
+// gitHistoryService serves a repository with a few commits, branches and
+// tags over the git protocol.
+func gitHistoryService(ctx context.Context, t testing.TB, c *dagger.Client) (*dagger.Service, string) {
+	t.Helper()
+	return gitServiceWithBranch(ctx, t, c, c.Directory(), "main", `
+commit() {
+	echo "$1" > content
+	git add content
+	GIT_AUTHOR_DATE="@$2 +0000" GIT_COMMITTER_DATE="@$2 +0000" git commit -m "$1"
+}
+
+commit init 1700000000
+git tag -a v1.0.0 -m "v1.0.0"
+commit second 1700000100
+git branch release/v1
+GIT_AUTHOR_DATE="@1700000200 +0000" GIT_COMMITTER_DATE="@1700000200 +0000" \
+	git commit --allow-empty -m third -m "With a body."
+git tag v1.1.0
+git tag latest
+`)
+}
+
+// gitPushService returns a git daemon serving a repository with a single
+// commit on its main branch, which can be pushed to.
+func gitPushService(ctx context.Context, t testing.TB, c *dagger.Client) (*dagger.Service, string) {
+	t.Helper()
+	return gitServiceWithBranch(ctx, t, c, c.Directory(), "main", `
+echo init > content
+git add content
+git commit -m init
+`, "--enable=receive-pack")
+}
+
+func TestGitPush(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	svc, url := gitPushService(ctx, t, c)
+	// keep the service running, since it holds the pushed commits
+	_, err := svc.Start(ctx)
+	require.NoError(t, err)
+
+	repo := c.Git(url, dagger.GitOpts{ExperimentalServiceHost: svc})
+	pushOpts := dagger.GitRefPushOpts{ExperimentalServiceHost: svc}
+
+	mainCommit, err := repo.Branch("main").Commit(ctx)
+	require.NoError(t, err)
+
+	commit := c.Directory().
+		WithNewFile("content", "pushed\n").
+		WithNewFile("ignored.log", "ignored\n").
+		WithNewFile(".gitignore", "*.log\n").
+		AsGitCommit("add pushed content", "Test Pusher <pusher@localhost>", dagger.DirectoryAsGitCommitOpts{
+			Parent: repo.Branch("main"),
+		})
+
+	sha, err := commit.Push(ctx, url, "pushed", pushOpts)
+	require.NoError(t, err)
+	require.Len(t, sha, 40)
+
+	t.Run("commit", func(t *testing.T) {
+		local, err := commit.Commit(ctx)
+		require.NoError(t, err)
+		require.Equal(t, sha, local)
+
+		remote, err := repo.Branch("pushed").Commit(ctx)
+		require.NoError(t, err)
+		require.Equal(t, sha, remote)
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		entries, err := repo.Branch("pushed").Tree().Entries(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{".gitignore", "content"}, entries)
+
+		contents, err := repo.Branch("pushed").Tree().File("content").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "pushed\n", contents)
+	})
+
+	t.Run("log", func(t *testing.T) {
+		log, err := repo.Branch("pushed").Log(ctx)
+		require.NoError(t, err)
+		require.Len(t, log, 2)
+
+		commit, err := log[0].Commit(ctx)
+		require.NoError(t, err)
+		require.Equal(t, sha, commit)
+
+		subject, err := log[0].Subject(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "add pushed content", subject)
+
+		authorName, err := log[0].AuthorName(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Test Pusher", authorName)
+
+		authorEmail, err := log[0].AuthorEmail(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "pusher@localhost", authorEmail)
+
+		parent, err := log[1].Commit(ctx)
+		require.NoError(t, err)
+		require.Equal(t, mainCommit, parent)
+	})
+
+	t.Run("root commit and force", func(t *testing.T) {
+		root := c.Directory().
+			WithNewFile("content", "root\n").
+			AsGitCommit("root", "Test Pusher <pusher@localhost>")
+
+		rootSHA, err := root.Push(ctx, url, "root", pushOpts)
+		require.NoError(t, err)
+
+		log, err := repo.Branch("root").Log(ctx)
+		require.NoError(t, err)
+		require.Len(t, log, 1)
+		commit, err := log[0].Commit(ctx)
+		require.NoError(t, err)
+		require.Equal(t, rootSHA, commit)
+
+		_, err = root.Push(ctx, url, "main", pushOpts)
+		require.Error(t, err)
+
+		forceOpts := pushOpts
+		forceOpts.Force = true
+		forceSHA, err := root.Push(ctx, url, "main", forceOpts)
+		require.NoError(t, err)
+		require.Equal(t, rootSHA, forceSHA)
+	})
+
+	t.Run("date", func(t *testing.T) {
+		dir := c.Directory().WithNewFile("content", "dated\n")
+
+		// without a date, the same files make the same commit every time
+		first, err := dir.AsGitCommit("dated", "Test Pusher <pusher@localhost>").Commit(ctx)
+		require.NoError(t, err)
+		second, err := dir.AsGitCommit("dated", "Test Pusher <pusher@localhost>").Commit(ctx)
+		require.NoError(t, err)
+		require.Equal(t, first, second)
+
+		dated := dir.AsGitCommit("dated", "Test Pusher <pusher@localhost>", dagger.DirectoryAsGitCommitOpts{
+			Date: "2023-11-14T22:13:20Z",
+		})
+		datedSHA, err := dated.Push(ctx, url, "dated", pushOpts)
+		require.NoError(t, err)
+		require.NotEqual(t, first, datedSHA)
+
+		log, err := repo.Branch("dated").Log(ctx)
+		require.NoError(t, err)
+		require.Len(t, log, 1)
+		date, err := log[0].Date(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "2023-11-14T22:13:20Z", date)
+
+		_, err = dir.AsGitCommit("dated", "Test Pusher <pusher@localhost>", dagger.DirectoryAsGitCommitOpts{
+			Date: "yesterday",
+		}).Commit(ctx)
+		require.ErrorContains(t, err, "invalid date")
+	})
+
+	t.Run("local remote", func(t *testing.T) {
+		_, err := commit.Push(ctx, "/tmp/repo", "pushed")
+		require.ErrorContains(t, err, "invalid remote")
+	})
+
+	t.Run("invalid author", func(t *testing.T) {
+		_, err := c.Directory().
+			AsGitCommit("message", "not an author").
+			Commit(ctx)
+		require.ErrorContains(t, err, "invalid author")
+	})
+}
+
+func TestGitTreeCheckoutOptions(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	t.Run("sparse paths", func(t *testing.T) {
+		svc, url := gitService(ctx, t, c, c.Directory().
+			WithNewFile("README.md", "hello").
+			WithNewFile("services/api/main.go", "package main").
+			WithNewFile("services/web/index.html", "<html/>").
+			WithNewFile("go.mod", "module example.com/app"))
+		ref := c.Git(url, dagger.GitOpts{ExperimentalServiceHost: svc}).Branch("main")
+
+		entries, err := ref.Tree().Entries(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"README.md", "go.mod", "services"}, entries)
+
+		// the same commit checked out sparsely must not hit the full checkout
+		sparse := ref.Tree(dagger.GitRefTreeOpts{
+			SparsePaths: []string{"services/api", "go.mod"},
+		})
+		entries, err = sparse.Entries(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"go.mod", "services"}, entries)
+		entries, err = sparse.Entries(ctx, dagger.DirectoryEntriesOpts{Path: "services"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"api"}, entries)
+
+		keepGitDir := c.Git(url, dagger.GitOpts{ExperimentalServiceHost: svc, KeepGitDir: true}).
+			Branch("main").
+			Tree(dagger.GitRefTreeOpts{SparsePaths: []string{"services/web"}})
+		entries, err = keepGitDir.Entries(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{".git", "services"}, entries)
+		contents, err := keepGitDir.File("services/web/index.html").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "<html/>", contents)
+
+		_, err = ref.Tree(dagger.GitRefTreeOpts{SparsePaths: []string{"../escape"}}).Sync(ctx)
+		require.ErrorContains(t, err, "must be relative to the repository root")
+	})
+
+	t.Run("depth", func(t *testing.T) {
+		svc, url := gitHistoryService(ctx, t, c)
+		ref := c.Git(url, dagger.GitOpts{ExperimentalServiceHost: svc, KeepGitDir: true}).Branch("main")
+
+		commits := func(opts dagger.GitRefTreeOpts) string {
+			out, err := c.Container().
+				From(alpineImage).
+				WithExec([]string{"apk", "add", "git"}).
+				WithMountedDirectory("/repo", ref.Tree(opts)).
+				WithWorkdir("/repo").
+				WithExec([]string{"git", "rev-list", "--count", "HEAD"}).
+				Stdout(ctx)
+			require.NoError(t, err)
+			return strings.TrimSpace(out)
+		}
+
+		require.Equal(t, "1", commits(dagger.GitRefTreeOpts{}))
+		require.Equal(t, "2", commits(dagger.GitRefTreeOpts{Depth: 2}))
+		require.Equal(t, "3", commits(dagger.GitRefTreeOpts{Depth: -1}))
+	})
+}
@@ -267,6 +267,73 @@ func TestHostDirectoryExcludeInclude(t *testing.T) {
 	})
 }
 
+func TestHostDirectoryIgnoreFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":                "node_modules/\n*.log\n",
+		".dockerignore":             "docs\n",
+		".git/HEAD":                 "ref: refs/heads/main\n",
+		".git/info/exclude":         "local.txt\n",
+		"main.go":                   "package main\n",
+		"debug.log":                 "log",
+		"local.txt":                 "local",
+		"node_modules/dep/index.js": "dep",
+		"docs/index.md":             "docs",
+		"sub/.gitignore":            "generated.go\n!keep.log\n",
+		"sub/generated.go":          "package sub\n",
+		"sub/keep.log":              "kept",
+		"sub/other/generated.go":    "package other\n",
+		"unrelated/generated.go":    "package unrelated\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	c, ctx := connect(t)
+
+	t.Run("gitignore", func(t *testing.T) {
+		entries, err := c.Host().Directory(dir, dagger.HostDirectoryOpts{
+			Gitignore: true,
+		}).Entries(ctx, dagger.DirectoryEntriesOpts{Recursive: true})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{
+			".dockerignore",
+			".gitignore",
+			"docs",
+			"docs/index.md",
+			"main.go",
+			"sub",
+			"sub/.gitignore",
+			"sub/keep.log",
+			"sub/other",
+			"unrelated",
+			"unrelated/generated.go",
+		}, entries)
+	})
+
+	t.Run("dockerignore", func(t *testing.T) {
+		entries, err := c.Host().Directory(dir, dagger.HostDirectoryOpts{
+			Dockerignore: true,
+		}).Entries(ctx)
+		require.NoError(t, err)
+		require.NotContains(t, entries, "docs")
+		require.Contains(t, entries, "node_modules")
+		require.Contains(t, entries, ".git")
+	})
+
+	t.Run("combined with exclude", func(t *testing.T) {
+		entries, err := c.Host().Directory(dir, dagger.HostDirectoryOpts{
+			Exclude:      []string{"unrelated"},
+			Gitignore:    true,
+			Dockerignore: true,
+		}).Entries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{".dockerignore", ".gitignore", "main.go", "sub"}, entries)
+	})
+}
+
 func TestHostFile(t *testing.T) {
 	t.Parallel()
 
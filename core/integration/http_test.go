@@ -2,10 +2,13 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"dagger.io/dagger"
+	"github.com/dagger/dagger/core"
 	"github.com/moby/buildkit/identity"
+	"github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -62,3 +65,210 @@ func TestHTTPServiceStableDigest(t *testing.T) {
 	c2, ctx2 := connect(t)
 	require.Equal(t, hostname(ctx1, c1), hostname(ctx2, c2))
 }
+
+func TestHTTPChecksum(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	content := identity.NewID()
+	checksum := digest.FromString(content).String()
+	svc, url := httpService(ctx, t, c, content)
+
+	contents, err := c.HTTP(url, dagger.HTTPOpts{
+		ExperimentalServiceHost: svc,
+		Checksum:                checksum,
+	}).Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, content, contents)
+
+	t.Run("mismatch", func(t *testing.T) {
+		_, err := c.HTTP(url, dagger.HTTPOpts{
+			ExperimentalServiceHost: svc,
+			Checksum:                digest.FromString("something else").String(),
+		}).Contents(ctx)
+		require.ErrorContains(t, err, "checksum mismatch")
+	})
+
+	t.Run("cached without network", func(t *testing.T) {
+		// no service to reach the URL: only the checksum can find the content
+		c2, ctx2 := connect(t)
+		contents, err := c2.HTTP(url, dagger.HTTPOpts{
+			Checksum: checksum,
+		}).Contents(ctx2)
+		require.NoError(t, err)
+		require.Equal(t, content, contents)
+	})
+
+	t.Run("filename and permissions", func(t *testing.T) {
+		for _, tc := range []struct {
+			filename string
+			perm     int
+		}{
+			{"one.sh", 0o755},
+			{"two.txt", 0o640},
+		} {
+			file := c.HTTP(url, dagger.HTTPOpts{
+				ExperimentalServiceHost: svc,
+				Checksum:                checksum,
+				Filename:                tc.filename,
+				Permissions:             tc.perm,
+			})
+			out, err := c.Container().
+				From(alpineImage).
+				WithMountedFile("/mnt/file", file).
+				WithExec([]string{"stat", "-c", "%a", "/mnt/file"}).
+				Stdout(ctx)
+			require.NoError(t, err)
+			require.Equal(t, fmt.Sprintf("%o\n", tc.perm), out)
+
+			name, err := file.Name(ctx)
+			require.NoError(t, err)
+			require.Equal(t, tc.filename, name)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := c.HTTP(url, dagger.HTTPOpts{Checksum: "md5:abc"}).Contents(ctx)
+		require.Error(t, err)
+	})
+}
+
+func TestHTTPFilenameAndPermissions(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	svc, url := httpService(ctx, t, c, "#!/bin/sh\necho hello\n")
+
+	file := c.HTTP(url, dagger.HTTPOpts{
+		ExperimentalServiceHost: svc,
+		Filename:                "hello.sh",
+		Permissions:             0o755,
+	})
+	name, err := file.Name(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "hello.sh", name)
+
+	out, err := c.Container().
+		From(alpineImage).
+		WithMountedFile("/bin/hello.sh", file).
+		WithExec([]string{"sh", "-c", "stat -c %a /bin/hello.sh && hello.sh"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "755\nhello\n", out)
+
+	_, err = c.HTTP(url, dagger.HTTPOpts{
+		ExperimentalServiceHost: svc,
+		Filename:                "sub/hello.sh",
+	}).Sync(ctx)
+	require.ErrorContains(t, err, "must not be a path")
+}
+
+func TestHTTPHeaders(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	const token = "s3cr3t-t0k3n"
+	svc, url := httpAuthService(ctx, t, c, "Hello, world!", "Bearer "+token)
+
+	t.Run("secret header", func(t *testing.T) {
+		secretID, err := c.SetSecret("http-auth-header", "Bearer "+token).ID(ctx)
+		require.NoError(t, err)
+
+		file := c.HTTP(url, dagger.HTTPOpts{
+			ExperimentalServiceHost: svc,
+			Headers: []dagger.HTTPHeader{
+				{Name: "Authorization", Secret: secretID},
+			},
+		})
+		contents, err := file.Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Hello, world!", contents)
+
+		// only the secret's ID is recorded, never its value
+		id, err := file.ID(ctx)
+		require.NoError(t, err)
+		f, err := core.FileID(id).Decode()
+		require.NoError(t, err)
+		for _, def := range f.LLB.Def {
+			require.NotContains(t, string(def), token)
+		}
+	})
+
+	t.Run("plain header", func(t *testing.T) {
+		contents, err := c.HTTP(url, dagger.HTTPOpts{
+			ExperimentalServiceHost: svc,
+			Headers: []dagger.HTTPHeader{
+				{Name: "Authorization", Value: "Bearer " + token},
+			},
+		}).Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Hello, world!", contents)
+	})
+
+	t.Run("no header", func(t *testing.T) {
+		_, err := c.HTTP(url, dagger.HTTPOpts{
+			ExperimentalServiceHost: svc,
+		}).Contents(ctx)
+		require.ErrorContains(t, err, "invalid response status 401")
+	})
+}
+
+// httpAuthService serves the content only to requests with the given
+// Authorization header.
+func httpAuthService(ctx context.Context, t *testing.T, c *dagger.Client, content, authorization string) (*dagger.Service, string) {
+	t.Helper()
+
+	srv := c.Container().
+		From("python").
+		WithMountedDirectory(
+			"/srv/www",
+			c.Directory().WithNewFile("index.html", content),
+		).
+		With(withHTTPAuthServer(authorization)).
+		WithExposedPort(8000).
+		WithExec([]string{"python", "/serve.py", "8000", "/srv/www"}).
+		AsService()
+
+	httpURL, err := srv.Endpoint(ctx, dagger.ServiceEndpointOpts{
+		Scheme: "http",
+	})
+	require.NoError(t, err)
+
+	return srv, httpURL
+}
+
+// withHTTPAuthServer adds a /serve.py script to the container, which serves a
+// directory over HTTP only to requests with the given Authorization header:
+//
+//	python /serve.py <port> <dir>
+func withHTTPAuthServer(authorization string) dagger.WithContainerFunc {
+	return func(ctr *dagger.Container) *dagger.Container {
+		return ctr.
+			WithNewFile("/serve.py", dagger.ContainerWithNewFileOpts{
+				Contents: `import http.server, os, sys
+
+def authorized(header):
+    # auth schemes are case-insensitive, and git sends "basic" for tokens
+    scheme, _, credentials = (header or "").partition(" ")
+    want_scheme, _, want_credentials = os.environ["AUTHORIZATION"].partition(" ")
+    return scheme.lower() == want_scheme.lower() and credentials == want_credentials
+
+class Handler(http.server.SimpleHTTPRequestHandler):
+    def do_GET(self):
+        if not authorized(self.headers.get("Authorization")):
+            self.send_response(401)
+            self.send_header("WWW-Authenticate", 'Basic realm="test"')
+            self.end_headers()
+            return
+        super().do_GET()
+
+os.chdir(sys.argv[2])
+http.server.ThreadingHTTPServer(("", int(sys.argv[1])), Handler).serve_forever()
+`,
+			}).
+			WithEnvVariable("AUTHORIZATION", authorization)
+	}
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"dagger.io/dagger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockfile(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	_, err := c.LoadLockfile(ctx, "")
+	require.NoError(t, err)
+
+	ref, err := c.Container().From(alpineImage).ImageRef(ctx)
+	require.NoError(t, err)
+
+	contents, err := c.Lockfile(ctx)
+	require.NoError(t, err)
+
+	var lock struct {
+		Images map[string]string
+	}
+	require.NoError(t, json.Unmarshal([]byte(contents), &lock))
+	pinned, found := lock.Images["docker.io/library/"+alpineImage]
+	require.True(t, found, contents)
+	require.True(t, strings.HasSuffix(ref, "@"+pinned), ref)
+
+	t.Run("strict", func(t *testing.T) {
+		c2, ctx := connect(t)
+
+		_, err := c2.LoadLockfile(ctx, contents, dagger.LoadLockfileOpts{Strict: true})
+		require.NoError(t, err)
+
+		ref, err := c2.Container().From(alpineImage).ImageRef(ctx)
+		require.NoError(t, err)
+		require.True(t, strings.HasSuffix(ref, "@"+pinned), ref)
+
+		_, err = c2.Container().From(golangImage).Sync(ctx)
+		require.ErrorContains(t, err, "is not pinned in dagger.lock")
+
+		_, err = c2.Git("https://github.com/dagger/dagger").Branch("main").Commit(ctx)
+		require.ErrorContains(t, err, "git ref main of https://github.com/dagger/dagger is not pinned in dagger.lock")
+	})
+
+	t.Run("pinned git ref", func(t *testing.T) {
+		c2, ctx := connect(t)
+
+		_, err := c2.LoadLockfile(ctx, `{"git":{"https://github.com/dagger/dagger":{"main":"c80ac2c13df7d573a069938e01ca13f7a81f0345"}}}`)
+		require.NoError(t, err)
+
+		commit, err := c2.Git("https://github.com/dagger/dagger").Branch("main").Commit(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "c80ac2c13df7d573a069938e01ca13f7a81f0345", commit)
+	})
+}
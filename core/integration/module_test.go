@@ -8,6 +8,7 @@ import (
 	"go/format"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -2065,6 +2066,166 @@ def use_hello() -> str:
 	})
 }
 
+func TestModuleVendoredDeps(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	// the dependency doesn't exist on GitHub, so it can only be loaded from
+	// the vendor directory
+	const depPath = "github.com/dagger/nonexistent-module/dep"
+	const depCommit = "0123456789abcdef0123456789abcdef01234567"
+
+	depSrc := c.Container().From(golangImage).
+		WithMountedFile(testCLIBinPath, daggerCliFile(t, c)).
+		WithWorkdir("/work/dep").
+		With(daggerExec("mod", "init", "--name=dep", "--sdk=go")).
+		WithNewFile("/work/dep/main.go", dagger.ContainerWithNewFileOpts{
+			Contents: useInner,
+		}).
+		Directory("/work/dep")
+	depDigest, err := depSrc.Digest(ctx)
+	require.NoError(t, err)
+
+	vendorManifest := func(commit string) string {
+		t.Helper()
+		bs, err := json.Marshal(modules.VendorManifest{
+			Modules: []*modules.VendoredModule{{
+				Path:          depPath,
+				Commit:        commit,
+				Digest:        depDigest,
+				Dir:           depPath + "@" + commit,
+				SourceSubpath: ".",
+			}},
+		})
+		require.NoError(t, err)
+		return string(bs)
+	}
+
+	modCfg, err := json.Marshal(modules.Config{
+		Name:         "use",
+		SDK:          "go",
+		Dependencies: []string{depPath + "@v1.0.0"},
+		DependencyPins: map[string]*modules.DependencyPin{
+			depPath + "@v1.0.0": {
+				Commit:  depCommit,
+				Digest:  depDigest,
+				Version: "v1.0.0",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	modGen := c.Container().From(golangImage).
+		WithMountedFile(testCLIBinPath, daggerCliFile(t, c)).
+		WithWorkdir("/work").
+		With(daggerExec("mod", "init", "--name=use", "--sdk=go")).
+		WithNewFile("/work/main.go", dagger.ContainerWithNewFileOpts{
+			Contents: useOuter,
+		}).
+		WithNewFile("/work/dagger.json", dagger.ContainerWithNewFileOpts{
+			Contents: string(modCfg),
+		}).
+		WithDirectory("/work/dagger-vendor/"+depPath+"@"+depCommit, depSrc).
+		WithNewFile("/work/dagger-vendor/modules.json", dagger.ContainerWithNewFileOpts{
+			Contents: vendorManifest(depCommit),
+		})
+
+	t.Run("loads vendored deps", func(t *testing.T) {
+		t.Parallel()
+		out, err := modGen.With(daggerQuery(`{use{useHello}}`)).Stdout(ctx)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"use":{"useHello":"hello"}}`, out)
+	})
+
+	t.Run("prints graph offline", func(t *testing.T) {
+		t.Parallel()
+		out, err := modGen.With(daggerExec("mod", "graph")).Stdout(ctx)
+		require.NoError(t, err)
+		require.Contains(t, out, "dep ("+depPath+"@v1.0.0) v1.0.0 0123456789ab")
+	})
+
+	t.Run("verifies vendored deps", func(t *testing.T) {
+		t.Parallel()
+		_, err := modGen.
+			WithNewFile("/work/dagger-vendor/"+depPath+"@"+depCommit+"/main.go", dagger.ContainerWithNewFileOpts{
+				Contents: useInner + "\n// tampered\n",
+			}).
+			With(daggerQuery(`{use{useHello}}`)).
+			Sync(ctx)
+		require.Error(t, err)
+		require.ErrorContains(t, err, "vendored module "+depPath+" has digest")
+	})
+
+	t.Run("outdated vendor directory", func(t *testing.T) {
+		t.Parallel()
+		_, err := modGen.
+			WithNewFile("/work/dagger-vendor/modules.json", dagger.ContainerWithNewFileOpts{
+				Contents: vendorManifest("89abcdef0123456789abcdef0123456789abcdef"),
+			}).
+			With(daggerQuery(`{use{useHello}}`)).
+			Sync(ctx)
+		require.Error(t, err)
+		require.ErrorContains(t, err, "module "+depPath+" is not vendored at commit "+depCommit)
+	})
+}
+
+func TestModuleDepsCommands(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	base := c.Container().From(golangImage).
+		WithMountedFile(testCLIBinPath, daggerCliFile(t, c)).
+		WithWorkdir("/work/dep").
+		With(daggerExec("mod", "init", "--name=dep", "--sdk=go")).
+		WithNewFile("/work/dep/main.go", dagger.ContainerWithNewFileOpts{
+			Contents: useInner,
+		}).
+		WithWorkdir("/work").
+		With(daggerExec("mod", "init", "--name=use", "--sdk=go"))
+
+	t.Run("deps and info", func(t *testing.T) {
+		t.Parallel()
+
+		modGen := base.
+			WithNewFile("/work/main.go", dagger.ContainerWithNewFileOpts{
+				Contents: useOuter,
+			}).
+			With(daggerExec("mod", "install", "./dep"))
+
+		out, err := modGen.With(daggerExec("mod", "deps")).Stdout(ctx)
+		require.NoError(t, err)
+		require.Regexp(t, `(?m)^dep\s+dep\s+-\s+go\s*$`, out)
+
+		out, err = modGen.With(daggerExec("mod", "info")).Stdout(ctx)
+		require.NoError(t, err)
+		// labels are bold
+		require.Regexp(t, `(?m)name\S*\s+use\s*$`, out)
+		require.Regexp(t, `(?m)dependencies\S*\s+dep\s*$`, out)
+		require.Regexp(t, `(?m)^\*Use\s+useHello\s*$`, out)
+	})
+
+	t.Run("uninstall", func(t *testing.T) {
+		t.Parallel()
+
+		modGen := base.With(daggerExec("mod", "install", "./dep"))
+
+		for _, dep := range []string{"dep", "./dep"} {
+			cfg, err := modGen.
+				With(daggerExec("mod", "uninstall", dep)).
+				File("/work/dagger.json").
+				Contents(ctx)
+			require.NoError(t, err)
+			require.NotContains(t, cfg, "dependencies")
+		}
+
+		_, err := modGen.With(daggerExec("mod", "uninstall", "nope")).Sync(ctx)
+		require.Error(t, err)
+		require.ErrorContains(t, err, `module has no dependency "nope"`)
+	})
+}
+
 func TestModuleCodegenonDepChange(t *testing.T) {
 	t.Parallel()
 
@@ -3210,6 +3371,39 @@ func (m *Test) Fn(dir *Directory, subpath Optional[string]) *Directory {
 			}
 		})
 	})
+
+	t.Run("search directory", func(t *testing.T) {
+		t.Parallel()
+
+		modGen := c.Container().From(golangImage).
+			WithMountedFile(testCLIBinPath, daggerCliFile(t, c)).
+			WithWorkdir("/work").
+			With(daggerExec("mod", "init", "--name=test", "--sdk=go")).
+			WithNewFile("main.go", dagger.ContainerWithNewFileOpts{
+				Contents: `package main
+
+type Test struct {}
+
+func (m *Test) Dir() *Directory {
+	return dag.Directory().
+		WithNewFile("foo.txt", "one\nneedle two\nthree\n").
+		WithNewFile("sub/bar.txt", "needle\n").
+		WithNewFile("baz.txt", "nothing here\n")
+}
+`,
+			})
+
+		logGen(ctx, t, modGen.Directory("."))
+
+		out, err := modGen.With(daggerCall("dir", "--search", "needle")).Stdout(ctx)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		sort.Strings(lines)
+		require.Equal(t, []string{
+			"foo.txt:2:needle two",
+			"sub/bar.txt:1:needle",
+		}, lines)
+	})
 }
 
 func TestModuleGoSyncDeps(t *testing.T) {
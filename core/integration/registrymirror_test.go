@@ -0,0 +1,80 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"dagger.io/dagger"
+	"github.com/moby/buildkit/identity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryMirror(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+
+	// publish an image that only exists on the mirror
+	repo := "mirror-test-" + identity.NewID()
+	_, err := c.Container().From(alpineImage).
+		WithNewFile("/mirrored", dagger.ContainerWithNewFileOpts{Contents: "yes"}).
+		Publish(ctx, fmt.Sprintf("%s/library/%s:v1", registryHost, repo))
+	require.NoError(t, err)
+
+	c = c.WithRegistryMirror("docker.io", registryHost, dagger.WithRegistryMirrorOpts{
+		Insecure: true,
+	})
+	mirrored := c.Container().From(repo + ":v1")
+
+	contents, err := mirrored.File("/mirrored").Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "yes", contents)
+
+	ref, err := mirrored.ImageRef(ctx)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(ref, "docker.io/library/"+repo+":v1@sha256:"), ref)
+
+	mirrors, err := c.RegistryMirrors(ctx)
+	require.NoError(t, err)
+	var found bool
+	for _, m := range mirrors {
+		registry, err := m.Registry(ctx)
+		require.NoError(t, err)
+		mirror, err := m.Mirror(ctx)
+		require.NoError(t, err)
+		engine, err := m.Engine(ctx)
+		require.NoError(t, err)
+		insecure, err := m.Insecure(ctx)
+		require.NoError(t, err)
+		if registry == "docker.io" && mirror == registryHost && !engine && insecure {
+			found = true
+		}
+	}
+	require.True(t, found)
+
+	t.Run("scoped to the session", func(t *testing.T) {
+		other, otherCtx := connect(t)
+
+		// the image only exists on the mirror, which the other session
+		// doesn't know about
+		_, err := other.Container().From(repo + ":v1").Sync(otherCtx)
+		require.Error(t, err)
+
+		mirrors, err := other.RegistryMirrors(otherCtx)
+		require.NoError(t, err)
+		for _, m := range mirrors {
+			mirror, err := m.Mirror(otherCtx)
+			require.NoError(t, err)
+			require.NotEqual(t, registryHost, mirror)
+		}
+	})
+
+	t.Run("falls back to the registry", func(t *testing.T) {
+		c, ctx := connect(t)
+
+		_, err := c.WithRegistryMirror("docker.io", "mirror.invalid").
+			Container().From(alpineImage).Sync(ctx)
+		require.NoError(t, err)
+	})
+}
@@ -1599,10 +1599,14 @@ func httpService(ctx context.Context, t *testing.T, c *dagger.Client, content st
 
 func gitService(ctx context.Context, t testing.TB, c *dagger.Client, content *dagger.Directory) (*dagger.Service, string) {
 	t.Helper()
-	return gitServiceWithBranch(ctx, t, c, content, "main")
+	return gitServiceWithBranch(ctx, t, c, content, "main", "")
 }
 
-func gitServiceWithBranch(ctx context.Context, t testing.TB, c *dagger.Client, content *dagger.Directory, branchName string) (*dagger.Service, string) {
+// gitServiceWithBranch serves a repository over the git protocol, with the
+// given content committed to the given branch. If setup is set, it's run in
+// the repository instead to create its commits. daemonFlags are passed to
+// git daemon.
+func gitServiceWithBranch(ctx context.Context, t testing.TB, c *dagger.Client, content *dagger.Directory, branchName, setup string, daemonFlags ...string) (*dagger.Service, string) {
 	t.Helper()
 
 	const gitPort = 9418
@@ -1612,7 +1616,31 @@ func gitServiceWithBranch(ctx context.Context, t testing.TB, c *dagger.Client, c
 		WithDirectory("/root/repo", content).
 		WithMountedFile("/root/start.sh",
 			c.Directory().
-				WithNewFile("start.sh", fmt.Sprintf(`#!/bin/sh
+				WithNewFile("start.sh", gitRepoScript(branchName, setup)+fmt.Sprintf(`
+git daemon --verbose --export-all %s --base-path=/root/srv
+`, strings.Join(daemonFlags, " "))).
+				File("start.sh")).
+		WithExposedPort(gitPort).
+		WithExec([]string{"sh", "/root/start.sh"}).
+		AsService()
+
+	gitHost, err := gitDaemon.Hostname(ctx)
+	require.NoError(t, err)
+
+	repoURL := fmt.Sprintf("git://%s/repo.git", gitHost)
+
+	return gitDaemon, repoURL
+}
+
+// gitRepoScript returns a script creating a repository in /root/repo, with
+// its content committed to the given branch or created by the given setup
+// script, and a bare clone of it in /root/srv/repo.git.
+func gitRepoScript(branchName, setup string) string {
+	if setup == "" {
+		setup = `git add * || true
+git commit -m "init"`
+	}
+	return fmt.Sprintf(`#!/bin/sh
 
 set -e -u -x
 
@@ -1621,32 +1649,19 @@ cd /root
 git config --global user.email "root@localhost"
 git config --global user.name "Test User"
 
-mkdir srv
+mkdir -p repo srv
 
 cd repo
 	git init
 	git branch -m %s
-	git add * || true
-	git commit -m "init"
+%s
 cd ..
 
 cd srv
 	git clone --bare ../repo repo.git
+	git -C repo.git update-server-info
 cd ..
-
-git daemon --verbose --export-all --base-path=/root/srv
-`, branchName)).
-				File("start.sh")).
-		WithExposedPort(gitPort).
-		WithExec([]string{"sh", "/root/start.sh"}).
-		AsService()
-
-	gitHost, err := gitDaemon.Hostname(ctx)
-	require.NoError(t, err)
-
-	repoURL := fmt.Sprintf("git://%s/repo.git", gitHost)
-
-	return gitDaemon, repoURL
+`, branchName, setup)
 }
 
 var nestingLimitOnce = &sync.Once{}
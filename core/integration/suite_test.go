@@ -261,7 +261,7 @@ func (ctr DaggerCLIContainer) WithLoadedEnv(
 	baseCtr := ctr.Container
 	if convertToGitEnv {
 		branchName := identity.NewID()
-		gitSvc, _ := gitServiceWithBranch(ctr.ctx, ctr.t, ctr.c, thisRepoDir, branchName)
+		gitSvc, _ := gitServiceWithBranch(ctr.ctx, ctr.t, ctr.c, thisRepoDir, branchName, "")
 		baseCtr = baseCtr.WithServiceBinding("git", gitSvc)
 
 		endpoint, err := gitSvc.Endpoint(ctr.ctx)
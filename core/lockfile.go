@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/dagger/dagger/core/modules"
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+)
+
+// Lockfile pins the container image tags and git refs resolved during a
+// session to the digests and commits they resolved to, so that subsequent
+// sessions resolve them the same way.
+//
+// A Lockfile does nothing until it has been loaded, so that sessions that
+// don't use one keep resolving references at run time.
+type Lockfile struct {
+	mu     sync.Mutex
+	loaded bool
+	strict bool
+	pins   lockfilePins
+}
+
+type lockfilePins struct {
+	// Images maps normalized image references (e.g.
+	// docker.io/library/golang:1.21) to the digest they resolved to.
+	Images map[string]digest.Digest `json:"images,omitempty"`
+
+	// Git maps repository URLs to the commit each of their refs resolved to.
+	Git map[string]map[string]string `json:"git,omitempty"`
+}
+
+// ImageResolver resolves an image reference to the digest of its manifest.
+type ImageResolver func(ctx context.Context, ref string) (digest.Digest, error)
+
+// GitRefResolver resolves a ref of the given git repository to a commit.
+type GitRefResolver func(ctx context.Context, url, ref string) (string, error)
+
+func NewLockfile() *Lockfile {
+	return &Lockfile{}
+}
+
+// Load replaces the pins with the ones in the given lockfile contents. In
+// strict mode, resolving a reference that isn't pinned fails instead of
+// recording a new pin.
+func (lock *Lockfile) Load(contents []byte, strict bool) error {
+	var pins lockfilePins
+	if len(contents) > 0 {
+		if err := json.Unmarshal(contents, &pins); err != nil {
+			return fmt.Errorf("parse %s: %w", modules.LockFilename, err)
+		}
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+	lock.loaded = true
+	lock.strict = strict
+	lock.pins = pins
+	return nil
+}
+
+// Contents returns the lockfile's current pins, serialized.
+func (lock *Lockfile) Contents() ([]byte, error) {
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+	contents, err := json.MarshalIndent(lock.pins, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(contents, '\n'), nil
+}
+
+// PinImage returns the given image address pinned to a digest. Addresses that
+// already include a digest are returned as-is.
+func (lock *Lockfile) PinImage(ctx context.Context, addr string, resolve ImageResolver) (string, error) {
+	lock.mu.Lock()
+	loaded, strict := lock.loaded, lock.strict
+	lock.mu.Unlock()
+	if !loaded {
+		return addr, nil
+	}
+
+	refName, err := reference.ParseNormalizedNamed(addr)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := refName.(reference.Canonical); ok {
+		return addr, nil
+	}
+	refName = reference.TagNameOnly(refName)
+	key := refName.String()
+
+	lock.mu.Lock()
+	dgst, found := lock.pins.Images[key]
+	lock.mu.Unlock()
+
+	if !found {
+		if strict {
+			return "", fmt.Errorf("image %s is not pinned in %s", key, modules.LockFilename)
+		}
+		dgst, err = resolve(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		lock.mu.Lock()
+		if lock.pins.Images == nil {
+			lock.pins.Images = map[string]digest.Digest{}
+		}
+		lock.pins.Images[key] = dgst
+		lock.mu.Unlock()
+	}
+
+	digested, err := reference.WithDigest(refName, dgst)
+	if err != nil {
+		return "", err
+	}
+	return digested.String(), nil
+}
+
+// PinGitRef returns the commit the given ref of a git repository is pinned to.
+func (lock *Lockfile) PinGitRef(ctx context.Context, url, ref string, resolve GitRefResolver) (string, error) {
+	lock.mu.Lock()
+	loaded, strict := lock.loaded, lock.strict
+	commit, found := lock.pins.Git[url][ref]
+	lock.mu.Unlock()
+	if !loaded {
+		return ref, nil
+	}
+	if found {
+		return commit, nil
+	}
+
+	if strict {
+		return "", fmt.Errorf("git ref %s of %s is not pinned in %s", ref, url, modules.LockFilename)
+	}
+	commit, err := resolve(ctx, url, ref)
+	if err != nil {
+		return "", err
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+	if lock.pins.Git == nil {
+		lock.pins.Git = map[string]map[string]string{}
+	}
+	if lock.pins.Git[url] == nil {
+		lock.pins.Git[url] = map[string]string{}
+	}
+	lock.pins.Git[url][ref] = commit
+	return commit, nil
+}
+
+// Update re-resolves every pinned reference to its current value.
+func (lock *Lockfile) Update(ctx context.Context, resolveImage ImageResolver, resolveGitRef GitRefResolver) error {
+	lock.mu.Lock()
+	var images []string
+	for ref := range lock.pins.Images {
+		images = append(images, ref)
+	}
+	gitRefs := map[string][]string{}
+	for url, refs := range lock.pins.Git {
+		for ref := range refs {
+			gitRefs[url] = append(gitRefs[url], ref)
+		}
+	}
+	lock.mu.Unlock()
+
+	updatedImages := map[string]digest.Digest{}
+	for _, ref := range images {
+		dgst, err := resolveImage(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("update image %s: %w", ref, err)
+		}
+		updatedImages[ref] = dgst
+	}
+
+	updatedGit := map[string]map[string]string{}
+	for url, refs := range gitRefs {
+		updatedGit[url] = map[string]string{}
+		for _, ref := range refs {
+			commit, err := resolveGitRef(ctx, url, ref)
+			if err != nil {
+				return fmt.Errorf("update git ref %s of %s: %w", ref, url, err)
+			}
+			updatedGit[url][ref] = commit
+		}
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+	for ref, dgst := range updatedImages {
+		lock.pins.Images[ref] = dgst
+	}
+	for url, refs := range updatedGit {
+		for ref, commit := range refs {
+			lock.pins.Git[url][ref] = commit
+		}
+	}
+	return nil
+}
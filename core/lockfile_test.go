@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockfile(t *testing.T) {
+	ctx := context.Background()
+
+	imageDigest := digest.FromString("image-v1")
+	resolveImage := func(ctx context.Context, ref string) (digest.Digest, error) {
+		return imageDigest, nil
+	}
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	resolveGitRef := func(ctx context.Context, url, ref string) (string, error) {
+		return commit, nil
+	}
+
+	t.Run("not loaded", func(t *testing.T) {
+		lock := NewLockfile()
+
+		addr, err := lock.PinImage(ctx, "golang:1.21", resolveImage)
+		require.NoError(t, err)
+		require.Equal(t, "golang:1.21", addr)
+
+		ref, err := lock.PinGitRef(ctx, "https://example.com/repo", "main", resolveGitRef)
+		require.NoError(t, err)
+		require.Equal(t, "main", ref)
+	})
+
+	t.Run("records and reuses pins", func(t *testing.T) {
+		lock := NewLockfile()
+		require.NoError(t, lock.Load(nil, false))
+
+		addr, err := lock.PinImage(ctx, "golang:1.21", resolveImage)
+		require.NoError(t, err)
+		require.Equal(t, "docker.io/library/golang:1.21@"+imageDigest.String(), addr)
+
+		ref, err := lock.PinGitRef(ctx, "https://example.com/repo", "main", resolveGitRef)
+		require.NoError(t, err)
+		require.Equal(t, commit, ref)
+
+		contents, err := lock.Contents()
+		require.NoError(t, err)
+
+		reloaded := NewLockfile()
+		require.NoError(t, reloaded.Load(contents, true))
+
+		failResolve := func(ctx context.Context, ref string) (digest.Digest, error) {
+			t.Fatal("pinned image should not be resolved")
+			return "", nil
+		}
+		addr, err = reloaded.PinImage(ctx, "docker.io/library/golang:1.21", failResolve)
+		require.NoError(t, err)
+		require.Equal(t, "docker.io/library/golang:1.21@"+imageDigest.String(), addr)
+
+		ref, err = reloaded.PinGitRef(ctx, "https://example.com/repo", "main", resolveGitRef)
+		require.NoError(t, err)
+		require.Equal(t, commit, ref)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		lock := NewLockfile()
+		require.NoError(t, lock.Load([]byte(`{}`), true))
+
+		_, err := lock.PinImage(ctx, "alpine", resolveImage)
+		require.ErrorContains(t, err, "image docker.io/library/alpine:latest is not pinned")
+
+		_, err = lock.PinGitRef(ctx, "https://example.com/repo", "main", resolveGitRef)
+		require.ErrorContains(t, err, "git ref main of https://example.com/repo is not pinned")
+
+		digested := "alpine@" + imageDigest.String()
+		addr, err := lock.PinImage(ctx, digested, resolveImage)
+		require.NoError(t, err)
+		require.Equal(t, digested, addr)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		lock := NewLockfile()
+		require.NoError(t, lock.Load(nil, false))
+		_, err := lock.PinImage(ctx, "alpine", resolveImage)
+		require.NoError(t, err)
+		_, err = lock.PinGitRef(ctx, "https://example.com/repo", "main", resolveGitRef)
+		require.NoError(t, err)
+
+		newDigest := digest.FromString("image-v2")
+		newCommit := "89abcdef0123456789abcdef0123456789abcdef"
+		err = lock.Update(ctx,
+			func(ctx context.Context, ref string) (digest.Digest, error) {
+				return newDigest, nil
+			},
+			func(ctx context.Context, url, ref string) (string, error) {
+				return newCommit, nil
+			},
+		)
+		require.NoError(t, err)
+
+		addr, err := lock.PinImage(ctx, "alpine", resolveImage)
+		require.NoError(t, err)
+		require.Equal(t, "docker.io/library/alpine:latest@"+newDigest.String(), addr)
+
+		ref, err := lock.PinGitRef(ctx, "https://example.com/repo", "main", resolveGitRef)
+		require.NoError(t, err)
+		require.Equal(t, newCommit, ref)
+	})
+}
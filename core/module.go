@@ -33,6 +33,19 @@ type Module struct {
 	// Dependencies as configured by the module
 	DependencyConfig []string `json:"dependencyConfig"`
 
+	// The pins of the module's git dependencies, keyed by their entry in
+	// DependencyConfig
+	DependencyPins map[string]*modules.DependencyPin `json:"dependencyPins,omitempty"`
+
+	// The pins of the versions selected across the dependency graph of the
+	// root module for git modules depended on at lower versions, keyed by
+	// their path. They're inherited by every module of the graph.
+	SelectedVersions map[string]*modules.DependencyPin `json:"selectedVersions,omitempty"`
+
+	// The vendor directory the module's git dependencies are loaded from, if
+	// any
+	Vendor *ModuleVendor `json:"vendor,omitempty"`
+
 	// The module's objects
 	Objects []*TypeDef `json:"objects,omitempty"`
 
@@ -65,6 +78,13 @@ func (mod *Module) PBDefinitions() ([]*pb.Definition, error) {
 		}
 		defs = append(defs, dirDefs...)
 	}
+	if mod.Vendor != nil {
+		vendorDefs, err := mod.Vendor.Directory.PBDefinitions()
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, vendorDefs...)
+	}
 	return defs, nil
 }
 
@@ -74,6 +94,20 @@ func (mod Module) Clone() *Module {
 		cp.SourceDirectory = mod.SourceDirectory.Clone()
 	}
 	cp.DependencyConfig = cloneSlice(mod.DependencyConfig)
+	if mod.DependencyPins != nil {
+		cp.DependencyPins = make(map[string]*modules.DependencyPin, len(mod.DependencyPins))
+		for dep, pin := range mod.DependencyPins {
+			pinCp := *pin
+			cp.DependencyPins[dep] = &pinCp
+		}
+	}
+	if mod.SelectedVersions != nil {
+		cp.SelectedVersions = make(map[string]*modules.DependencyPin, len(mod.SelectedVersions))
+		for modPath, pin := range mod.SelectedVersions {
+			pinCp := *pin
+			cp.SelectedVersions[modPath] = &pinCp
+		}
+	}
 	cp.Objects = make([]*TypeDef, len(mod.Objects))
 	for i, def := range mod.Objects {
 		cp.Objects[i] = def.Clone()
@@ -81,6 +115,13 @@ func (mod Module) Clone() *Module {
 	return &cp
 }
 
+// DependencyPin returns the pin of the given dependency of the module, or
+// the pin of the version selected for it across the dependency graph of the
+// root module if it's higher.
+func (mod *Module) DependencyPin(dep string) *modules.DependencyPin {
+	return modules.SelectedPin(dep, mod.DependencyPins[dep], mod.SelectedVersions)
+}
+
 func (mod *Module) WithObject(def *TypeDef) (*Module, error) {
 	mod = mod.Clone()
 	if def.AsObject == nil {
@@ -142,32 +183,29 @@ func ModuleFromConfig(
 	}
 
 	// Reposition the root of the sourceDir in case it's pointing to a subdir of current sourceDir
-	if filepath.Clean(cfg.Root) != "." {
-		rootPath := filepath.Join(filepath.Dir(configPath), cfg.Root)
-		if rootPath != filepath.Dir(configPath) {
-			configPathAbs, err := filepath.Abs(configPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get config absolute path: %w", err)
-			}
-			rootPathAbs, err := filepath.Abs(rootPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get root absolute path: %w", err)
-			}
-
-			configPath, err = filepath.Rel(rootPathAbs, configPathAbs)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get config relative to root: %w", err)
-			}
-			if strings.HasPrefix(configPath, "../") {
-				// this likely shouldn't happen, a client shouldn't submit a
-				// module config that escapes the module root
-				return nil, fmt.Errorf("module subpath is not under module root")
-			}
-
-			sourceDir, err = sourceDir.Directory(ctx, bk, svcs, rootPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get root directory: %w", err)
-			}
+	if rootPath := cfg.SourceRootPath(filepath.Dir(configPath)); rootPath != "." {
+		configPathAbs, err := filepath.Abs(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config absolute path: %w", err)
+		}
+		rootPathAbs, err := filepath.Abs(rootPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get root absolute path: %w", err)
+		}
+
+		configPath, err = filepath.Rel(rootPathAbs, configPathAbs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config relative to root: %w", err)
+		}
+		if strings.HasPrefix(configPath, "../") {
+			// this likely shouldn't happen, a client shouldn't submit a
+			// module config that escapes the module root
+			return nil, fmt.Errorf("module subpath is not under module root")
+		}
+
+		sourceDir, err = sourceDir.Directory(ctx, bk, svcs, rootPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get root directory: %w", err)
 		}
 	}
 
@@ -176,6 +214,8 @@ func ModuleFromConfig(
 		SourceDirectorySubpath: filepath.Dir(configPath),
 		Name:                   cfg.Name,
 		DependencyConfig:       cfg.Dependencies,
+		DependencyPins:         cfg.DependencyPins,
+		SelectedVersions:       cfg.SelectedVersions,
 		SDK:                    cfg.SDK,
 	}, nil
 }
@@ -183,6 +223,9 @@ func ModuleFromConfig(
 // Load the module metadata from the given module reference.
 // parentSrcDir and parentSrcSubpath are used to resolve local
 // module refs if needed (i.e. this is a local dep of another module)
+// If the ref is pinned, the module is loaded from the pinned commit and its
+// source is verified against the pinned digest. Git modules are loaded from
+// the vendor directory instead of fetched if they're vendored in it.
 func ModuleFromRef(
 	ctx context.Context,
 	bk *buildkit.Client,
@@ -192,8 +235,10 @@ func ModuleFromRef(
 	parentSrcDir *Directory, // nil if not being loaded as a dep of another mod
 	parentSrcSubpath string, // "" if not being loaded as a dep of another mod
 	moduleRefStr string,
+	pin *modules.DependencyPin, // nil if the ref isn't pinned
+	vendor *ModuleVendor, // nil if the parent isn't vendoring its dependencies
 ) (*Module, error) {
-	modRef, err := modules.ResolveStableRef(moduleRefStr)
+	modRef, err := modules.ResolveStableRef(pin.PinnedRef(moduleRefStr))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse dependency url %q: %w", moduleRefStr, err)
 	}
@@ -218,6 +263,13 @@ func ModuleFromRef(
 		}
 	case modRef.Git != nil:
 		var err error
+		sourceDir, configPath, err = vendor.Source(ctx, bk, svcs, modRef.Path, modRef.Git.Commit)
+		if err != nil {
+			return nil, err
+		}
+		if sourceDir != nil {
+			break
+		}
 		sourceDir, err = NewDirectorySt(ctx, llb.Git(modRef.Git.CloneURL, modRef.Version), "", pipeline, platform, nil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create git directory: %w", err)
@@ -227,5 +279,19 @@ func ModuleFromRef(
 		return nil, fmt.Errorf("invalid module ref %q", moduleRefStr)
 	}
 
-	return ModuleFromConfig(ctx, bk, svcs, sourceDir, configPath)
+	mod, err := ModuleFromConfig(ctx, bk, svcs, sourceDir, configPath)
+	if err != nil {
+		return nil, err
+	}
+	if modRef.Git != nil && pin != nil && pin.Digest != "" {
+		dgst, err := mod.SourceDirectory.ContentDigest(ctx, bk, svcs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get digest of module %q: %w", moduleRefStr, err)
+		}
+		if dgst.String() != pin.Digest {
+			return nil, fmt.Errorf("module %q at commit %s has digest %s, but %s is pinned; run `dagger mod update` if the change is expected", moduleRefStr, pin.Commit, dgst, pin.Digest)
+		}
+	}
+	mod.Vendor = vendor
+	return mod, nil
 }
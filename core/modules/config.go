@@ -8,11 +8,16 @@ import (
 	"strings"
 
 	"dagger.io/dagger"
+	"golang.org/x/mod/semver"
 )
 
 // Filename is the name of the module config file.
 const Filename = "dagger.json"
 
+// LockFilename is the name of the lockfile pinning the references resolved
+// by the module, kept next to its config file.
+const LockFilename = "dagger.lock"
+
 // Config is the module config loaded from dagger.json.
 type Config struct {
 	// The name of the module.
@@ -31,8 +36,64 @@ type Config struct {
 	// Exclude these file globs when loading the module root.
 	Exclude []string `json:"exclude,omitempty"`
 
+	// Exclude the files ignored by git when loading the module root.
+	Gitignore bool `json:"gitignore,omitempty"`
+
+	// Exclude the files ignored by the .dockerignore file of the module root
+	// when loading it.
+	Dockerignore bool `json:"dockerignore,omitempty"`
+
 	// Modules that this module depends on.
 	Dependencies []string `json:"dependencies,omitempty"`
+
+	// The commits and content digests the git dependencies resolved to, keyed
+	// by their entry in Dependencies. Local dependencies aren't pinned since
+	// they're part of the module's own source.
+	DependencyPins map[string]*DependencyPin `json:"dependencyPins,omitempty"`
+
+	// The pins of the versions selected across the dependency graph for the
+	// git modules that other modules of the graph depend on at lower versions,
+	// keyed by their path. They override the pins of those modules when the
+	// graph is loaded, so that each module is loaded at a single version.
+	SelectedVersions map[string]*DependencyPin `json:"selectedVersions,omitempty"`
+}
+
+// DependencyPin pins a git dependency to what it resolved to when it was
+// installed or last updated.
+type DependencyPin struct {
+	// The commit the dependency's version resolved to.
+	Commit string `json:"commit"`
+
+	// The content digest of the dependency's source root at that commit.
+	Digest string `json:"digest"`
+
+	// The semver version of the tag the dependency's version resolved to, if
+	// any, which is what versions are selected by.
+	Version string `json:"version,omitempty"`
+}
+
+// SelectedPin returns the pin of the version selected for the given
+// dependency in selected, keyed by module path, if it's higher than the
+// dependency's own pin, or its own pin otherwise.
+func SelectedPin(dep string, pin *DependencyPin, selected map[string]*DependencyPin) *DependencyPin {
+	if pin == nil || pin.Version == "" {
+		return pin
+	}
+	modPath, _, _ := strings.Cut(dep, "@")
+	if selectedPin := selected[modPath]; selectedPin != nil && semver.Compare(selectedPin.Version, pin.Version) > 0 {
+		return selectedPin
+	}
+	return pin
+}
+
+// PinnedRef returns the given dependency ref with its version replaced by the
+// pinned commit, or the ref unchanged if there's no pin.
+func (pin *DependencyPin) PinnedRef(ref string) string {
+	if pin == nil || pin.Commit == "" {
+		return ref
+	}
+	modPath, _, _ := strings.Cut(ref, "@")
+	return modPath + "@" + pin.Commit
 }
 
 func NewConfig(name, sdkNameOrRef, rootPath string) *Config {
@@ -57,27 +118,187 @@ func (cfg *Config) RootAndSubpath(modSourceDir string) (string, string, error) {
 	return modRootDir, subPath, nil
 }
 
-// Use adds the given module references to the module's dependencies.
+// SourceRootPath returns the path of the module's source root, relative to
+// the directory the config file is in, the same way the engine repositions
+// the source directory of a module when loading it.
+func (cfg *Config) SourceRootPath(configDir string) string {
+	if filepath.Clean(cfg.Root) == "." {
+		return "."
+	}
+	rootPath := filepath.Join(configDir, cfg.Root)
+	if rootPath == filepath.Clean(configDir) {
+		return "."
+	}
+	return rootPath
+}
+
+// Use adds the given module references to the module's dependencies. The
+// dependencies already installed keep their pins, while the new git ones are
+// resolved and pinned.
 func (cfg *Config) Use(ctx context.Context, dag *dagger.Client, ref *Ref, refs ...string) error {
-	var deps []string
-	deps = append(deps, cfg.Dependencies...)
-	deps = append(deps, refs...)
-	depSet := make(map[string]*Ref)
-	for _, dep := range deps {
+	depSet := make(map[string]string)
+	pins := make(map[string]*DependencyPin)
+	for _, dep := range cfg.Dependencies {
+		pin := cfg.DependencyPins[dep]
+		depMod, err := resolvePinnedDependency(ref, pin.PinnedRef(dep))
+		if err != nil {
+			return fmt.Errorf("failed to get module: %w", err)
+		}
+		if depMod.Local {
+			dep = depMod.String()
+		}
+		depSet[depMod.Symbolic()] = dep
+		if pin != nil {
+			pins[dep] = pin
+		}
+	}
+	for _, dep := range refs {
 		depMod, err := ResolveModuleDependency(ctx, dag, ref, dep)
 		if err != nil {
 			return fmt.Errorf("failed to get module: %w", err)
 		}
-		depSet[depMod.Symbolic()] = depMod
+		if depMod.Local {
+			dep = depMod.String()
+		} else {
+			pin, err := depMod.Pin(ctx, dag)
+			if err != nil {
+				return fmt.Errorf("failed to pin module %s: %w", dep, err)
+			}
+			pins[dep] = pin
+		}
+		depSet[depMod.Symbolic()] = dep
 	}
 
+	cfg.setDependencies(depSet, pins)
+	return nil
+}
+
+// Update re-resolves the given dependencies of the module, or all of them if
+// none is given, and pins the git ones to what they now resolve to. A
+// dependency is given either by its entry in the config or by its path, in
+// which case a version may be given to switch to, e.g. github.com/foo/bar@v2.
+func (cfg *Config) Update(ctx context.Context, dag *dagger.Client, ref *Ref, deps ...string) error {
+	updates := make(map[string]string, len(cfg.Dependencies))
+	if len(deps) == 0 {
+		for _, dep := range cfg.Dependencies {
+			updates[dep] = dep
+		}
+	}
+	for _, dep := range deps {
+		existing, ok := cfg.findDependency(dep)
+		if !ok {
+			return fmt.Errorf("module has no dependency %q", dep)
+		}
+		if !strings.Contains(dep, "@") {
+			// keep following the version it was installed with
+			dep = existing
+		}
+		updates[existing] = dep
+	}
+
+	depSet := make(map[string]string)
+	pins := make(map[string]*DependencyPin)
+	for _, dep := range cfg.Dependencies {
+		update, ok := updates[dep]
+		if !ok {
+			depSet[dep] = dep
+			if pin := cfg.DependencyPins[dep]; pin != nil {
+				pins[dep] = pin
+			}
+			continue
+		}
+		depMod, err := ResolveModuleDependency(ctx, dag, ref, update)
+		if err != nil {
+			return fmt.Errorf("failed to get module: %w", err)
+		}
+		if depMod.Local {
+			// nothing to update, it's part of the module's source
+			depSet[dep] = dep
+			continue
+		}
+		pin, err := depMod.Pin(ctx, dag)
+		if err != nil {
+			return fmt.Errorf("failed to pin module %s: %w", update, err)
+		}
+		pins[update] = pin
+		depSet[dep] = update
+	}
+
+	cfg.setDependencies(depSet, pins)
+	return nil
+}
+
+// Uninstall removes the given dependencies from the module's dependencies,
+// along with their pins. A dependency is given either by its entry in the
+// config, by its path, or by the name of its module.
+func (cfg *Config) Uninstall(ctx context.Context, dag *dagger.Client, ref *Ref, deps ...string) error {
+	var byName map[string]string
+	removed := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		existing, ok := cfg.findDependency(dep)
+		if !ok {
+			if byName == nil {
+				depNodes, err := ResolveDependencies(ctx, dag, ref, cfg)
+				if err != nil {
+					return fmt.Errorf("failed to get module dependencies: %w", err)
+				}
+				byName = make(map[string]string, len(depNodes))
+				for _, depNode := range depNodes {
+					byName[depNode.Config.Name] = depNode.Dependency
+				}
+			}
+			existing, ok = byName[dep]
+		}
+		if !ok {
+			return fmt.Errorf("module has no dependency %q", dep)
+		}
+		removed[existing] = true
+	}
+
+	depSet := make(map[string]string)
+	pins := make(map[string]*DependencyPin)
+	for _, dep := range cfg.Dependencies {
+		if removed[dep] {
+			continue
+		}
+		depSet[dep] = dep
+		if pin := cfg.DependencyPins[dep]; pin != nil {
+			pins[dep] = pin
+		}
+	}
+
+	cfg.setDependencies(depSet, pins)
+	return nil
+}
+
+// findDependency returns the entry of the given dependency in the config,
+// matching either the whole entry or its path.
+func (cfg *Config) findDependency(dep string) (string, bool) {
+	depPath, _, _ := strings.Cut(dep, "@")
+	for _, existing := range cfg.Dependencies {
+		existingPath, _, _ := strings.Cut(existing, "@")
+		if existing == dep || existingPath == depPath || filepath.Clean(existingPath) == filepath.Clean(depPath) {
+			return existing, true
+		}
+	}
+	return "", false
+}
+
+// setDependencies sets the dependencies of the config to the values of the
+// given set, sorted, along with the pins of the git ones.
+func (cfg *Config) setDependencies(depSet map[string]string, pins map[string]*DependencyPin) {
 	cfg.Dependencies = nil
+	cfg.DependencyPins = nil
 	for _, dep := range depSet {
-		cfg.Dependencies = append(cfg.Dependencies, dep.String())
+		cfg.Dependencies = append(cfg.Dependencies, dep)
+		if pin, ok := pins[dep]; ok {
+			if cfg.DependencyPins == nil {
+				cfg.DependencyPins = make(map[string]*DependencyPin)
+			}
+			cfg.DependencyPins[dep] = pin
+		}
 	}
 	sort.Strings(cfg.Dependencies)
-
-	return nil
 }
 
 // NormalizeConfigPath appends /dagger.json to the given path if it is not
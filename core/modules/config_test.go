@@ -0,0 +1,163 @@
+package modules
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencyPinPinnedRef(t *testing.T) {
+	pin := &DependencyPin{Commit: "0123456789abcdef0123456789abcdef01234567"}
+	require.Equal(t, "github.com/foo/bar/baz@"+pin.Commit, pin.PinnedRef("github.com/foo/bar/baz@v1.2.0"))
+	require.Equal(t, "github.com/foo/bar@"+pin.Commit, pin.PinnedRef("github.com/foo/bar"))
+
+	var noPin *DependencyPin
+	require.Equal(t, "github.com/foo/bar@v1.2.0", noPin.PinnedRef("github.com/foo/bar@v1.2.0"))
+	require.Equal(t, "github.com/foo/bar@main", (&DependencyPin{}).PinnedRef("github.com/foo/bar@main"))
+}
+
+func TestConfigSourceRootPath(t *testing.T) {
+	for _, tc := range []struct {
+		root      string
+		configDir string
+		want      string
+	}{
+		{root: "", configDir: ".", want: "."},
+		{root: ".", configDir: "foo", want: "."},
+		{root: "..", configDir: "foo", want: "."},
+		{root: "..", configDir: "foo/bar", want: "foo"},
+		{root: "../..", configDir: "foo/bar/baz", want: "foo"},
+	} {
+		cfg := &Config{Root: tc.root}
+		require.Equal(t, tc.want, cfg.SourceRootPath(tc.configDir), "root %q in %q", tc.root, tc.configDir)
+	}
+}
+
+func TestConfigUseKeepsPins(t *testing.T) {
+	pin := &DependencyPin{
+		Commit: "0123456789abcdef0123456789abcdef01234567",
+		Digest: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}
+	cfg := &Config{
+		Dependencies: []string{
+			"github.com/foo/bar@v1.2.0",
+			"dep",
+		},
+		DependencyPins: map[string]*DependencyPin{
+			"github.com/foo/bar@v1.2.0": pin,
+			"github.com/foo/gone@main":  pin,
+		},
+	}
+
+	// no new dependency, so nothing to resolve remotely
+	err := cfg.Use(context.Background(), nil, &Ref{Path: ".", Local: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"dep", "github.com/foo/bar@v1.2.0"}, cfg.Dependencies)
+	require.Equal(t, map[string]*DependencyPin{
+		"github.com/foo/bar@v1.2.0": pin,
+	}, cfg.DependencyPins)
+}
+
+func TestConfigUpdateUnknownDependency(t *testing.T) {
+	cfg := &Config{
+		Dependencies: []string{"github.com/foo/bar@v1.2.0"},
+	}
+	err := cfg.Update(context.Background(), nil, &Ref{Path: ".", Local: true}, "github.com/foo/baz@v1.2.0")
+	require.ErrorContains(t, err, `module has no dependency "github.com/foo/baz@v1.2.0"`)
+
+	dep, ok := cfg.findDependency("github.com/foo/bar@v2.0.0")
+	require.True(t, ok)
+	require.Equal(t, "github.com/foo/bar@v1.2.0", dep)
+	dep, ok = cfg.findDependency("github.com/foo/bar")
+	require.True(t, ok)
+	require.Equal(t, "github.com/foo/bar@v1.2.0", dep)
+}
+
+func TestDependencyGraphSelectVersions(t *testing.T) {
+	gitNode := func(name, dep, version string, deps ...*DependencyNode) *DependencyNode {
+		modPath, _, _ := strings.Cut(dep, "@")
+		ref, err := ResolveStableRef(modPath + "@" + version)
+		require.NoError(t, err)
+		return &DependencyNode{
+			Dependency:   dep,
+			Ref:          ref,
+			Pin:          &DependencyPin{Commit: version, Version: version},
+			Config:       &Config{Name: name},
+			Dependencies: deps,
+		}
+	}
+	newGraph := func(deps ...*DependencyNode) *DependencyGraph {
+		return &DependencyGraph{
+			Root: &DependencyNode{
+				Ref:          &Ref{Path: ".", Local: true},
+				Config:       &Config{Name: "root"},
+				Dependencies: deps,
+			},
+			Selected: map[string]*DependencyPin{},
+		}
+	}
+
+	graph := newGraph(
+		gitNode("a", "github.com/foo/a@^1.0", "v1.0.0",
+			gitNode("c", "github.com/foo/c@^1.2", "v1.2.0"),
+		),
+		gitNode("b", "github.com/foo/b@v1.0.0", "v1.0.0",
+			gitNode("c", "github.com/foo/c@^1.4", "v1.4.0"),
+		),
+	)
+	require.NoError(t, graph.selectVersions())
+	c := graph.Root.Dependencies[1].Dependencies[0]
+	require.Equal(t, "v1.4.0", graph.Selected[c.Ref.Symbolic()].Version)
+
+	// a's dependency on c is loaded at the version b requires
+	lowC := graph.Root.Dependencies[0].Dependencies[0]
+	require.Equal(t, "v1.4.0", graph.SelectedPin(lowC).Version)
+	require.Same(t, c.Pin, graph.SelectedPin(c))
+	require.Equal(t, map[string]*DependencyPin{
+		"github.com/foo/c": {Commit: "v1.4.0", Version: "v1.4.0"},
+	}, graph.selectedVersions())
+
+	graph = newGraph(
+		gitNode("a", "github.com/foo/a@^1.0", "v1.0.0",
+			gitNode("c", "github.com/foo/c@~1.2", "v1.2.0"),
+		),
+		gitNode("c", "github.com/foo/c@^1.4", "v1.4.0"),
+	)
+	require.ErrorContains(t, graph.selectVersions(), "module a requires github.com/foo/c@~1.2, but module root requires v1.4.0")
+}
+
+func TestSelectedPin(t *testing.T) {
+	pin := &DependencyPin{Commit: "aaaa", Version: "v1.2.0"}
+	higher := &DependencyPin{Commit: "bbbb", Version: "v1.4.0"}
+	selected := map[string]*DependencyPin{"github.com/foo/bar": higher}
+
+	require.Same(t, higher, SelectedPin("github.com/foo/bar@^1.2", pin, selected))
+	require.Same(t, pin, SelectedPin("github.com/foo/baz@^1.2", pin, selected))
+	require.Same(t, higher, SelectedPin("github.com/foo/bar@^1.4", higher, map[string]*DependencyPin{"github.com/foo/bar": pin}))
+
+	// unversioned pins are never replaced
+	unversioned := &DependencyPin{Commit: "cccc"}
+	require.Same(t, unversioned, SelectedPin("github.com/foo/bar@main", unversioned, selected))
+	require.Nil(t, SelectedPin("./bar", nil, selected))
+}
+
+func TestConfigUninstall(t *testing.T) {
+	pin := &DependencyPin{Commit: "0123456789abcdef0123456789abcdef01234567"}
+	cfg := &Config{
+		Dependencies: []string{"dep", "github.com/foo/bar@^1.2", "github.com/foo/baz@main"},
+		DependencyPins: map[string]*DependencyPin{
+			"github.com/foo/bar@^1.2": pin,
+			"github.com/foo/baz@main": pin,
+		},
+	}
+
+	// matched by path, so nothing to resolve remotely
+	err := cfg.Uninstall(context.Background(), nil, &Ref{Path: ".", Local: true}, "github.com/foo/bar", "./dep")
+	require.NoError(t, err)
+	require.Equal(t, []string{"github.com/foo/baz@main"}, cfg.Dependencies)
+	require.Equal(t, map[string]*DependencyPin{
+		"github.com/foo/baz@main": pin,
+	}, cfg.DependencyPins)
+}
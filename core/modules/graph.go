@@ -0,0 +1,316 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"dagger.io/dagger"
+	"golang.org/x/mod/semver"
+)
+
+// DependencyNode is a module of a dependency graph, along with its own
+// dependencies.
+type DependencyNode struct {
+	// The module's entry in the config of its dependent, or "" for the root
+	// of the graph.
+	Dependency string
+
+	// The module's ref, at the commit it's pinned to if it's a git module.
+	Ref *Ref
+
+	// The module's pin in the config of its dependent, if any.
+	Pin *DependencyPin
+
+	// The module's config.
+	Config *Config
+
+	Dependencies []*DependencyNode
+}
+
+// DependencyGraph is the graph of the dependencies of a module, along with
+// the versions selected for its versioned git modules.
+type DependencyGraph struct {
+	Root *DependencyNode
+
+	// The pin of the version selected for each versioned git module of the
+	// graph, keyed by its symbolic ref.
+	Selected map[string]*DependencyPin
+}
+
+// ResolveDependencyGraph resolves the graph of the dependencies of the given
+// module, each git one at the commit it's pinned to, and selects the version
+// of each git module required at different versions.
+//
+// Versions are selected like Go's minimal version selection: the selected
+// version of a module is the highest of the versions it's pinned to across
+// the graph, which must satisfy every version constraint on it.
+func ResolveDependencyGraph(ctx context.Context, dag *dagger.Client, ref *Ref, cfg *Config) (*DependencyGraph, error) {
+	root := &DependencyNode{
+		Ref:    ref,
+		Config: cfg,
+	}
+	r, err := newGraphResolver(dag, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.resolve(ctx, root, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	graph := &DependencyGraph{
+		Root:     root,
+		Selected: map[string]*DependencyPin{},
+	}
+	if err := graph.selectVersions(); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// ResolveDependencies resolves the direct dependencies of the given module,
+// each git one at the commit it's pinned to, without theirs.
+func ResolveDependencies(ctx context.Context, dag *dagger.Client, ref *Ref, cfg *Config) ([]*DependencyNode, error) {
+	root := &DependencyNode{
+		Ref:    ref,
+		Config: cfg,
+	}
+	r, err := newGraphResolver(dag, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.resolveDependencies(ctx, root); err != nil {
+		return nil, err
+	}
+	return root.Dependencies, nil
+}
+
+type graphResolver struct {
+	dag *dagger.Client
+
+	// configs caches the configs of the modules of the graph by their
+	// symbolic ref and version, since they may be reached many times.
+	configs map[string]*Config
+
+	// vendor is the manifest of the root module's vendor directory, if any.
+	vendor    *VendorManifest
+	vendorDir string
+}
+
+func newGraphResolver(dag *dagger.Client, ref *Ref) (*graphResolver, error) {
+	r := &graphResolver{
+		dag:     dag,
+		configs: map[string]*Config{},
+	}
+	if ref.Local {
+		// read the configs of vendored modules from their copies, so that the
+		// graph can be resolved offline
+		moduleDir, err := ref.LocalSourcePath()
+		if err != nil {
+			return nil, err
+		}
+		r.vendor, err = LoadVendorManifest(moduleDir)
+		if err != nil {
+			return nil, err
+		}
+		r.vendorDir = filepath.Join(moduleDir, VendorDirname)
+	}
+	return r, nil
+}
+
+// config returns the config of the given module, from its vendored copy if
+// there's one at the same commit.
+func (r *graphResolver) config(ctx context.Context, ref *Ref) (*Config, error) {
+	if ref.Git != nil {
+		// an outdated vendor directory is ignored, since it's only read as an
+		// optimization here
+		if vendored, _ := r.vendor.Lookup(ref.Path, ref.Git.Commit); vendored != nil {
+			vendoredRef := &Ref{
+				Path:  filepath.Join(r.vendorDir, vendored.Dir, vendored.SourceSubpath),
+				Local: true,
+			}
+			return vendoredRef.Config(ctx, r.dag)
+		}
+	}
+	return ref.Config(ctx, r.dag)
+}
+
+func (r *graphResolver) resolve(ctx context.Context, node *DependencyNode, ancestors map[string]bool) error {
+	key := node.key()
+	if ancestors[key] {
+		return fmt.Errorf("module %s has a circular dependency", node.Config.Name)
+	}
+	ancestors[key] = true
+	defer delete(ancestors, key)
+
+	if err := r.resolveDependencies(ctx, node); err != nil {
+		return err
+	}
+	for _, dep := range node.Dependencies {
+		if err := r.resolve(ctx, dep, ancestors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveDependencies resolves the direct dependencies of the node.
+func (r *graphResolver) resolveDependencies(ctx context.Context, node *DependencyNode) error {
+	for _, dep := range node.Config.Dependencies {
+		pin := node.Config.DependencyPins[dep]
+		depRef, err := resolvePinnedDependency(node.Ref, pin.PinnedRef(dep))
+		if err != nil {
+			return fmt.Errorf("dependency %s of module %s: %w", dep, node.Config.Name, err)
+		}
+		if depRef.Local {
+			// resolve its own local dependencies relative to it
+			depRef = &Ref{
+				Path:  filepath.Join(depRef.Path, depRef.SubPath),
+				Local: true,
+			}
+		}
+
+		depNode := &DependencyNode{
+			Dependency: dep,
+			Ref:        depRef,
+			Pin:        pin,
+		}
+		depKey := depNode.key()
+		depCfg, ok := r.configs[depKey]
+		if !ok {
+			depCfg, err = r.config(ctx, depRef)
+			if err != nil {
+				return fmt.Errorf("dependency %s of module %s: %w", dep, node.Config.Name, err)
+			}
+			r.configs[depKey] = depCfg
+		}
+		depNode.Config = depCfg
+		node.Dependencies = append(node.Dependencies, depNode)
+	}
+	return nil
+}
+
+// selectVersions selects the highest version each versioned git module is
+// pinned to, and checks that it satisfies every constraint on the module.
+func (graph *DependencyGraph) selectVersions() error {
+	selectedBy := map[string]string{}
+	graph.Root.walk(func(parent, node *DependencyNode) {
+		if node.Ref.Git == nil || node.Pin == nil || node.Pin.Version == "" {
+			return
+		}
+		sym := node.Ref.Symbolic()
+		selected, ok := graph.Selected[sym]
+		if !ok || semver.Compare(node.Pin.Version, selected.Version) > 0 {
+			graph.Selected[sym] = node.Pin
+			selectedBy[sym] = parent.Config.Name
+		}
+	})
+
+	var conflicts []string
+	graph.Root.walk(func(parent, node *DependencyNode) {
+		if node.Ref.Git == nil {
+			return
+		}
+		sym := node.Ref.Symbolic()
+		selected, ok := graph.Selected[sym]
+		if !ok {
+			return
+		}
+		_, version, _ := strings.Cut(node.Dependency, "@")
+		if !IsVersionConstraint(version) {
+			return
+		}
+		constraint, err := ParseVersionConstraint(version)
+		if err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("module %s requires %s: %s", parent.Config.Name, node.Dependency, err))
+			return
+		}
+		if !constraint.Allows(selected.Version) {
+			conflicts = append(conflicts, fmt.Sprintf("module %s requires %s, but module %s requires %s", parent.Config.Name, node.Dependency, selectedBy[sym], selected.Version))
+		}
+	})
+	if len(conflicts) > 0 {
+		return fmt.Errorf("conflicting dependency versions:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return nil
+}
+
+// key identifies the module of the node at its version.
+func (node *DependencyNode) key() string {
+	if node.Ref.Git != nil {
+		return node.Ref.Symbolic() + "@" + node.Ref.Version
+	}
+	return filepath.Join(node.Ref.Path, node.Ref.SubPath)
+}
+
+// walk calls fn with every node under this one, and its parent, depth-first.
+func (node *DependencyNode) walk(fn func(parent, node *DependencyNode)) {
+	for _, dep := range node.Dependencies {
+		fn(node, dep)
+		dep.walk(fn)
+	}
+}
+
+// SelectedPin returns the pin of the version selected for the module of the
+// node, or its own pin if it's pinned to that version or it's not a versioned
+// git module.
+func (graph *DependencyGraph) SelectedPin(node *DependencyNode) *DependencyPin {
+	if node.Ref.Git == nil || node.Pin == nil || node.Pin.Version == "" {
+		return node.Pin
+	}
+	if selected := graph.Selected[node.Ref.Symbolic()]; selected != nil && selected.Version != node.Pin.Version {
+		return selected
+	}
+	return node.Pin
+}
+
+// selectedVersions returns the pins of the versions selected for the modules
+// that the dependencies of the root depend on at lower versions, keyed by
+// their path, or nil if there's none.
+func (graph *DependencyGraph) selectedVersions() map[string]*DependencyPin {
+	var selected map[string]*DependencyPin
+	for _, dep := range graph.Root.Dependencies {
+		dep.walk(func(_, node *DependencyNode) {
+			pin := graph.SelectedPin(node)
+			if pin == node.Pin {
+				return
+			}
+			if selected == nil {
+				selected = map[string]*DependencyPin{}
+			}
+			pinCp := *pin
+			selected[node.Ref.Path] = &pinCp
+		})
+	}
+	return selected
+}
+
+// SelectVersions pins the versioned git dependencies of the module to the
+// versions selected for them across its dependency graph, which may be
+// higher than the versions they resolved to if other modules of the graph
+// require so, and records the versions selected for the modules its
+// dependencies depend on, so that they're loaded at those too. It returns the
+// resulting graph.
+func (cfg *Config) SelectVersions(ctx context.Context, dag *dagger.Client, ref *Ref) (*DependencyGraph, error) {
+	graph, err := ResolveDependencyGraph(ctx, dag, ref, cfg)
+	if err != nil {
+		return nil, err
+	}
+	var raised bool
+	for _, dep := range graph.Root.Dependencies {
+		pin := graph.SelectedPin(dep)
+		if pin == dep.Pin {
+			continue
+		}
+		pinCp := *pin
+		cfg.DependencyPins[dep.Dependency] = &pinCp
+		raised = true
+	}
+	if raised {
+		// the dependencies of the raised versions may differ
+		return cfg.SelectVersions(ctx, dag, ref)
+	}
+	cfg.SelectedVersions = graph.selectedVersions()
+	return graph, nil
+}
@@ -30,6 +30,7 @@ type GitRef struct {
 	HTMLURL  string // HTMLURL is the URL a user can use to browse the repo.
 	CloneURL string // CloneURL is the URL to clone.
 	Commit   string // Commit is the commit to check out.
+	Tag      string // Tag is the version tag the ref resolved to, if any.
 }
 
 func (ref *Ref) String() string {
@@ -139,8 +140,10 @@ func (ref *Ref) AsModule(ctx context.Context, c *dagger.Client) (*dagger.Module,
 		}
 
 		return c.Host().Directory(modRootDir, dagger.HostDirectoryOpts{
-			Include: cfg.Include,
-			Exclude: cfg.Exclude,
+			Include:      cfg.Include,
+			Exclude:      cfg.Exclude,
+			Gitignore:    cfg.Gitignore,
+			Dockerignore: cfg.Dockerignore,
 		}).AsModule(dagger.DirectoryAsModuleOpts{
 			SourceSubpath: subdirRelPath,
 		}), nil
@@ -160,6 +163,50 @@ func (ref *Ref) AsModule(ctx context.Context, c *dagger.Client) (*dagger.Module,
 	}
 }
 
+// SourceRoot returns the source root of a git module ref, the way the engine
+// loads it as a dependency, and the subpath of its config file in it.
+func (ref *Ref) SourceRoot(ctx context.Context, c *dagger.Client) (*dagger.Directory, string, error) {
+	if ref.Git == nil {
+		return nil, "", fmt.Errorf("cannot get source root of non-git module")
+	}
+	cfg, err := ref.Config(ctx, c)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get module config: %w", err)
+	}
+	configDir := filepath.Dir(NormalizeConfigPath(ref.SubPath))
+	rootPath := cfg.SourceRootPath(configDir)
+	subPath, err := filepath.Rel(rootPath, configDir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get module subpath: %w", err)
+	}
+	return c.Git(ref.Git.CloneURL).Commit(ref.Git.Commit).Tree().Directory(rootPath), subPath, nil
+}
+
+// Pin returns the pin of a git module ref: the commit it resolved to, and
+// the content digest of the module's source root at that commit, which is
+// what the engine verifies when loading it as a dependency.
+func (ref *Ref) Pin(ctx context.Context, c *dagger.Client) (*DependencyPin, error) {
+	if ref.Git == nil {
+		return nil, fmt.Errorf("cannot pin non-git module")
+	}
+	src, _, err := ref.SourceRoot(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	dgst, err := src.Digest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get module digest: %w", err)
+	}
+	pin := &DependencyPin{
+		Commit: ref.Git.Commit,
+		Digest: dgst,
+	}
+	if version, ok := TagVersion(ref.Git.Tag, ref.SubPath); ok {
+		pin.Version = version
+	}
+	return pin, nil
+}
+
 // TODO dedup with ResolveMovingRef
 func ResolveStableRef(modQuery string) (*Ref, error) {
 	modPath, modVersion, hasVersion := strings.Cut(modQuery, "@")
@@ -203,6 +250,9 @@ func ResolveStableRef(modQuery string) (*Ref, error) {
 	if !hasVersion {
 		return nil, fmt.Errorf("no version provided for %s", modPath)
 	}
+	if IsVersionConstraint(modVersion) {
+		return nil, fmt.Errorf("version constraint of %s is not resolved, run `dagger mod update` to pin it", modQuery)
+	}
 
 	ref.Version = modVersion    // assume commit
 	ref.Git.Commit = modVersion // assume commit
@@ -252,12 +302,35 @@ func ResolveMovingRef(ctx context.Context, dag *dagger.Client, modQuery string)
 
 	ref.Git.CloneURL = "https://" + segments[0] + "/" + segments[1] + "/" + segments[2]
 
-	if !hasVersion {
+	var subPath string
+	if len(segments) == 4 {
+		subPath = segments[3]
+	}
+
+	switch {
+	case !hasVersion:
 		var err error
 		modVersion, err = defaultBranch(ctx, dag, ref.Git.CloneURL)
 		if err != nil {
 			return nil, fmt.Errorf("determine default branch: %w", err)
 		}
+	case IsVersionConstraint(modVersion):
+		constraint, err := ParseVersionConstraint(modVersion)
+		if err != nil {
+			return nil, err
+		}
+		tags, err := dag.Git(ref.Git.CloneURL).Tags(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list git tags: %w", err)
+		}
+		tag, _, ok := constraint.Latest(tags, subPath)
+		if !ok {
+			return nil, fmt.Errorf("no version of %s satisfies %s", modPath, constraint)
+		}
+		modVersion = tag
+	}
+	if _, ok := TagVersion(modVersion, subPath); ok {
+		ref.Git.Tag = modVersion
 	}
 
 	gitCommit, err := dag.Git(ref.Git.CloneURL, dagger.GitOpts{KeepGitDir: true}).Commit(modVersion).Commit(ctx)
@@ -284,8 +357,24 @@ func ResolveModuleDependency(ctx context.Context, dag *dagger.Client, parent *Re
 		return nil, fmt.Errorf("failed to resolve module: %w", err)
 	}
 
+	return relativeToParent(parent, mod), nil
+}
+
+// resolvePinnedDependency resolves a dependency of the parent module without
+// resolving its version again, e.g. a dependency pinned to a commit.
+func resolvePinnedDependency(parent *Ref, urlStr string) (*Ref, error) {
+	mod, err := ResolveStableRef(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve module: %w", err)
+	}
+	return relativeToParent(parent, mod), nil
+}
+
+// relativeToParent returns the given dependency of the parent module with its
+// path relative to the parent, if it's local.
+func relativeToParent(parent, mod *Ref) *Ref {
 	if !mod.Local {
-		return mod, nil
+		return mod
 	}
 
 	// make local modules relative to the parent module
@@ -300,7 +389,7 @@ func ResolveModuleDependency(ctx context.Context, dag *dagger.Client, parent *Re
 		cp.Path = filepath.Join(cp.Path, mod.Path)
 	}
 
-	return &cp, nil
+	return &cp
 }
 
 func defaultBranch(ctx context.Context, dag *dagger.Client, repo string) (string, error) {
@@ -0,0 +1,156 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"dagger.io/dagger"
+)
+
+// VendorDirname is the name of the directory the git dependencies of a
+// module are vendored in, next to its config file.
+const VendorDirname = "dagger-vendor"
+
+// VendorManifestFilename is the name of the manifest of the vendored
+// dependencies, in the vendor directory.
+const VendorManifestFilename = "modules.json"
+
+// VendorManifest lists the git modules vendored in the vendor directory of a
+// module: its git dependencies and theirs, at the commits they're pinned to.
+type VendorManifest struct {
+	Modules []*VendoredModule `json:"modules"`
+}
+
+// VendoredModule is a git module vendored at a commit.
+type VendoredModule struct {
+	// The path of the module, e.g. github.com/foo/bar/baz.
+	Path string `json:"path"`
+
+	// The commit the module is vendored at.
+	Commit string `json:"commit"`
+
+	// The content digest of the module's source root at that commit.
+	Digest string `json:"digest"`
+
+	// The directory of the vendored copy of the module's source root,
+	// relative to the vendor directory.
+	Dir string `json:"dir"`
+
+	// The subpath of the module's config file in its source root.
+	SourceSubpath string `json:"sourceSubpath"`
+}
+
+// Lookup returns the vendored copy of the module at the given path and
+// commit, or nil if the module isn't vendored. It fails if the module is only
+// vendored at other commits, since the vendor directory is out of date.
+func (manifest *VendorManifest) Lookup(modPath, commit string) (*VendoredModule, error) {
+	if manifest == nil {
+		return nil, nil
+	}
+	var vendored bool
+	for _, mod := range manifest.Modules {
+		if mod.Path != modPath {
+			continue
+		}
+		if mod.Commit == commit {
+			return mod, nil
+		}
+		vendored = true
+	}
+	if vendored {
+		return nil, fmt.Errorf("module %s is not vendored at commit %s, run `dagger mod vendor` to update the vendor directory", modPath, commit)
+	}
+	return nil, nil
+}
+
+// LoadVendorManifest loads the manifest of the vendor directory of the local
+// module in the given directory, or returns nil if it has none.
+func LoadVendorManifest(moduleDir string) (*VendorManifest, error) {
+	manifestBytes, err := os.ReadFile(filepath.Join(moduleDir, VendorDirname, VendorManifestFilename))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read vendor manifest: %w", err)
+	}
+	var manifest VendorManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse vendor manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Vendor returns the vendor directory of the module of the given dependency
+// graph, holding a copy of the source root of each git module of the graph
+// at the commit it's pinned to, as loaded by Ref.AsModule, along with its
+// manifest.
+func Vendor(ctx context.Context, dag *dagger.Client, graph *DependencyGraph) (*dagger.Directory, error) {
+	vendorDir := dag.Directory()
+	manifest := &VendorManifest{}
+	seen := map[string]bool{}
+
+	var err error
+	graph.Root.walk(func(_, node *DependencyNode) {
+		if err != nil || node.Ref.Git == nil {
+			return
+		}
+		depRef, refErr := ResolveStableRef(node.Pin.PinnedRef(node.Dependency))
+		if refErr != nil || depRef.Local {
+			// local dependencies of git modules are part of their source
+			return
+		}
+		key := node.Ref.Path + "@" + node.Ref.Git.Commit
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		var mod *VendoredModule
+		var src *dagger.Directory
+		mod, src, err = vendorModule(ctx, dag, node)
+		if err != nil {
+			err = fmt.Errorf("failed to vendor module %s: %w", node.Dependency, err)
+			return
+		}
+		vendorDir = vendorDir.WithDirectory(mod.Dir, src)
+		manifest.Modules = append(manifest.Modules, mod)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(manifest.Modules, func(i, j int) bool {
+		return manifest.Modules[i].Dir < manifest.Modules[j].Dir
+	})
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal vendor manifest: %w", err)
+	}
+	return vendorDir.WithNewFile(VendorManifestFilename, string(manifestBytes)+"\n"), nil
+}
+
+func vendorModule(ctx context.Context, dag *dagger.Client, node *DependencyNode) (*VendoredModule, *dagger.Directory, error) {
+	src, subPath, err := node.Ref.SourceRoot(ctx, dag)
+	if err != nil {
+		return nil, nil, err
+	}
+	dgst, err := src.Digest(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get module digest: %w", err)
+	}
+	if node.Pin != nil && node.Pin.Digest != "" && node.Pin.Digest != dgst {
+		return nil, nil, fmt.Errorf("module at commit %s has digest %s, but %s is pinned", node.Ref.Git.Commit, dgst, node.Pin.Digest)
+	}
+	return &VendoredModule{
+		Path:          node.Ref.Path,
+		Commit:        node.Ref.Git.Commit,
+		Digest:        dgst,
+		Dir:           node.Ref.Path + "@" + node.Ref.Git.Commit,
+		SourceSubpath: subPath,
+	}, src, nil
+}
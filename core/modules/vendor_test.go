@@ -0,0 +1,61 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVendorManifestLookup(t *testing.T) {
+	manifest := &VendorManifest{
+		Modules: []*VendoredModule{
+			{Path: "github.com/foo/bar", Commit: "aaaa", Dir: "github.com/foo/bar@aaaa"},
+			{Path: "github.com/foo/bar", Commit: "bbbb", Dir: "github.com/foo/bar@bbbb"},
+			{Path: "github.com/foo/baz", Commit: "cccc", Dir: "github.com/foo/baz@cccc"},
+		},
+	}
+
+	mod, err := manifest.Lookup("github.com/foo/bar", "bbbb")
+	require.NoError(t, err)
+	require.Equal(t, "github.com/foo/bar@bbbb", mod.Dir)
+
+	mod, err = manifest.Lookup("github.com/foo/qux", "aaaa")
+	require.NoError(t, err)
+	require.Nil(t, mod)
+
+	_, err = manifest.Lookup("github.com/foo/baz", "dddd")
+	require.ErrorContains(t, err, "module github.com/foo/baz is not vendored at commit dddd")
+
+	var noManifest *VendorManifest
+	mod, err = noManifest.Lookup("github.com/foo/bar", "aaaa")
+	require.NoError(t, err)
+	require.Nil(t, mod)
+}
+
+func TestLoadVendorManifest(t *testing.T) {
+	moduleDir := t.TempDir()
+
+	manifest, err := LoadVendorManifest(moduleDir)
+	require.NoError(t, err)
+	require.Nil(t, manifest)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(moduleDir, VendorDirname), 0o755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(moduleDir, VendorDirname, VendorManifestFilename),
+		[]byte(`{"modules":[{"path":"github.com/foo/bar","commit":"aaaa","digest":"sha256:1234","dir":"github.com/foo/bar@aaaa","sourceSubpath":"."}]}`),
+		0o644,
+	))
+	manifest, err = LoadVendorManifest(moduleDir)
+	require.NoError(t, err)
+	require.Equal(t, &VendorManifest{
+		Modules: []*VendoredModule{{
+			Path:          "github.com/foo/bar",
+			Commit:        "aaaa",
+			Digest:        "sha256:1234",
+			Dir:           "github.com/foo/bar@aaaa",
+			SourceSubpath: ".",
+		}},
+	}, manifest)
+}
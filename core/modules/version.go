@@ -0,0 +1,174 @@
+package modules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// VersionConstraint is a semver constraint on the version of a dependency,
+// resolved against the tags of its repository, e.g. github.com/foo/bar@^1.2.
+//
+// The supported constraints are:
+//
+//	^1.2.3  >=1.2.3 <2.0.0 (or <0.3.0 for 0.2.3, and <0.0.4 for 0.0.3)
+//	~1.2.3  >=1.2.3 <1.3.0 (or <2.0.0 for ~1)
+//	>=1.2.3 any version from 1.2.3 up
+//
+// Minor and patch versions may be omitted, e.g. ^1.2 or ~1. Pre-release
+// versions never satisfy a constraint.
+type VersionConstraint struct {
+	raw string
+
+	// min is the minimum version allowed, and max the version above it that
+	// isn't allowed anymore, if any.
+	min string
+	max string
+}
+
+// IsVersionConstraint returns whether the version of a dependency is a
+// constraint, as opposed to a tag, a branch or a commit.
+func IsVersionConstraint(version string) bool {
+	return strings.HasPrefix(version, "^") ||
+		strings.HasPrefix(version, "~") ||
+		strings.HasPrefix(version, ">=")
+}
+
+// ParseVersionConstraint parses a version constraint.
+func ParseVersionConstraint(constraint string) (*VersionConstraint, error) {
+	var op string
+	for _, prefix := range []string{"^", "~", ">="} {
+		if strings.HasPrefix(constraint, prefix) {
+			op = prefix
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("invalid version constraint %q: must start with ^, ~ or >=", constraint)
+	}
+
+	rawVersion := strings.TrimSpace(strings.TrimPrefix(constraint, op))
+	version := canonicalVersion(rawVersion)
+	if version == "" || semver.Prerelease(version) != "" {
+		return nil, fmt.Errorf("invalid version constraint %q", constraint)
+	}
+
+	c := &VersionConstraint{
+		raw: constraint,
+		min: version,
+	}
+	var major, minor, patch int
+	fmt.Sscanf(version, "v%d.%d.%d", &major, &minor, &patch)
+	switch op {
+	case "^":
+		switch {
+		case major > 0:
+			c.max = fmt.Sprintf("v%d.0.0", major+1)
+		case minor > 0:
+			c.max = fmt.Sprintf("v0.%d.0", minor+1)
+		default:
+			c.max = fmt.Sprintf("v0.0.%d", patch+1)
+		}
+	case "~":
+		if strings.Count(rawVersion, ".") == 0 {
+			// ~1 allows any minor version, like ^1
+			c.max = fmt.Sprintf("v%d.0.0", major+1)
+		} else {
+			c.max = fmt.Sprintf("v%d.%d.0", major, minor+1)
+		}
+	}
+	return c, nil
+}
+
+func (c *VersionConstraint) String() string {
+	return c.raw
+}
+
+// Allows returns whether the given semver version satisfies the constraint.
+func (c *VersionConstraint) Allows(version string) bool {
+	version = canonicalVersion(version)
+	if version == "" || semver.Prerelease(version) != "" {
+		return false
+	}
+	if semver.Compare(version, c.min) < 0 {
+		return false
+	}
+	return c.max == "" || semver.Compare(version, c.max) < 0
+}
+
+// Latest returns the tag of the latest version satisfying the constraint
+// among the given tags of a repository, along with its version. Tags of
+// modules in a subdirectory of the repository may be prefixed with it, e.g.
+// foo/v1.2.3 for a module in foo, in which case they take precedence over
+// the tags of the whole repository.
+func (c *VersionConstraint) Latest(tags []string, subPath string) (string, string, bool) {
+	versions := map[string]string{}
+	for _, prefix := range tagPrefixes(subPath) {
+		for _, tag := range tags {
+			if !strings.HasPrefix(tag, prefix) {
+				continue
+			}
+			version := canonicalVersion(strings.TrimPrefix(tag, prefix))
+			if version == "" || !c.Allows(version) {
+				continue
+			}
+			if _, ok := versions[version]; !ok {
+				versions[version] = tag
+			}
+		}
+		if len(versions) > 0 {
+			break
+		}
+	}
+	if len(versions) == 0 {
+		return "", "", false
+	}
+
+	sorted := make([]string, 0, len(versions))
+	for version := range versions {
+		sorted = append(sorted, version)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return semver.Compare(sorted[i], sorted[j]) > 0
+	})
+	return versions[sorted[0]], sorted[0], true
+}
+
+// TagVersion returns the semver version of the given tag of a module in the
+// given subdirectory of its repository, if it's a version tag.
+func TagVersion(tag, subPath string) (string, bool) {
+	for _, prefix := range tagPrefixes(subPath) {
+		if !strings.HasPrefix(tag, prefix) {
+			continue
+		}
+		if version := canonicalVersion(strings.TrimPrefix(tag, prefix)); version != "" {
+			return version, true
+		}
+	}
+	return "", false
+}
+
+// tagPrefixes returns the prefixes of the version tags of a module in the
+// given subdirectory of its repository, by order of precedence.
+func tagPrefixes(subPath string) []string {
+	subPath = strings.Trim(subPath, "/")
+	if subPath == "" || subPath == "." {
+		return []string{""}
+	}
+	return []string{subPath + "/", ""}
+}
+
+// canonicalVersion returns the canonical form of the given semver version,
+// which may omit its leading v, or its minor and patch versions, or "" if
+// it's not a semver version.
+func canonicalVersion(version string) string {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if !semver.IsValid(version) {
+		return ""
+	}
+	return semver.Canonical(version)
+}
@@ -0,0 +1,108 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionConstraint(t *testing.T) {
+	for _, tc := range []struct {
+		constraint string
+		allowed    []string
+		denied     []string
+	}{
+		{
+			constraint: "^1.2",
+			allowed:    []string{"v1.2.0", "1.2.5", "v1.9.0"},
+			denied:     []string{"v1.1.9", "v2.0.0", "v1.3.0-rc.1", "main"},
+		},
+		{
+			constraint: "^0.2.3",
+			allowed:    []string{"v0.2.3", "v0.2.9"},
+			denied:     []string{"v0.2.2", "v0.3.0", "v1.0.0"},
+		},
+		{
+			constraint: "^0.0.3",
+			allowed:    []string{"v0.0.3"},
+			denied:     []string{"v0.0.4", "v0.1.0"},
+		},
+		{
+			constraint: "~1.2.3",
+			allowed:    []string{"v1.2.3", "v1.2.10"},
+			denied:     []string{"v1.2.2", "v1.3.0"},
+		},
+		{
+			constraint: "~1",
+			allowed:    []string{"v1.0.0", "v1.5.0"},
+			denied:     []string{"v0.9.0", "v2.0.0"},
+		},
+		{
+			constraint: ">=1.2",
+			allowed:    []string{"v1.2.0", "v2.0.0", "v10.1.0"},
+			denied:     []string{"v1.1.0"},
+		},
+	} {
+		c, err := ParseVersionConstraint(tc.constraint)
+		require.NoError(t, err)
+		require.Equal(t, tc.constraint, c.String())
+		for _, version := range tc.allowed {
+			require.True(t, c.Allows(version), "%s should allow %s", tc.constraint, version)
+		}
+		for _, version := range tc.denied {
+			require.False(t, c.Allows(version), "%s should deny %s", tc.constraint, version)
+		}
+	}
+
+	for _, constraint := range []string{"1.2", "^", "^main", "~1.2.0-rc.1"} {
+		_, err := ParseVersionConstraint(constraint)
+		require.Error(t, err, constraint)
+	}
+
+	require.True(t, IsVersionConstraint("^1.2"))
+	require.True(t, IsVersionConstraint(">=1"))
+	require.False(t, IsVersionConstraint("v1.2.0"))
+	require.False(t, IsVersionConstraint("main"))
+}
+
+func TestVersionConstraintLatest(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.2.0", "1.4.1", "v1.5.0-rc.1", "v2.0.0", "foo/v1.1.0", "foo/v1.3.0", "latest"}
+
+	c, err := ParseVersionConstraint("^1.1")
+	require.NoError(t, err)
+
+	tag, version, ok := c.Latest(tags, "")
+	require.True(t, ok)
+	require.Equal(t, "1.4.1", tag)
+	require.Equal(t, "v1.4.1", version)
+
+	// the tags of the subdirectory take precedence
+	tag, version, ok = c.Latest(tags, "foo")
+	require.True(t, ok)
+	require.Equal(t, "foo/v1.3.0", tag)
+	require.Equal(t, "v1.3.0", version)
+
+	tag, _, ok = c.Latest(tags, "bar")
+	require.True(t, ok)
+	require.Equal(t, "1.4.1", tag)
+
+	c, err = ParseVersionConstraint("^3")
+	require.NoError(t, err)
+	_, _, ok = c.Latest(tags, "")
+	require.False(t, ok)
+}
+
+func TestTagVersion(t *testing.T) {
+	version, ok := TagVersion("v1.2", "")
+	require.True(t, ok)
+	require.Equal(t, "v1.2.0", version)
+
+	version, ok = TagVersion("foo/v1.2.3", "foo")
+	require.True(t, ok)
+	require.Equal(t, "v1.2.3", version)
+
+	_, ok = TagVersion("main", "")
+	require.False(t, ok)
+	_, ok = TagVersion("foo/v1.2.3", "")
+	require.False(t, ok)
+}
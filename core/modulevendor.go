@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/dagger/dagger/core/modules"
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+// ModuleVendor is the vendor directory of a module, holding copies of its
+// git dependencies and of theirs, which are loaded instead of fetching them.
+// The dependencies of a module share its vendor directory.
+type ModuleVendor struct {
+	Directory *Directory              `json:"directory"`
+	Manifest  *modules.VendorManifest `json:"manifest"`
+}
+
+// LoadModuleVendor loads the vendor directory next to the config file of the
+// module at the given subpath of the source directory, if there's one.
+func LoadModuleVendor(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	sourceDir *Directory,
+	sourceSubpath string,
+) (*ModuleVendor, error) {
+	entries, err := sourceDir.Entries(ctx, bk, svcs, sourceSubpath, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module directory: %w", err)
+	}
+	if !slices.Contains(entries, modules.VendorDirname) {
+		return nil, nil
+	}
+
+	vendorDir, err := sourceDir.Directory(ctx, bk, svcs, filepath.Join(sourceSubpath, modules.VendorDirname))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vendor directory: %w", err)
+	}
+	manifestFile, err := vendorDir.File(ctx, bk, svcs, modules.VendorManifestFilename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vendor manifest: %w", err)
+	}
+	manifestBytes, err := manifestFile.Contents(ctx, bk, svcs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vendor manifest: %w", err)
+	}
+	var manifest modules.VendorManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse vendor manifest: %w", err)
+	}
+	return &ModuleVendor{
+		Directory: vendorDir,
+		Manifest:  &manifest,
+	}, nil
+}
+
+// Source returns the vendored source root of the git module at the given
+// path and commit, along with the path of its config file in it, or nil if
+// the module isn't vendored. The vendored copy is verified against the digest
+// it was vendored with.
+func (vendor *ModuleVendor) Source(
+	ctx context.Context,
+	bk *buildkit.Client,
+	svcs *Services,
+	modPath string,
+	commit string,
+) (*Directory, string, error) {
+	if vendor == nil {
+		return nil, "", nil
+	}
+	vendored, err := vendor.Manifest.Lookup(modPath, commit)
+	if err != nil || vendored == nil {
+		return nil, "", err
+	}
+	sourceDir, err := vendor.Directory.Directory(ctx, bk, svcs, vendored.Dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get vendored module %s: %w", modPath, err)
+	}
+	dgst, err := sourceDir.ContentDigest(ctx, bk, svcs)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get digest of vendored module %s: %w", modPath, err)
+	}
+	if dgst.String() != vendored.Digest {
+		return nil, "", fmt.Errorf("vendored module %s has digest %s, but was vendored with %s; run `dagger mod vendor` to restore it", modPath, dgst, vendored.Digest)
+	}
+	return sourceDir, modules.NormalizeConfigPath(vendored.SourceSubpath), nil
+}
@@ -0,0 +1,341 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// patchFile is the change a unified diff makes to a single file.
+type patchFile struct {
+	// OldPath and NewPath are the paths of the file before and after the
+	// change, as they appear in the diff. OldPath is empty for new files and
+	// NewPath is empty for deleted files.
+	OldPath string
+	NewPath string
+
+	// NewMode is the mode the file is given, if any.
+	NewMode fs.FileMode
+
+	Hunks []*patchHunk
+
+	// sawOld and sawNew record whether the ---/+++ lines were seen yet.
+	sawOld bool
+	sawNew bool
+}
+
+// patchHunk is a single @@ section of a unified diff.
+type patchHunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+
+	// Lines are the lines of the hunk, each starting with ' ', '-' or '+',
+	// without their trailing newline.
+	Lines []string
+
+	// OldNoEOL and NewNoEOL are set when the last line of the old or new side
+	// isn't followed by a newline.
+	OldNoEOL bool
+	NewNoEOL bool
+}
+
+// parsePatch parses a unified diff, as produced by `diff -u` or `git diff`.
+func parsePatch(patch string) ([]*patchFile, error) {
+	var files []*patchFile
+	var cur *patchFile
+	var hunk *patchHunk
+	var oldLeft, newLeft int
+	lastSide := byte(0)
+
+	lines := strings.Split(strings.TrimSuffix(patch, "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\r")
+
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			raw := lines[i]
+			if line == "" {
+				// some tools trim the trailing space of empty context lines
+				line = " "
+				raw = " " + raw
+			}
+			switch line[0] {
+			case ' ':
+				oldLeft--
+				newLeft--
+			case '-':
+				oldLeft--
+			case '+':
+				newLeft--
+			case '\\':
+				// "\ No newline at end of file" refers to the previous line
+				switch lastSide {
+				case '-':
+					hunk.OldNoEOL = true
+				case '+':
+					hunk.NewNoEOL = true
+				default:
+					hunk.OldNoEOL = true
+					hunk.NewNoEOL = true
+				}
+				continue
+			default:
+				return nil, fmt.Errorf("line %d: unexpected line in hunk: %q", i+1, line)
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, fmt.Errorf("line %d: hunk is longer than its header says", i+1)
+			}
+			lastSide = line[0]
+			hunk.Lines = append(hunk.Lines, raw)
+			continue
+		}
+
+		if hunk != nil && strings.HasPrefix(line, `\`) {
+			// "\ No newline at end of file" after the last line of a hunk
+			switch lastSide {
+			case '-':
+				hunk.OldNoEOL = true
+			case '+':
+				hunk.NewNoEOL = true
+			default:
+				hunk.OldNoEOL = true
+				hunk.NewNoEOL = true
+			}
+			continue
+		}
+		hunk = nil
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			oldPath, newPath, _ := strings.Cut(strings.TrimPrefix(line, "diff --git "), " ")
+			cur = &patchFile{OldPath: oldPath, NewPath: newPath}
+			files = append(files, cur)
+		case strings.HasPrefix(line, "--- "):
+			if cur == nil || cur.sawOld || len(cur.Hunks) > 0 {
+				cur = &patchFile{}
+				files = append(files, cur)
+			}
+			cur.sawOld = true
+			cur.OldPath = patchHeaderPath(strings.TrimPrefix(line, "--- "))
+		case strings.HasPrefix(line, "+++ "):
+			if cur == nil || !cur.sawOld {
+				return nil, fmt.Errorf("line %d: +++ line without a preceding --- line", i+1)
+			}
+			cur.sawNew = true
+			cur.NewPath = patchHeaderPath(strings.TrimPrefix(line, "+++ "))
+		case strings.HasPrefix(line, "new file mode "):
+			if cur != nil {
+				cur.OldPath = ""
+				cur.NewMode = parsePatchMode(strings.TrimPrefix(line, "new file mode "))
+			}
+		case strings.HasPrefix(line, "new mode "):
+			if cur != nil {
+				cur.NewMode = parsePatchMode(strings.TrimPrefix(line, "new mode "))
+			}
+		case strings.HasPrefix(line, "deleted file mode "):
+			if cur != nil {
+				cur.NewPath = ""
+			}
+		case strings.HasPrefix(line, "GIT binary patch"),
+			strings.HasPrefix(line, "Binary files "):
+			return nil, fmt.Errorf("line %d: binary patches are not supported", i+1)
+		case strings.HasPrefix(line, "@@ "):
+			if cur == nil || !cur.sawNew {
+				return nil, fmt.Errorf("line %d: hunk without a preceding file header", i+1)
+			}
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			hunk = h
+			oldLeft, newLeft = h.OldLines, h.NewLines
+			lastSide = 0
+			cur.Hunks = append(cur.Hunks, h)
+		}
+	}
+	if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+		return nil, fmt.Errorf("patch ends in the middle of a hunk")
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no changes found in patch")
+	}
+	return files, nil
+}
+
+// patchTimeFormat is the format of the timestamps of the ---/+++ lines of
+// diff -u, with optional fractional seconds.
+const patchTimeFormat = "2006-01-02 15:04:05.999999999 -0700"
+
+// patchHeaderPath returns the path of a ---/+++ line, or an empty string for
+// /dev/null.
+func patchHeaderPath(header string) string {
+	// diff -u appends the modification time after a tab
+	p, timestamp, _ := strings.Cut(header, "\t")
+	p = strings.TrimSpace(p)
+	if p == "/dev/null" {
+		return ""
+	}
+	// diff -N marks missing files with the Unix epoch instead of /dev/null
+	if t, err := time.Parse(patchTimeFormat, strings.TrimSpace(timestamp)); err == nil && t.Unix() == 0 {
+		return ""
+	}
+	if unquoted, err := strconv.Unquote(p); err == nil {
+		return unquoted
+	}
+	return p
+}
+
+func parsePatchMode(mode string) fs.FileMode {
+	m, err := strconv.ParseUint(strings.TrimSpace(mode), 8, 32)
+	if err != nil {
+		return 0
+	}
+	return fs.FileMode(m).Perm()
+}
+
+func parseHunkHeader(line string) (*patchHunk, error) {
+	// @@ -oldStart[,oldLines] +newStart[,newLines] @@ [section]
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" {
+		return nil, fmt.Errorf("invalid hunk header: %q", line)
+	}
+	h := &patchHunk{}
+	var err error
+	h.OldStart, h.OldLines, err = parseHunkRange(fields[1], "-")
+	if err != nil {
+		return nil, fmt.Errorf("invalid hunk header %q: %w", line, err)
+	}
+	h.NewStart, h.NewLines, err = parseHunkRange(fields[2], "+")
+	if err != nil {
+		return nil, fmt.Errorf("invalid hunk header %q: %w", line, err)
+	}
+	return h, nil
+}
+
+func parseHunkRange(r, prefix string) (int, int, error) {
+	if !strings.HasPrefix(r, prefix) {
+		return 0, 0, fmt.Errorf("range %q should start with %q", r, prefix)
+	}
+	start, count, found := strings.Cut(strings.TrimPrefix(r, prefix), ",")
+	s, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	n := 1
+	if found {
+		n, err = strconv.Atoi(count)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return s, n, nil
+}
+
+// stripPatchPath removes the given number of leading components from a path
+// of a patch, like `patch -p`, and returns it relative to the patched
+// directory.
+func stripPatchPath(p string, strip int) (string, error) {
+	if p == "" {
+		return "", nil
+	}
+	components := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	if strip >= len(components) {
+		return "", fmt.Errorf("cannot strip %d leading components from %q", strip, p)
+	}
+	return path.Join("/", strings.Join(components[strip:], "/")), nil
+}
+
+// apply applies the hunks of the patch to the given contents and returns the
+// result, along with a report of every hunk that doesn't apply.
+func (fp *patchFile) apply(contents string) (string, []string) {
+	var lines []string
+	eofNewline := true
+	if contents != "" {
+		eofNewline = strings.HasSuffix(contents, "\n")
+		lines = strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+	}
+
+	var rejects []string
+	// offset is how much the hunks applied so far moved the following lines
+	offset := 0
+	// minPos is where the previous hunk ended, which the next one can't
+	// overlap
+	minPos := 0
+	for i, h := range fp.Hunks {
+		var oldLines, newLines []string
+		for _, l := range h.Lines {
+			switch l[0] {
+			case ' ':
+				oldLines = append(oldLines, l[1:])
+				newLines = append(newLines, l[1:])
+			case '-':
+				oldLines = append(oldLines, l[1:])
+			case '+':
+				newLines = append(newLines, l[1:])
+			}
+		}
+
+		expected := h.OldStart - 1 + offset
+		if h.OldLines == 0 {
+			// pure insertions are positioned after the given line
+			expected = h.OldStart + offset
+		}
+		pos := findHunk(lines, oldLines, expected, minPos)
+		if pos < 0 {
+			rejects = append(rejects, fmt.Sprintf(
+				"hunk #%d (@@ -%d,%d +%d,%d @@) does not apply:\n%s",
+				i+1, h.OldStart, h.OldLines, h.NewStart, h.NewLines, strings.Join(h.Lines, "\n"),
+			))
+			continue
+		}
+
+		atEOF := pos+len(oldLines) == len(lines)
+		patched := make([]string, 0, len(lines)-len(oldLines)+len(newLines))
+		patched = append(patched, lines[:pos]...)
+		patched = append(patched, newLines...)
+		patched = append(patched, lines[pos+len(oldLines):]...)
+		lines = patched
+		if atEOF {
+			eofNewline = !h.NewNoEOL
+		}
+
+		offset += len(newLines) - len(oldLines)
+		minPos = pos + len(newLines)
+	}
+
+	if len(lines) == 0 {
+		return "", rejects
+	}
+	result := strings.Join(lines, "\n")
+	if eofNewline {
+		result += "\n"
+	}
+	return result, rejects
+}
+
+// findHunk returns the position of the given lines in the file closest to
+// the expected one, not before minPos, or -1 if they can't be found.
+func findHunk(lines, hunkLines []string, expected, minPos int) int {
+	matches := func(pos int) bool {
+		if pos < minPos || pos+len(hunkLines) > len(lines) {
+			return false
+		}
+		for i, l := range hunkLines {
+			if lines[pos+i] != l {
+				return false
+			}
+		}
+		return true
+	}
+	for delta := 0; expected-delta >= minPos || expected+delta <= len(lines); delta++ {
+		if matches(expected - delta) {
+			return expected - delta
+		}
+		if delta > 0 && matches(expected+delta) {
+			return expected + delta
+		}
+	}
+	return -1
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const gitPatch = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,5 +1,5 @@
+ package main
+
+ func main() {
+-	println("hello")
++	println("hello, world")
+ }
+diff --git a/new.sh b/new.sh
+new file mode 100755
+index 0000000..3333333
+--- /dev/null
++++ b/new.sh
+@@ -0,0 +1,2 @@
++#!/bin/sh
++echo hi
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 4444444..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+
+func TestParsePatch(t *testing.T) {
+	t.Run("git diff", func(t *testing.T) {
+		files, err := parsePatch(gitPatch)
+		require.NoError(t, err)
+		require.Len(t, files, 3)
+
+		require.Equal(t, "a/main.go", files[0].OldPath)
+		require.Equal(t, "b/main.go", files[0].NewPath)
+		require.Len(t, files[0].Hunks, 1)
+		require.Len(t, files[0].Hunks[0].Lines, 6)
+
+		require.Equal(t, "", files[1].OldPath)
+		require.Equal(t, "b/new.sh", files[1].NewPath)
+		require.Equal(t, fs.FileMode(0o755), files[1].NewMode)
+
+		require.Equal(t, "a/old.txt", files[2].OldPath)
+		require.Equal(t, "", files[2].NewPath)
+	})
+
+	t.Run("diff -u", func(t *testing.T) {
+		files, err := parsePatch("--- a.txt\t2023-01-01 00:00:00.000000000 +0000\n" +
+			"+++ a.txt\t2023-01-02 00:00:00.000000000 +0000\n" +
+			"@@ -1 +1 @@\n" +
+			"-one\n" +
+			"\\ No newline at end of file\n" +
+			"+two\n")
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		require.Equal(t, "a.txt", files[0].OldPath)
+		require.Equal(t, "a.txt", files[0].NewPath)
+		require.True(t, files[0].Hunks[0].OldNoEOL)
+		require.False(t, files[0].Hunks[0].NewNoEOL)
+	})
+
+	t.Run("diff -N", func(t *testing.T) {
+		files, err := parsePatch("--- a/new.txt\t1970-01-01 00:00:00 +0000\n" +
+			"+++ b/new.txt\t2023-01-02 00:00:00 +0000\n" +
+			"@@ -0,0 +1 @@\n" +
+			"+new\n" +
+			"--- a/old.txt\t2023-01-02 00:00:00 +0000\n" +
+			"+++ b/old.txt\t1969-12-31 19:00:00.000000000 -0500\n" +
+			"@@ -1 +0,0 @@\n" +
+			"-old\n")
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		require.Equal(t, "", files[0].OldPath)
+		require.Equal(t, "b/new.txt", files[0].NewPath)
+		require.Equal(t, "a/old.txt", files[1].OldPath)
+		require.Equal(t, "", files[1].NewPath)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := parsePatch("not a patch\n")
+		require.ErrorContains(t, err, "no changes found")
+
+		_, err = parsePatch("--- a\n+++ b\n@@ -1,2 +1,2 @@\n one\n")
+		require.ErrorContains(t, err, "middle of a hunk")
+
+		_, err = parsePatch("diff --git a/bin b/bin\nBinary files a/bin and b/bin differ\n")
+		require.ErrorContains(t, err, "binary patches are not supported")
+	})
+}
+
+func TestStripPatchPath(t *testing.T) {
+	p, err := stripPatchPath("a/dir/file.txt", 1)
+	require.NoError(t, err)
+	require.Equal(t, "/dir/file.txt", p)
+
+	p, err = stripPatchPath("dir/file.txt", 0)
+	require.NoError(t, err)
+	require.Equal(t, "/dir/file.txt", p)
+
+	p, err = stripPatchPath("a/../../escape.txt", 1)
+	require.NoError(t, err)
+	require.Equal(t, "/escape.txt", p)
+
+	_, err = stripPatchPath("file.txt", 1)
+	require.ErrorContains(t, err, "cannot strip 1 leading components")
+}
+
+func TestPatchApply(t *testing.T) {
+	files, err := parsePatch(gitPatch)
+	require.NoError(t, err)
+
+	t.Run("modify", func(t *testing.T) {
+		patched, rejects := files[0].apply("package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n")
+		require.Empty(t, rejects)
+		require.Equal(t, "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n", patched)
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		patched, rejects := files[0].apply("// header\n\npackage main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n")
+		require.Empty(t, rejects)
+		require.Equal(t, "// header\n\npackage main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n", patched)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		patched, rejects := files[1].apply("")
+		require.Empty(t, rejects)
+		require.Equal(t, "#!/bin/sh\necho hi\n", patched)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		patched, rejects := files[2].apply("bye\n")
+		require.Empty(t, rejects)
+		require.Equal(t, "", patched)
+	})
+
+	t.Run("missing newline", func(t *testing.T) {
+		files, err := parsePatch("--- a.txt\n+++ a.txt\n@@ -1 +1 @@\n-one\n\\ No newline at end of file\n+two\n")
+		require.NoError(t, err)
+		patched, rejects := files[0].apply("one")
+		require.Empty(t, rejects)
+		require.Equal(t, "two\n", patched)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		_, rejects := files[0].apply("package main\n\nfunc main() {\n\tprintln(\"bonjour\")\n}\n")
+		require.Len(t, rejects, 1)
+		require.Contains(t, rejects[0], "hunk #1 (@@ -1,5 +1,5 @@) does not apply")
+		require.Contains(t, rejects[0], "-\tprintln(\"hello\")")
+	})
+}
+
+func TestReplaceContents(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		search   string
+		replace  string
+		all      bool
+		regex    bool
+		expected string
+	}{
+		{name: "first", search: "1.0", replace: "2.0", expected: "v2.0 v1.0"},
+		{name: "all", search: "1.0", replace: "2.0", all: true, expected: "v2.0 v2.0"},
+		{name: "regex", search: `v(\d)\.0`, replace: "version $1", regex: true, expected: "version 1 v1.0"},
+		{name: "regex all", search: `v(\d)\.0`, replace: "version ${1}!", regex: true, all: true, expected: "version 1! version 1!"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			replaced, err := replaceContents([]byte("v1.0 v1.0"), tc.search, tc.replace, tc.all, tc.regex)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(replaced))
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := replaceContents([]byte("v1.0"), "2.0", "3.0", false, false)
+		require.ErrorContains(t, err, `search "2.0" not found`)
+
+		_, err = replaceContents([]byte("v1.0"), `\d{3}`, "x", false, true)
+		require.ErrorContains(t, err, "did not match")
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		_, err := replaceContents([]byte("v1.0"), "(", "x", false, true)
+		require.ErrorContains(t, err, "invalid regex")
+	})
+}
@@ -54,12 +54,16 @@ func OpenDef(ctx context.Context, bk *buildkit.Client, def *pb.Definition) (fs.F
 }
 
 func (fs *FS) Open(name string) (fs.File, error) {
-	stat, err := fs.ref.StatFile(fs.ctx, bkgw.StatRequest{Path: name})
+	return OpenFile(fs.ctx, fs.ref, name)
+}
+
+func OpenFile(ctx context.Context, ref bkgw.Reference, name string) (*File, error) {
+	stat, err := ref.StatFile(ctx, bkgw.StatRequest{Path: name})
 	if err != nil {
 		return nil, err
 	}
 
-	return &File{ctx: fs.ctx, ref: fs.ref, stat: stat, name: name}, nil
+	return &File{ctx: ctx, ref: ref, stat: stat, name: name}, nil
 }
 
 type File struct {
@@ -68,8 +72,14 @@ type File struct {
 	name   string
 	stat   *fstypes.Stat
 	offset int64
+
+	// chunk is the rest of the last chunk read, ahead of offset. Reading
+	// whole chunks avoids a round trip for every small read.
+	chunk []byte
 }
 
+var _ io.ReadSeeker = (*File)(nil)
+
 func (f *File) Stat() (fs.FileInfo, error) {
 	return &refFileInfo{stat: f.stat}, nil
 }
@@ -79,21 +89,53 @@ func (f *File) Read(p []byte) (int, error) {
 		return 0, io.EOF
 	}
 
-	content, err := f.ref.ReadFile(f.ctx, bkgw.ReadRequest{
-		Filename: f.name,
-		Range: &bkgw.FileRange{
-			Offset: int(f.offset),
-			Length: len(p),
-		},
-	})
-	if err != nil {
-		return 0, err
+	if len(f.chunk) == 0 {
+		length := f.stat.Size_ - f.offset
+		if length > buildkit.MaxFileContentsChunkSize {
+			length = buildkit.MaxFileContentsChunkSize
+		}
+		content, err := f.ref.ReadFile(f.ctx, bkgw.ReadRequest{
+			Filename: f.name,
+			Range: &bkgw.FileRange{
+				Offset: int(f.offset),
+				Length: int(length),
+			},
+		})
+		if err != nil {
+			return 0, err
+		}
+		if len(content) == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		f.chunk = content
 	}
-	n := copy(p, content)
+
+	n := copy(p, f.chunk)
+	f.chunk = f.chunk[n:]
 	f.offset += int64(n)
 	return n, nil
 }
 
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.stat.Size_
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position %d", offset)
+	}
+	if offset != f.offset {
+		f.chunk = nil
+		f.offset = offset
+	}
+	return offset, nil
+}
+
 func (f *File) Close() error {
 	return nil
 }
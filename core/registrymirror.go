@@ -0,0 +1,10 @@
+package core
+
+// RegistryMirror is a mirror to pull container images from instead of the
+// registry they're addressed to.
+type RegistryMirror struct {
+	Registry string `json:"registry"`
+	Mirror   string `json:"mirror"`
+	Insecure bool   `json:"insecure"`
+	Engine   bool   `json:"engine"`
+}
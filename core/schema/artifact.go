@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"context"
+
+	"github.com/dagger/dagger/core"
+)
+
+var _ SchemaResolvers = &artifactSchema{}
+
+type artifactSchema struct {
+	*APIServer
+
+	svcs *core.Services
+}
+
+func (s *artifactSchema) Name() string {
+	return "artifact"
+}
+
+func (s *artifactSchema) Schema() string {
+	return Artifact
+}
+
+func (s *artifactSchema) Resolvers() Resolvers {
+	rs := Resolvers{
+		"Query": ObjectResolver{
+			"ociArtifact": ToResolver(s.ociArtifact),
+		},
+		"Directory": ObjectResolver{
+			"publishArtifact": ToResolver(s.publishArtifact),
+		},
+	}
+
+	ResolveIDable[core.OCIArtifact](rs, "OCIArtifact", ObjectResolver{
+		"address": ToResolver(s.address),
+		"files":   ToResolver(s.files),
+	})
+
+	return rs
+}
+
+type ociArtifactArgs struct {
+	Address string
+}
+
+func (s *artifactSchema) ociArtifact(ctx context.Context, parent *core.Query, args ociArtifactArgs) (*core.OCIArtifact, error) {
+	return core.NewOCIArtifact(args.Address, parent.PipelinePath(), s.platform)
+}
+
+func (s *artifactSchema) address(ctx context.Context, parent *core.OCIArtifact, args any) (string, error) {
+	return parent.Address, nil
+}
+
+type artifactFilesArgs struct {
+	MediaTypes []string
+}
+
+func (s *artifactSchema) files(ctx context.Context, parent *core.OCIArtifact, args artifactFilesArgs) (*core.Directory, error) {
+	return parent.Files(ctx, s.bk, args.MediaTypes)
+}
+
+type publishArtifactArgs struct {
+	Address      string
+	ArtifactType string
+	MediaType    string
+	Annotations  []core.ArtifactAnnotation
+}
+
+func (s *artifactSchema) publishArtifact(ctx context.Context, parent *core.Directory, args publishArtifactArgs) (string, error) {
+	return parent.PublishArtifact(ctx, s.bk, s.svcs, args.Address, args.ArtifactType, args.MediaType, args.Annotations)
+}
@@ -86,6 +86,7 @@ func (s *containerSchema) Resolvers() Resolvers {
 		"withFile":                ToResolver(s.withFile),
 		"withNewFile":             ToResolver(s.withNewFile),
 		"withDirectory":           ToResolver(s.withDirectory),
+		"withDirectories":         ToResolver(s.withDirectories),
 		"withExec":                ToResolver(s.withExec),
 		"stdout":                  ToResolver(s.stdout),
 		"stderr":                  ToResolver(s.stderr),
@@ -94,6 +95,8 @@ func (s *containerSchema) Resolvers() Resolvers {
 		"export":                  ToResolver(s.export),
 		"asTarball":               ToResolver(s.asTarball),
 		"import":                  ToResolver(s.import_),
+		"exportToDocker":          ToResolver(s.exportToDocker),
+		"fromDocker":              ToResolver(s.fromDocker),
 		"withRegistryAuth":        ToResolver(s.withRegistryAuth),
 		"withoutRegistryAuth":     ToResolver(s.withoutRegistryAuth),
 		"imageRef":                ToResolver(s.imageRef),
@@ -106,6 +109,9 @@ func (s *containerSchema) Resolvers() Resolvers {
 		"shellEndpoint":           ToResolver(s.shellEndpoint),
 		"experimentalWithGPU":     ToResolver(s.withGPU),
 		"experimentalWithAllGPUs": ToResolver(s.withAllGPUs),
+		"layers":                  ToResolver(s.layers),
+		"withSquashedLayers":      ToResolver(s.withSquashedLayers),
+		"withLayerBoundary":       ToResolver(s.withLayerBoundary),
 	})
 
 	return rs
@@ -141,10 +147,25 @@ func (s *containerSchema) sync(ctx context.Context, parent *core.Container, _ an
 
 type containerFromArgs struct {
 	Address string
+	Verify  *core.ImageVerification
 }
 
 func (s *containerSchema) from(ctx context.Context, parent *core.Container, args containerFromArgs) (*core.Container, error) {
-	return parent.From(ctx, s.bk, args.Address)
+	addr, err := s.lockfile.PinImage(ctx, args.Address, s.resolveImageDigest)
+	if err != nil {
+		return nil, err
+	}
+	if args.Verify != nil {
+		addr, err = core.VerifyImage(ctx, s.bk, addr, *args.Verify)
+		if err != nil {
+			return nil, fmt.Errorf("verify image: %w", err)
+		}
+	}
+
+	if s.bk.HasRegistryMirror(addr) {
+		return parent.FromRegistryMirror(ctx, s.bk, s.ociStore, s.leaseManager, addr)
+	}
+	return parent.From(ctx, s.bk, addr)
 }
 
 type containerBuildArgs struct {
@@ -452,10 +473,26 @@ type containerPublishArgs struct {
 	PlatformVariants  []core.ContainerID
 	ForcedCompression core.ImageLayerCompression
 	MediaTypes        core.ImageMediaTypes
+	SignWith          core.SecretID
 }
 
 func (s *containerSchema) publish(ctx context.Context, parent *core.Container, args containerPublishArgs) (string, error) {
-	return parent.Publish(ctx, s.bk, s.svcs, args.Address, args.PlatformVariants, args.ForcedCompression, args.MediaTypes)
+	ref, err := parent.Publish(ctx, s.bk, s.svcs, args.Address, args.PlatformVariants, args.ForcedCompression, args.MediaTypes)
+	if err != nil {
+		return "", err
+	}
+
+	if args.SignWith != "" {
+		key, err := s.secrets.GetSecret(ctx, args.SignWith.String())
+		if err != nil {
+			return "", err
+		}
+		if err := core.SignImage(ctx, s.bk, ref, key); err != nil {
+			return "", fmt.Errorf("sign image: %w", err)
+		}
+	}
+
+	return ref, nil
 }
 
 type containerWithMountedFileArgs struct {
@@ -613,6 +650,21 @@ func (s *containerSchema) withDirectory(ctx context.Context, parent *core.Contai
 	return parent.WithDirectory(ctx, s.bk, args.Path, dir, args.CopyFilter, args.Owner)
 }
 
+type containerWithDirectoriesArgs struct {
+	Path        string
+	Directories []core.DirectoryID
+	Conflict    core.MergeConflict
+	Owner       string
+}
+
+func (s *containerSchema) withDirectories(ctx context.Context, parent *core.Container, args containerWithDirectoriesArgs) (*core.Container, error) {
+	dirs, err := decodeDirectories(args.Directories)
+	if err != nil {
+		return nil, err
+	}
+	return parent.WithDirectories(ctx, s.bk, s.svcs, args.Path, dirs, args.Conflict, args.Owner)
+}
+
 type containerWithFileArgs struct {
 	withFileArgs
 	Owner string
@@ -705,6 +757,35 @@ func (s *containerSchema) import_(ctx context.Context, parent *core.Container, a
 	)
 }
 
+type containerExportToDockerArgs struct {
+	Tag    string
+	Socket socket.ID
+}
+
+func (s *containerSchema) exportToDocker(ctx context.Context, parent *core.Container, args containerExportToDockerArgs) (bool, error) {
+	if err := parent.ExportToDocker(ctx, s.bk, s.svcs, args.Tag, args.Socket); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+type containerFromDockerArgs struct {
+	Tag    string
+	Socket socket.ID
+}
+
+func (s *containerSchema) fromDocker(ctx context.Context, parent *core.Container, args containerFromDockerArgs) (*core.Container, error) {
+	return parent.FromDocker(
+		ctx,
+		args.Tag,
+		args.Socket,
+		s.bk,
+		s.ociStore,
+		s.leaseManager,
+	)
+}
+
 type containerWithRegistryAuthArgs struct {
 	Address  string        `json:"address"`
 	Username string        `json:"username"`
@@ -844,3 +925,27 @@ func (s *containerSchema) shellEndpoint(ctx context.Context, parent *core.Contai
 	}
 	return "ws://dagger/" + endpoint, nil
 }
+
+func (s *containerSchema) layers(ctx context.Context, parent *core.Container, args any) ([]core.ContainerLayer, error) {
+	return parent.Layers(ctx, s.bk, s.svcs)
+}
+
+type containerWithSquashedLayersArgs struct {
+	From core.ContainerID
+}
+
+func (s *containerSchema) withSquashedLayers(ctx context.Context, parent *core.Container, args containerWithSquashedLayersArgs) (*core.Container, error) {
+	var base *core.Container
+	if args.From != "" {
+		var err error
+		base, err = args.From.Decode()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parent.WithSquashedLayers(ctx, base)
+}
+
+func (s *containerSchema) withLayerBoundary(ctx context.Context, parent *core.Container, args any) (*core.Container, error) {
+	return parent.WithLayerBoundary(ctx)
+}
@@ -2,7 +2,9 @@ package schema
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
+	"time"
 
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 
@@ -31,7 +33,8 @@ func (s *directorySchema) Schema() string {
 func (s *directorySchema) Resolvers() Resolvers {
 	rs := Resolvers{
 		"Query": ObjectResolver{
-			"directory": ToResolver(s.directory),
+			"directory":        ToResolver(s.directory),
+			"mergeDirectories": ToResolver(s.mergeDirectories),
 		},
 	}
 
@@ -39,7 +42,10 @@ func (s *directorySchema) Resolvers() Resolvers {
 		"sync":             ToResolver(s.sync),
 		"pipeline":         ToResolver(s.pipeline),
 		"entries":          ToResolver(s.entries),
+		"entryInfos":       ToResolver(s.entryInfos),
+		"digest":           ToResolver(s.digest),
 		"glob":             ToResolver(s.glob),
+		"search":           ToResolver(s.search),
 		"file":             ToResolver(s.file),
 		"withFile":         ToResolver(s.withFile),
 		"withNewFile":      ToResolver(s.withNewFile),
@@ -47,10 +53,17 @@ func (s *directorySchema) Resolvers() Resolvers {
 		"directory":        ToResolver(s.subdirectory),
 		"withDirectory":    ToResolver(s.withDirectory),
 		"withTimestamps":   ToResolver(s.withTimestamps),
+		"withSymlink":      ToResolver(s.withSymlink),
+		"withPermissions":  ToResolver(s.withPermissions),
+		"withOwner":        ToResolver(s.withOwner),
 		"withNewDirectory": ToResolver(s.withNewDirectory),
 		"withoutDirectory": ToResolver(s.withoutDirectory),
 		"diff":             ToResolver(s.diff),
+		"withPatch":        ToResolver(s.withPatch),
+		"withTemplates":    ToResolver(s.withTemplates),
 		"export":           ToResolver(s.export),
+		"asArchive":        ToResolver(s.asArchive),
+		"asGitCommit":      ToResolver(s.asGitCommit),
 		"dockerBuild":      ToResolver(s.dockerBuild),
 	})
 
@@ -79,6 +92,31 @@ func (s *directorySchema) directory(ctx context.Context, parent *core.Query, arg
 	return core.NewScratchDirectory(parent.PipelinePath(), platform), nil
 }
 
+type mergeDirectoriesArgs struct {
+	Directories []core.DirectoryID
+	Conflict    core.MergeConflict
+}
+
+func (s *directorySchema) mergeDirectories(ctx context.Context, parent *core.Query, args mergeDirectoriesArgs) (*core.Directory, error) {
+	dirs, err := decodeDirectories(args.Directories)
+	if err != nil {
+		return nil, err
+	}
+	return core.MergeDirectories(ctx, s.bk, s.svcs, dirs, args.Conflict, parent.PipelinePath(), s.platform)
+}
+
+func decodeDirectories(ids []core.DirectoryID) ([]*core.Directory, error) {
+	dirs := make([]*core.Directory, len(ids))
+	for i, id := range ids {
+		dir, err := id.Decode()
+		if err != nil {
+			return nil, err
+		}
+		dirs[i] = dir
+	}
+	return dirs, nil
+}
+
 func (s *directorySchema) sync(ctx context.Context, parent *core.Directory, _ any) (core.DirectoryID, error) {
 	_, err := parent.Evaluate(ctx, s.bk, s.svcs)
 	if err != nil {
@@ -127,12 +165,55 @@ func (s *directorySchema) withTimestamps(ctx context.Context, parent *core.Direc
 	return parent.WithTimestamps(ctx, args.Timestamp)
 }
 
+type dirWithSymlinkArgs struct {
+	Target   string
+	LinkName string
+}
+
+func (s *directorySchema) withSymlink(ctx context.Context, parent *core.Directory, args dirWithSymlinkArgs) (*core.Directory, error) {
+	return parent.WithSymlink(ctx, s.bk, args.Target, args.LinkName)
+}
+
+type dirWithPermissionsArgs struct {
+	Path        string
+	Permissions int
+	Recursive   bool
+}
+
+func (s *directorySchema) withPermissions(ctx context.Context, parent *core.Directory, args dirWithPermissionsArgs) (*core.Directory, error) {
+	return parent.WithPermissions(ctx, args.Path, fs.FileMode(args.Permissions), args.Recursive)
+}
+
+type dirWithOwnerArgs struct {
+	UID       int
+	GID       int
+	Path      string
+	Recursive bool
+}
+
+func (s *directorySchema) withOwner(ctx context.Context, parent *core.Directory, args dirWithOwnerArgs) (*core.Directory, error) {
+	return parent.WithOwner(ctx, args.Path, core.Ownership{UID: args.UID, GID: args.GID}, args.Recursive)
+}
+
 type entriesArgs struct {
-	Path string
+	Path      string
+	Recursive bool
 }
 
 func (s *directorySchema) entries(ctx context.Context, parent *core.Directory, args entriesArgs) ([]string, error) {
-	return parent.Entries(ctx, s.bk, s.svcs, args.Path)
+	return parent.Entries(ctx, s.bk, s.svcs, args.Path, args.Recursive)
+}
+
+func (s *directorySchema) entryInfos(ctx context.Context, parent *core.Directory, args entriesArgs) ([]core.FileInfo, error) {
+	return parent.EntryInfos(ctx, s.bk, s.svcs, args.Path, args.Recursive)
+}
+
+func (s *directorySchema) digest(ctx context.Context, parent *core.Directory, _ any) (string, error) {
+	dgst, err := parent.ContentDigest(ctx, s.bk, s.svcs)
+	if err != nil {
+		return "", err
+	}
+	return dgst.String(), nil
 }
 
 type globArgs struct {
@@ -143,6 +224,18 @@ func (s *directorySchema) glob(ctx context.Context, parent *core.Directory, args
 	return parent.Glob(ctx, s.bk, s.svcs, ".", args.Pattern)
 }
 
+type searchArgs struct {
+	Pattern string
+	Regex   bool
+	Paths   []string
+
+	core.CopyFilter
+}
+
+func (s *directorySchema) search(ctx context.Context, parent *core.Directory, args searchArgs) ([]core.SearchMatch, error) {
+	return parent.Search(ctx, s.bk, s.svcs, args.Pattern, args.Regex, args.Paths, args.CopyFilter)
+}
+
 type dirFileArgs struct {
 	Path string
 }
@@ -204,6 +297,47 @@ func (s *directorySchema) diff(ctx context.Context, parent *core.Directory, args
 	return parent.Diff(ctx, dir)
 }
 
+type withPatchArgs struct {
+	Patch     string
+	PatchFile core.FileID
+	Strip     int
+}
+
+func (s *directorySchema) withPatch(ctx context.Context, parent *core.Directory, args withPatchArgs) (*core.Directory, error) {
+	patch := args.Patch
+	switch {
+	case args.PatchFile != "" && patch != "":
+		return nil, fmt.Errorf("only one of patch and patchFile can be set")
+	case args.PatchFile != "":
+		file, err := args.PatchFile.Decode()
+		if err != nil {
+			return nil, err
+		}
+		contents, err := file.Contents(ctx, s.bk, s.svcs)
+		if err != nil {
+			return nil, err
+		}
+		patch = string(contents)
+	case patch == "":
+		return nil, fmt.Errorf("either patch or patchFile must be set")
+	}
+	return parent.WithPatch(ctx, s.bk, s.svcs, patch, args.Strip)
+}
+
+type withTemplatesArgs struct {
+	Pattern string
+
+	templateArgs
+}
+
+func (s *directorySchema) withTemplates(ctx context.Context, parent *core.Directory, args withTemplatesArgs) (*core.Directory, error) {
+	secrets, err := s.templateSecrets(ctx, args.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	return parent.WithTemplates(ctx, s.bk, s.svcs, args.Pattern, args.Data, args.Delims, secrets)
+}
+
 type dirExportArgs struct {
 	Path string
 }
@@ -217,6 +351,42 @@ func (s *directorySchema) export(ctx context.Context, parent *core.Directory, ar
 	return true, nil
 }
 
+type dirAsArchiveArgs struct {
+	Format       core.ArchiveFormat
+	Reproducible bool
+}
+
+func (s *directorySchema) asArchive(ctx context.Context, parent *core.Directory, args dirAsArchiveArgs) (*core.File, error) {
+	return parent.AsArchive(ctx, s.bk, s.svcs, s.platform, args.Format, args.Reproducible)
+}
+
+type dirAsGitCommitArgs struct {
+	Message string
+	Author  string
+	Parent  core.GitRefID
+	Date    string
+}
+
+func (s *directorySchema) asGitCommit(ctx context.Context, parent *core.Directory, args dirAsGitCommitArgs) (*core.GitRef, error) {
+	var parentRef *core.GitRef
+	if args.Parent != "" {
+		var err error
+		parentRef, err = args.Parent.Decode()
+		if err != nil {
+			return nil, err
+		}
+	}
+	date := time.Unix(0, 0)
+	if args.Date != "" {
+		var err error
+		date, err = time.Parse(time.RFC3339, args.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q, must be in RFC 3339 format: %w", args.Date, err)
+		}
+	}
+	return parent.AsGitCommit(args.Message, args.Author, parentRef, date)
+}
+
 type dirDockerBuildArgs struct {
 	Platform   *specs.Platform
 	Dockerfile string
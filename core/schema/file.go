@@ -2,6 +2,8 @@ package schema
 
 import (
 	"context"
+	"encoding/base64"
+	"io/fs"
 
 	"github.com/dagger/dagger/core"
 )
@@ -31,11 +33,19 @@ func (s *fileSchema) Resolvers() Resolvers {
 	}
 
 	ResolveIDable[core.File](rs, "File", ObjectResolver{
-		"sync":           ToResolver(s.sync),
-		"contents":       ToResolver(s.contents),
-		"size":           ToResolver(s.size),
-		"export":         ToResolver(s.export),
-		"withTimestamps": ToResolver(s.withTimestamps),
+		"sync":            ToResolver(s.sync),
+		"contents":        ToResolver(s.contents),
+		"read":            ToResolver(s.read),
+		"size":            ToResolver(s.size),
+		"name":            ToResolver(s.name),
+		"permissions":     ToResolver(s.permissions),
+		"digest":          ToResolver(s.digest),
+		"export":          ToResolver(s.export),
+		"unpack":          ToResolver(s.unpack),
+		"withTimestamps":  ToResolver(s.withTimestamps),
+		"withPermissions": ToResolver(s.withPermissions),
+		"withReplaced":    ToResolver(s.withReplaced),
+		"withTemplate":    ToResolver(s.withTemplate),
 	})
 
 	return rs
@@ -66,6 +76,20 @@ func (s *fileSchema) contents(ctx context.Context, file *core.File, args any) (s
 	return string(content), nil
 }
 
+type fileReadArgs struct {
+	Offset int
+	Length int
+}
+
+func (s *fileSchema) read(ctx context.Context, file *core.File, args fileReadArgs) (string, error) {
+	chunk, err := file.Read(ctx, s.bk, s.svcs, args.Offset, args.Length)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(chunk), nil
+}
+
 func (s *fileSchema) size(ctx context.Context, file *core.File, args any) (int64, error) {
 	info, err := file.Stat(ctx, s.bk, s.svcs)
 	if err != nil {
@@ -75,6 +99,22 @@ func (s *fileSchema) size(ctx context.Context, file *core.File, args any) (int64
 	return info.Size_, nil
 }
 
+func (s *fileSchema) name(ctx context.Context, file *core.File, args any) (string, error) {
+	return file.Name(), nil
+}
+
+func (s *fileSchema) permissions(ctx context.Context, file *core.File, args any) (int, error) {
+	return file.Permissions(ctx, s.bk, s.svcs)
+}
+
+func (s *fileSchema) digest(ctx context.Context, file *core.File, args any) (string, error) {
+	dgst, err := file.ContentDigest(ctx, s.bk, s.svcs)
+	if err != nil {
+		return "", err
+	}
+	return dgst.String(), nil
+}
+
 type fileExportArgs struct {
 	Path               string
 	AllowParentDirPath bool
@@ -89,6 +129,14 @@ func (s *fileSchema) export(ctx context.Context, parent *core.File, args fileExp
 	return true, nil
 }
 
+type fileUnpackArgs struct {
+	Format core.ArchiveFormat
+}
+
+func (s *fileSchema) unpack(ctx context.Context, parent *core.File, args fileUnpackArgs) (*core.Directory, error) {
+	return parent.Unpack(ctx, s.bk, s.svcs, args.Format)
+}
+
 type fileWithTimestampsArgs struct {
 	Timestamp int
 }
@@ -96,3 +144,56 @@ type fileWithTimestampsArgs struct {
 func (s *fileSchema) withTimestamps(ctx context.Context, parent *core.File, args fileWithTimestampsArgs) (*core.File, error) {
 	return parent.WithTimestamps(ctx, args.Timestamp)
 }
+
+type fileWithPermissionsArgs struct {
+	Permissions int
+}
+
+func (s *fileSchema) withPermissions(ctx context.Context, parent *core.File, args fileWithPermissionsArgs) (*core.File, error) {
+	return parent.WithPermissions(ctx, fs.FileMode(args.Permissions))
+}
+
+type fileWithReplacedArgs struct {
+	Search  string
+	Replace string
+	All     bool
+	Regex   bool
+}
+
+func (s *fileSchema) withReplaced(ctx context.Context, parent *core.File, args fileWithReplacedArgs) (*core.File, error) {
+	return parent.WithReplaced(ctx, s.bk, s.svcs, args.Search, args.Replace, args.All, args.Regex)
+}
+
+// templateArgs are the arguments shared by File.withTemplate and
+// Directory.withTemplates.
+type templateArgs struct {
+	Data    any
+	Delims  []string
+	Secrets []core.SecretID
+}
+
+// templateSecrets returns the plaintext of the secrets given to a template,
+// by name.
+func (s *APIServer) templateSecrets(ctx context.Context, ids []core.SecretID) (map[string][]byte, error) {
+	secrets := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		secret, err := id.Decode()
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := s.secrets.GetSecret(ctx, id.String())
+		if err != nil {
+			return nil, err
+		}
+		secrets[secret.Name] = plaintext
+	}
+	return secrets, nil
+}
+
+func (s *fileSchema) withTemplate(ctx context.Context, parent *core.File, args templateArgs) (*core.File, error) {
+	secrets, err := s.templateSecrets(ctx, args.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	return parent.WithTemplate(ctx, s.bk, s.svcs, args.Data, args.Delims, secrets)
+}
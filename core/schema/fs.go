@@ -20,6 +20,9 @@ var Container string
 //go:embed http.graphqls
 var HTTP string
 
+//go:embed artifact.graphqls
+var Artifact string
+
 //go:embed cache.graphqls
 var Cache string
 
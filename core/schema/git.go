@@ -2,9 +2,12 @@ package schema
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/core/socket"
+	"github.com/dagger/dagger/engine/sources/gitdns"
 )
 
 var _ SchemaResolvers = &gitSchema{}
@@ -31,13 +34,19 @@ func (s *gitSchema) Resolvers() Resolvers {
 	}
 
 	ResolveIDable[core.GitRepository](rs, "GitRepository", ObjectResolver{
-		"branch": ToResolver(s.branch),
-		"tag":    ToResolver(s.tag),
-		"commit": ToResolver(s.commit),
+		"branch":   ToResolver(s.branch),
+		"tag":      ToResolver(s.tag),
+		"commit":   ToResolver(s.commit),
+		"head":     ToResolver(s.head),
+		"branches": ToResolver(s.branches),
+		"tags":     ToResolver(s.tags),
 	})
 	ResolveIDable[core.GitRef](rs, "GitRef", ObjectResolver{
-		"tree":   ToResolver(s.tree),
-		"commit": ToResolver(s.fetchCommit),
+		"tree":           ToResolver(s.tree),
+		"commit":         ToResolver(s.fetchCommit),
+		"log":            ToResolver(s.log),
+		"tagsPointingAt": ToResolver(s.tagsPointingAt),
+		"push":           ToResolver(s.push),
 	})
 
 	return rs
@@ -50,6 +59,9 @@ type gitArgs struct {
 
 	SSHKnownHosts string    `json:"sshKnownHosts"`
 	SSHAuthSocket socket.ID `json:"sshAuthSocket"`
+
+	HTTPAuthToken  core.SecretID `json:"httpAuthToken"`
+	HTTPAuthHeader core.SecretID `json:"httpAuthHeader"`
 }
 
 func (s *gitSchema) git(ctx context.Context, parent *core.Query, args gitArgs) (*core.GitRepository, error) {
@@ -70,14 +82,25 @@ func (s *gitSchema) git(ctx context.Context, parent *core.Query, args gitArgs) (
 		})
 	}
 
+	for _, id := range []core.SecretID{args.HTTPAuthToken, args.HTTPAuthHeader} {
+		if id == "" {
+			continue
+		}
+		if err := id.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	repo := &core.GitRepository{
-		URL:           args.URL,
-		KeepGitDir:    args.KeepGitDir,
-		SSHKnownHosts: args.SSHKnownHosts,
-		SSHAuthSocket: args.SSHAuthSocket,
-		Services:      svcs,
-		Pipeline:      parent.PipelinePath(),
-		Platform:      s.APIServer.platform,
+		URL:            args.URL,
+		KeepGitDir:     args.KeepGitDir,
+		SSHKnownHosts:  args.SSHKnownHosts,
+		SSHAuthSocket:  args.SSHAuthSocket,
+		HTTPAuthToken:  args.HTTPAuthToken,
+		HTTPAuthHeader: args.HTTPAuthHeader,
+		Services:       svcs,
+		Pipeline:       parent.PipelinePath(),
+		Platform:       s.APIServer.platform,
 	}
 	return repo, nil
 }
@@ -98,8 +121,12 @@ type branchArgs struct {
 }
 
 func (s *gitSchema) branch(ctx context.Context, parent *core.GitRepository, args branchArgs) (*core.GitRef, error) {
+	ref, err := s.pinGitRef(ctx, parent, args.Name)
+	if err != nil {
+		return nil, err
+	}
 	return &core.GitRef{
-		Ref:  args.Name,
+		Ref:  ref,
 		Repo: parent,
 	}, nil
 }
@@ -109,17 +136,61 @@ type tagArgs struct {
 }
 
 func (s *gitSchema) tag(ctx context.Context, parent *core.GitRepository, args tagArgs) (*core.GitRef, error) {
+	ref, err := s.pinGitRef(ctx, parent, args.Name)
+	if err != nil {
+		return nil, err
+	}
 	return &core.GitRef{
-		Ref:  args.Name,
+		Ref:  ref,
 		Repo: parent,
 	}, nil
 }
 
+func (s *gitSchema) head(ctx context.Context, parent *core.GitRepository, _ any) (*core.GitRef, error) {
+	branch, err := parent.DefaultBranch(ctx, s.bk, s.svcs)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := s.pinGitRef(ctx, parent, branch)
+	if err != nil {
+		return nil, err
+	}
+	return &core.GitRef{
+		Ref:  ref,
+		Repo: parent,
+	}, nil
+}
+
+type refPatternsArgs struct {
+	Patterns []string
+}
+
+func (s *gitSchema) branches(ctx context.Context, parent *core.GitRepository, args refPatternsArgs) ([]string, error) {
+	return parent.Branches(ctx, s.bk, s.svcs, args.Patterns)
+}
+
+func (s *gitSchema) tags(ctx context.Context, parent *core.GitRepository, args refPatternsArgs) ([]string, error) {
+	return parent.Tags(ctx, s.bk, s.svcs, args.Patterns)
+}
+
+// pinGitRef returns the commit the given ref is pinned to in the lockfile,
+// if one is loaded.
+func (s *gitSchema) pinGitRef(ctx context.Context, repo *core.GitRepository, ref string) (string, error) {
+	return s.lockfile.PinGitRef(ctx, repo.URL, ref, func(ctx context.Context, _, ref string) (string, error) {
+		return s.resolveGitCommit(ctx, repo, ref)
+	})
+}
+
 type treeArgs struct {
 	// SSHKnownHosts is deprecated
 	SSHKnownHosts string `json:"sshKnownHosts"`
 	// SSHAuthSocket is deprecated
 	SSHAuthSocket socket.ID `json:"sshAuthSocket"`
+
+	Depth       *int
+	SparsePaths []string
+	Submodules  *bool
+	LFS         bool `json:"lfs"`
 }
 
 func (s *gitSchema) tree(ctx context.Context, parent *core.GitRef, treeArgs treeArgs) (*core.Directory, error) {
@@ -131,9 +202,100 @@ func (s *gitSchema) tree(ctx context.Context, parent *core.GitRef, treeArgs tree
 		res.Repo.SSHKnownHosts = treeArgs.SSHKnownHosts
 		res.Repo.SSHAuthSocket = treeArgs.SSHAuthSocket
 	}
-	return res.Tree(ctx, s.bk)
+
+	checkout := gitdns.Checkout{
+		SparsePaths:  treeArgs.SparsePaths,
+		NoSubmodules: treeArgs.Submodules != nil && !*treeArgs.Submodules,
+		LFS:          treeArgs.LFS,
+	}
+	if depth := treeArgs.Depth; depth != nil {
+		switch {
+		case *depth == 0:
+			return nil, fmt.Errorf("depth must not be 0")
+		case *depth < 0:
+			checkout.Depth = -1
+		case *depth > 1:
+			checkout.Depth = *depth
+		}
+	}
+	for _, p := range checkout.SparsePaths {
+		if !filepath.IsLocal(p) {
+			return nil, fmt.Errorf("sparse path %q must be relative to the repository root", p)
+		}
+	}
+	return res.Tree(ctx, s.bk, checkout)
 }
 
 func (s *gitSchema) fetchCommit(ctx context.Context, parent *core.GitRef, _ any) (string, error) {
-	return parent.Commit(ctx, s.bk)
+	return parent.Commit(ctx, s.bk, s.svcs)
+}
+
+type logArgs struct {
+	Since string
+}
+
+func (s *gitSchema) log(ctx context.Context, parent *core.GitRef, args logArgs) ([]core.GitLogEntry, error) {
+	return parent.Log(ctx, s.bk, s.svcs, args.Since)
+}
+
+func (s *gitSchema) tagsPointingAt(ctx context.Context, parent *core.GitRef, _ any) ([]string, error) {
+	return parent.TagsPointingAt(ctx, s.bk, s.svcs)
+}
+
+type pushArgs struct {
+	Remote string
+	Branch string
+	Force  bool
+
+	HTTPAuthToken  core.SecretID `json:"httpAuthToken"`
+	HTTPAuthHeader core.SecretID `json:"httpAuthHeader"`
+
+	SSHAuthSocket socket.ID `json:"sshAuthSocket"`
+	SSHKnownHosts string    `json:"sshKnownHosts"`
+
+	ExperimentalServiceHost *core.ServiceID `json:"experimentalServiceHost"`
+}
+
+func (s *gitSchema) push(ctx context.Context, parent *core.GitRef, args pushArgs) (string, error) {
+	opts := core.GitPushOpts{
+		Remote:        args.Remote,
+		Branch:        args.Branch,
+		Force:         args.Force,
+		SSHAuthSocket: string(args.SSHAuthSocket),
+		SSHKnownHosts: args.SSHKnownHosts,
+	}
+
+	if args.ExperimentalServiceHost != nil {
+		svc, err := args.ExperimentalServiceHost.Decode()
+		if err != nil {
+			return "", err
+		}
+		host, err := svc.Hostname(ctx, s.svcs)
+		if err != nil {
+			return "", err
+		}
+		opts.Services = append(opts.Services, core.ServiceBinding{
+			Service:  svc,
+			Hostname: host,
+		})
+	}
+
+	for _, secret := range []struct {
+		id        core.SecretID
+		plaintext *[]byte
+	}{
+		{args.HTTPAuthToken, &opts.HTTPAuthToken},
+		{args.HTTPAuthHeader, &opts.HTTPAuthHeader},
+	} {
+		if secret.id == "" {
+			continue
+		}
+		plaintext, err := s.secrets.GetSecret(ctx, secret.id.String())
+		if err != nil {
+			return "", err
+		}
+		*secret.plaintext = plaintext
+	}
+
+	return parent.Push(ctx, s.bk, s.svcs, opts)
 }
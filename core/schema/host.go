@@ -65,10 +65,11 @@ type hostDirectoryArgs struct {
 	Path string
 
 	core.CopyFilter
+	core.IgnoreFiles
 }
 
 func (s *hostSchema) directory(ctx context.Context, parent *core.Query, args hostDirectoryArgs) (*core.Directory, error) {
-	return s.host.Directory(ctx, s.bk, args.Path, parent.PipelinePath(), "host.directory", s.platform, args.CopyFilter)
+	return s.host.Directory(ctx, s.bk, args.Path, parent.PipelinePath(), "host.directory", s.platform, args.CopyFilter, args.IgnoreFiles)
 }
 
 type hostSocketArgs struct {
@@ -2,6 +2,9 @@ package schema
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/engine"
@@ -37,6 +40,11 @@ func (s *httpSchema) Resolvers() Resolvers {
 type httpArgs struct {
 	URL                     string          `json:"url"`
 	ExperimentalServiceHost *core.ServiceID `json:"experimentalServiceHost"`
+
+	Checksum    string           `json:"checksum"`
+	Headers     []httpdns.Header `json:"headers"`
+	Filename    string           `json:"filename"`
+	Permissions int              `json:"permissions"`
 }
 
 func (s *httpSchema) http(ctx context.Context, parent *core.Query, args httpArgs) (*core.File, error) {
@@ -45,6 +53,48 @@ func (s *httpSchema) http(ctx context.Context, parent *core.Query, args httpArgs
 	// of following more optimized cache codepaths.
 	// Do a hash encode to prevent conflicts with use of `/` in the URL while also not hitting max filename limits
 	filename := digest.FromString(args.URL).Encoded()
+	if args.Filename != "" {
+		if args.Filename != filepath.Base(args.Filename) || args.Filename == "." || args.Filename == ".." {
+			return nil, fmt.Errorf("invalid filename %q: must not be a path", args.Filename)
+		}
+		filename = args.Filename
+	}
+
+	opts := []llb.HTTPOption{
+		llb.Filename(filename),
+	}
+
+	if args.Checksum != "" {
+		checksum, err := digest.Parse(args.Checksum)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum %q: %w", args.Checksum, err)
+		}
+		if checksum.Algorithm() != digest.SHA256 {
+			return nil, fmt.Errorf("unsupported checksum algorithm %q: only sha256 is supported", checksum.Algorithm())
+		}
+		opts = append(opts, llb.Checksum(checksum))
+	}
+
+	if args.Permissions != 0 {
+		if args.Permissions < 0 || args.Permissions > 0o7777 {
+			return nil, fmt.Errorf("invalid permissions %#o", args.Permissions)
+		}
+		opts = append(opts, llb.Chmod(os.FileMode(args.Permissions)))
+	}
+
+	for _, h := range args.Headers {
+		if h.Name == "" {
+			return nil, fmt.Errorf("header name must not be empty")
+		}
+		if h.Secret != "" {
+			if h.Value != "" {
+				return nil, fmt.Errorf("header %s must have either a value or a secret, not both", h.Name)
+			}
+			if err := core.SecretID(h.Secret).Validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	svcs := core.ServiceBindings{}
 	if args.ExperimentalServiceHost != nil {
@@ -62,11 +112,8 @@ func (s *httpSchema) http(ctx context.Context, parent *core.Query, args httpArgs
 		})
 	}
 
-	opts := []llb.HTTPOption{
-		llb.Filename(filename),
-	}
-
-	useDNS := len(svcs) > 0
+	// llb.HTTP can't send headers
+	useDNS := len(svcs) > 0 || len(args.Headers) > 0
 
 	clientMetadata, err := engine.ClientMetadataFromContext(ctx)
 	if err == nil && !useDNS {
@@ -82,7 +129,11 @@ func (s *httpSchema) http(ctx context.Context, parent *core.Query, args httpArgs
 		// that use a Buildkit frontend (# syntax = ...).
 		//
 		// TODO: add API cap
-		st = httpdns.State(args.URL, clientMetadata.ClientIDs(), opts...)
+		var clientIDs []string
+		if clientMetadata != nil {
+			clientIDs = clientMetadata.ClientIDs()
+		}
+		st = httpdns.State(args.URL, clientIDs, args.Headers, opts...)
 	} else {
 		st = llb.HTTP(args.URL, opts...)
 	}
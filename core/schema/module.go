@@ -248,6 +248,10 @@ func (s *moduleSchema) directoryAsModule(ctx context.Context, sourceDir *core.Di
 	if err != nil {
 		return nil, fmt.Errorf("failed to create module from config: %w", err)
 	}
+	modMeta.Vendor, err = core.LoadModuleVendor(ctx, s.bk, s.services, modMeta.SourceDirectory, modMeta.SourceDirectorySubpath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load module vendor directory: %w", err)
+	}
 
 	mod, err := s.AddModFromMetadata(ctx, modMeta, sourceDir.PipelinePath())
 	if err != nil {
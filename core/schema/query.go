@@ -11,6 +11,7 @@ import (
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/core/pipeline"
 	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/buildkit"
 )
 
 type querySchema struct {
@@ -34,6 +35,10 @@ func (s *querySchema) Resolvers() Resolvers {
 		"Query": ObjectResolver{
 			"pipeline":                  ToResolver(s.pipeline),
 			"checkVersionCompatibility": ToResolver(s.checkVersionCompatibility),
+			"loadLockfile":              ToVoidResolver(s.loadLockfile),
+			"lockfile":                  ToResolver(s.lockfile),
+			"withRegistryMirror":        ToResolver(s.withRegistryMirror),
+			"registryMirrors":           ToResolver(s.registryMirrors),
 		},
 		"Port": ObjectResolver{
 			"protocol": ToResolver(s.portProtocolHack),
@@ -110,6 +115,71 @@ func (s *querySchema) checkVersionCompatibility(ctx context.Context, _ *core.Que
 	return true, nil
 }
 
+type loadLockfileArgs struct {
+	Contents string
+	Strict   bool
+}
+
+func (s *querySchema) loadLockfile(ctx context.Context, _ *core.Query, args loadLockfileArgs) error {
+	return s.APIServer.lockfile.Load([]byte(args.Contents), args.Strict)
+}
+
+type lockfileArgs struct {
+	Update bool
+}
+
+func (s *querySchema) lockfile(ctx context.Context, _ *core.Query, args lockfileArgs) (string, error) {
+	if args.Update {
+		err := s.APIServer.lockfile.Update(ctx, s.resolveImageDigest, func(ctx context.Context, url, ref string) (string, error) {
+			return s.resolveGitCommit(ctx, &core.GitRepository{URL: url, Platform: s.platform}, ref)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	contents, err := s.APIServer.lockfile.Contents()
+	if err != nil {
+		return "", err
+	}
+	return string(contents), nil
+}
+
+type withRegistryMirrorArgs struct {
+	Registry string
+	Mirror   string
+	Insecure bool
+	CACerts  string
+}
+
+func (s *querySchema) withRegistryMirror(ctx context.Context, parent *core.Query, args withRegistryMirrorArgs) (*core.Query, error) {
+	err := s.bk.AddRegistryMirror(buildkit.RegistryMirror{
+		Registry: args.Registry,
+		Mirror:   args.Mirror,
+		Insecure: args.Insecure,
+		CACerts:  args.CACerts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		parent = &core.Query{}
+	}
+	return parent, nil
+}
+
+func (s *querySchema) registryMirrors(ctx context.Context, _ *core.Query, _ any) ([]core.RegistryMirror, error) {
+	mirrors := []core.RegistryMirror{}
+	for _, m := range s.bk.ListRegistryMirrors() {
+		mirrors = append(mirrors, core.RegistryMirror{
+			Registry: m.Registry,
+			Mirror:   m.Mirror,
+			Insecure: m.Insecure,
+			Engine:   m.Engine,
+		})
+	}
+	return mirrors, nil
+}
+
 func (s *querySchema) portProtocolHack(ctx context.Context, port core.Port, args any) (string, error) {
 	// HACK(vito): this is a little counter-intuitive, but we need to return a
 	// string instead of the core.NetworkProtocol value so the resolver layer can
@@ -24,6 +24,7 @@ import (
 	tools "github.com/dagger/graphql-go-tools"
 	"github.com/dagger/graphql/gqlerrors"
 	"github.com/iancoleman/strcase"
+	"github.com/moby/buildkit/client/llb"
 	"github.com/moby/buildkit/util/bklog"
 	"github.com/moby/buildkit/util/leaseutil"
 	"github.com/opencontainers/go-digest"
@@ -56,6 +57,7 @@ func New(ctx context.Context, params InitializeArgs) (*APIServer, error) {
 		leaseManager: params.LeaseManager,
 		services:     svcs,
 		host:         core.NewHost(),
+		lockfile:     core.NewLockfile(),
 
 		endpoints: map[string]http.Handler{},
 
@@ -86,6 +88,7 @@ func New(ctx context.Context, params InitializeArgs) (*APIServer, error) {
 		&hostSchema{api, api.host, api.services},
 		&moduleSchema{api},
 		&httpSchema{api, api.services},
+		&artifactSchema{api, api.services},
 		&platformSchema{api},
 		&socketSchema{api, api.host},
 	)
@@ -120,6 +123,7 @@ type APIServer struct {
 	leaseManager *leaseutil.Manager
 	host         *core.Host
 	services     *core.Services
+	lockfile     *core.Lockfile
 
 	buildCache  *core.CacheMap[uint64, *core.Container]
 	importCache *core.CacheMap[uint64, *specs.Descriptor]
@@ -219,6 +223,7 @@ func (s *APIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	mux.Handle("/query", NewHandler(&HandlerConfig{
 		Schema: schema.Compiled,
 	}))
+	mux.Handle("/file", http.HandlerFunc(s.serveFile))
 	mux.Handle("/shutdown", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
 		bklog.G(ctx).Debugf("shutting down client %s", clientMetadata.ClientID)
@@ -236,6 +241,41 @@ func (s *APIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	mux.ServeHTTP(w, r)
 }
 
+// serveFile streams the contents of the file whose ID is given by the id
+// form value, regardless of its size. Range requests are supported, e.g. to
+// resume interrupted downloads.
+func (s *APIServer) serveFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	file, err := core.FileID(r.FormValue("id")).Decode()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid file ID: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	f, err := file.Open(ctx, s.host, s.bk, s.services)
+	if err != nil {
+		bklog.G(ctx).WithError(err).Error("failed to open file")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if info.IsDir() {
+		http.Error(w, fmt.Sprintf("%s is a directory", file.File), http.StatusBadRequest)
+		return
+	}
+
+	// don't sniff the content type, which would read the first chunk twice
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, file.Name(), info.ModTime(), f)
+}
+
 func (s *APIServer) ShutdownClient(ctx context.Context, client *engine.ClientMetadata) error {
 	return s.services.StopClientServices(ctx, client)
 }
@@ -247,6 +287,26 @@ func (s *APIServer) MuxEndpoint(ctx context.Context, path string, handler http.H
 	return nil
 }
 
+// resolveImageDigest resolves an image reference to the digest of its
+// manifest, for pinning it in the lockfile.
+func (s *APIServer) resolveImageDigest(ctx context.Context, ref string) (digest.Digest, error) {
+	if s.bk.HasRegistryMirror(ref) {
+		desc, err := s.bk.ResolveMirroredImage(ctx, ref)
+		return desc.Digest, err
+	}
+	_, dgst, _, err := s.bk.ResolveImageConfig(ctx, ref, llb.ResolveImageConfigOpt{
+		Platform:    &s.platform,
+		ResolveMode: llb.ResolveModeDefault.String(),
+	})
+	return dgst, err
+}
+
+// resolveGitCommit resolves a ref of the given repository to a commit, for
+// pinning it in the lockfile.
+func (s *APIServer) resolveGitCommit(ctx context.Context, repo *core.GitRepository, ref string) (string, error) {
+	return (&core.GitRef{Ref: ref, Repo: repo}).Commit(ctx, s.bk, s.services)
+}
+
 func (s *APIServer) AddModFromMetadata(
 	ctx context.Context,
 	modMeta *core.Module,
@@ -308,11 +368,14 @@ func (s *APIServer) AddModFromRef(
 	modMeta, err := core.ModuleFromRef(
 		ctx, s.bk, s.services, pipeline, s.platform,
 		parentMod.SourceDirectory, parentMod.SourceDirectorySubpath,
-		ref,
+		ref, parentMod.DependencyPin(ref), parentMod.Vendor,
 	)
 	if err != nil {
 		return nil, err
 	}
+	// the versions selected for the root module apply to its whole graph,
+	// rather than the ones selected for each dependency on its own
+	modMeta.SelectedVersions = parentMod.SelectedVersions
 	return s.AddModFromMetadata(ctx, modMeta, pipeline)
 }
 
@@ -344,7 +407,15 @@ func (s *APIServer) ServeModuleToMainClient(ctx context.Context, modMeta *core.M
 	if !ok {
 		return fmt.Errorf("client call not found")
 	}
-	deps := append([]Mod{}, callCtx.deps.mods...)
+	deps := make([]Mod, 0, len(callCtx.deps.mods)+1)
+	for _, dep := range callCtx.deps.mods {
+		// a module served again replaces its previous version, e.g. when it
+		// changed on the host between the iterations of watch mode
+		if dep.Name() == mod.Name() {
+			continue
+		}
+		deps = append(deps, dep)
+	}
 	deps = append(deps, mod)
 	callCtx.deps, err = newModDeps(s, deps)
 	if err != nil {
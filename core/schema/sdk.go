@@ -60,7 +60,7 @@ func (s *APIServer) sdkForModule(ctx context.Context, mod *core.Module) (SDK, er
 
 	sdkMod, err := core.ModuleFromRef(ctx, s.bk, s.services, nil, s.platform,
 		mod.SourceDirectory, mod.SourceDirectorySubpath,
-		mod.SDK,
+		mod.SDK, nil, nil,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load sdk module %s: %w", mod.SDK, err)
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// SearchMatch is a line of a file matching the pattern given to
+// Directory.search.
+type SearchMatch struct {
+	Path       string `json:"path"`
+	LineNumber int    `json:"lineNumber"`
+	Line       string `json:"line"`
+}
+
+// searchBinaryPeekSize is how many bytes of a file are looked at for a NUL
+// byte to tell whether it's binary, like grep does.
+const searchBinaryPeekSize = 8000
+
+// newLineMatcher returns a function reporting whether a line matches the
+// given pattern, which is either a plain string or an RE2 regular
+// expression.
+func newLineMatcher(pattern string, regex bool) (func(string) bool, error) {
+	if pattern == "" {
+		return nil, errors.New("pattern must not be empty")
+	}
+	if !regex {
+		return func(line string) bool {
+			return strings.Contains(line, pattern)
+		}, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+	}
+	return re.MatchString, nil
+}
+
+// searchLines returns the lines read from r that match, reported as being
+// in the file at the given path. Binary files have no matches.
+func searchLines(filePath string, r io.Reader, match func(string) bool) ([]SearchMatch, error) {
+	br := bufio.NewReaderSize(r, searchBinaryPeekSize)
+
+	head, err := br.Peek(searchBinaryPeekSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if bytes.IndexByte(head, 0) != -1 {
+		return nil, nil
+	}
+
+	var matches []SearchMatch
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if line == "" && errors.Is(err, io.EOF) {
+			break
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if match(line) {
+			matches = append(matches, SearchMatch{
+				Path:       filePath,
+				LineNumber: lineNumber,
+				Line:       line,
+			})
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchLines(t *testing.T) {
+	contents := "package main\r\n\n// TODO: fix\nfunc main() {} // todo\n// TODO: last"
+
+	t.Run("plain", func(t *testing.T) {
+		match, err := newLineMatcher("TODO", false)
+		require.NoError(t, err)
+		matches, err := searchLines("main.go", strings.NewReader(contents), match)
+		require.NoError(t, err)
+		require.Equal(t, []SearchMatch{
+			{Path: "main.go", LineNumber: 3, Line: "// TODO: fix"},
+			{Path: "main.go", LineNumber: 5, Line: "// TODO: last"},
+		}, matches)
+	})
+
+	t.Run("regex", func(t *testing.T) {
+		match, err := newLineMatcher(`(?i)todo$|^package`, true)
+		require.NoError(t, err)
+		matches, err := searchLines("main.go", strings.NewReader(contents), match)
+		require.NoError(t, err)
+		require.Equal(t, []SearchMatch{
+			{Path: "main.go", LineNumber: 1, Line: "package main"},
+			{Path: "main.go", LineNumber: 4, Line: "func main() {} // todo"},
+		}, matches)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		match, err := newLineMatcher("TODO", false)
+		require.NoError(t, err)
+		matches, err := searchLines("bin", strings.NewReader("TODO\x00\nTODO\n"), match)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+	})
+
+	t.Run("long file", func(t *testing.T) {
+		match, err := newLineMatcher("needle", false)
+		require.NoError(t, err)
+		long := strings.Repeat("hay\n", 10000) + "needle\n"
+		matches, err := searchLines("long.txt", strings.NewReader(long), match)
+		require.NoError(t, err)
+		require.Equal(t, []SearchMatch{{Path: "long.txt", LineNumber: 10001, Line: "needle"}}, matches)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := newLineMatcher("", false)
+		require.ErrorContains(t, err, "pattern must not be empty")
+		_, err = newLineMatcher("(", true)
+		require.ErrorContains(t, err, "invalid regular expression")
+	})
+}
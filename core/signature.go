@@ -0,0 +1,347 @@
+package core
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/docker/distribution/reference"
+	"github.com/moby/buildkit/identity"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/vito/progrock"
+
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+// The following constants match the layout cosign uses to store signatures
+// in a registry, so that images signed by Dagger can be verified with
+// `cosign verify --key` and vice versa.
+const (
+	cosignSignatureMediaType  = "application/vnd.dev.cosign.simplesigning.v1+json"
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	cosignSignatureType       = "cosign container image signature"
+)
+
+// ImageVerification configures the keys an image must be signed with in
+// order to be pulled.
+type ImageVerification struct {
+	PublicKeys []string `json:"publicKeys"`
+}
+
+// simpleSigningPayload is the payload signed by cosign for container images.
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]any `json:"optional"`
+}
+
+// SignImage signs the image at the given digested ref with the PEM-encoded
+// private key and pushes the signature next to it in the registry.
+func SignImage(ctx context.Context, bk *buildkit.Client, ref string, privateKey []byte) (rerr error) {
+	named, dgst, err := parseDigestedRef(ref)
+	if err != nil {
+		return err
+	}
+
+	rec := progrock.FromContext(ctx)
+	vtx := rec.Vertex(
+		digest.Digest(identity.NewID()),
+		fmt.Sprintf("sign %s", ref),
+	)
+	defer func() { vtx.Done(rerr) }()
+
+	signer, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("parse signing key: %w", err)
+	}
+
+	var payload simpleSigningPayload
+	payload.Critical.Identity.DockerReference = named.Name()
+	payload.Critical.Image.DockerManifestDigest = dgst.String()
+	payload.Critical.Type = cosignSignatureType
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	sig, err := signPayload(signer, payloadBytes)
+	if err != nil {
+		return fmt.Errorf("sign payload: %w", err)
+	}
+
+	sigRef := signatureRef(named, dgst)
+
+	// append to any existing signatures, as cosign does
+	man, err := fetchSignatureManifest(ctx, bk, sigRef)
+	if err != nil {
+		return err
+	}
+	if man == nil {
+		man = &specs.Manifest{}
+	}
+
+	payloadDesc := specs.Descriptor{
+		MediaType: cosignSignatureMediaType,
+		Digest:    digest.FromBytes(payloadBytes),
+		Size:      int64(len(payloadBytes)),
+		Annotations: map[string]string{
+			cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig),
+		},
+	}
+	if err := bk.PushRegistryBlob(ctx, sigRef, payloadDesc, payloadBytes); err != nil {
+		return fmt.Errorf("push signature payload: %w", err)
+	}
+	man.Layers = append(man.Layers, payloadDesc)
+
+	cfg := specs.Image{
+		RootFS: specs.RootFS{Type: "layers"},
+	}
+	for _, layer := range man.Layers {
+		cfg.RootFS.DiffIDs = append(cfg.RootFS.DiffIDs, layer.Digest)
+	}
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	man.Config = specs.Descriptor{
+		MediaType: specs.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(cfgBytes),
+		Size:      int64(len(cfgBytes)),
+	}
+	if err := bk.PushRegistryBlob(ctx, sigRef, man.Config, cfgBytes); err != nil {
+		return fmt.Errorf("push signature config: %w", err)
+	}
+
+	man.SchemaVersion = 2
+	man.MediaType = specs.MediaTypeImageManifest
+	manBytes, err := json.Marshal(man)
+	if err != nil {
+		return err
+	}
+	manDesc := specs.Descriptor{
+		MediaType: specs.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manBytes),
+		Size:      int64(len(manBytes)),
+	}
+	if err := bk.PushRegistryBlob(ctx, sigRef, manDesc, manBytes); err != nil {
+		return fmt.Errorf("push signature manifest: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyImage checks that the image at the given address is signed by at
+// least one of the given PEM-encoded public keys. It returns the address
+// pinned to the verified digest, so that the image pulled is the one that
+// was verified.
+func VerifyImage(ctx context.Context, bk *buildkit.Client, addr string, opts ImageVerification) (_ string, rerr error) {
+	if len(opts.PublicKeys) == 0 {
+		return "", errors.New("no public keys to verify against")
+	}
+
+	keys := make([]crypto.PublicKey, 0, len(opts.PublicKeys))
+	for _, pemKey := range opts.PublicKeys {
+		key, err := parsePublicKey([]byte(pemKey))
+		if err != nil {
+			return "", fmt.Errorf("parse public key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	refName, err := reference.ParseNormalizedNamed(addr)
+	if err != nil {
+		return "", err
+	}
+	ref := reference.TagNameOnly(refName).String()
+
+	rec := progrock.FromContext(ctx)
+	vtx := rec.Vertex(
+		digest.Digest(identity.NewID()),
+		fmt.Sprintf("verify %s", ref),
+	)
+	defer func() { vtx.Done(rerr) }()
+
+	desc, err := bk.ResolveRegistryDescriptor(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	sigRef := signatureRef(refName, desc.Digest)
+	man, err := fetchSignatureManifest(ctx, bk, sigRef)
+	if err != nil {
+		return "", err
+	}
+	if man == nil {
+		return "", fmt.Errorf("no signatures found for %s", ref)
+	}
+
+	for _, layer := range man.Layers {
+		if layer.MediaType != cosignSignatureMediaType {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(layer.Annotations[cosignSignatureAnnotation])
+		if err != nil {
+			continue
+		}
+		payloadBytes, err := bk.FetchRegistryBlob(ctx, sigRef, layer)
+		if err != nil {
+			return "", fmt.Errorf("fetch signature payload: %w", err)
+		}
+		var payload simpleSigningPayload
+		if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+			continue
+		}
+		if payload.Critical.Image.DockerManifestDigest != desc.Digest.String() {
+			continue
+		}
+		for _, key := range keys {
+			if verifyPayload(key, payloadBytes, sig) == nil {
+				digested, err := reference.WithDigest(refName, desc.Digest)
+				if err != nil {
+					return "", err
+				}
+				return digested.String(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no valid signature found for %s", ref)
+}
+
+func parseDigestedRef(ref string) (reference.Named, digest.Digest, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return nil, "", err
+	}
+	digested, ok := named.(reference.Digested)
+	if !ok {
+		return nil, "", fmt.Errorf("reference %s has no digest", ref)
+	}
+	return reference.TrimNamed(named), digested.Digest(), nil
+}
+
+// signatureRef returns the ref cosign stores the signatures of the given
+// image digest at, e.g. registry/repo:sha256-<hex>.sig
+func signatureRef(named reference.Named, dgst digest.Digest) string {
+	return fmt.Sprintf("%s:%s-%s.sig", named.Name(), dgst.Algorithm(), dgst.Encoded())
+}
+
+// fetchSignatureManifest returns the manifest holding signatures at the
+// given ref, or nil if the image has not been signed yet.
+func fetchSignatureManifest(ctx context.Context, bk *buildkit.Client, sigRef string) (*specs.Manifest, error) {
+	desc, err := bk.ResolveRegistryDescriptor(ctx, sigRef)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("resolve signatures: %w", err)
+	}
+	manBytes, err := bk.FetchRegistryBlob(ctx, sigRef, desc)
+	if err != nil {
+		return nil, fmt.Errorf("fetch signatures: %w", err)
+	}
+	var man specs.Manifest
+	if err := json.Unmarshal(manBytes, &man); err != nil {
+		return nil, fmt.Errorf("unmarshal signatures: %w", err)
+	}
+	return &man, nil
+}
+
+func signPayload(signer crypto.Signer, payload []byte) ([]byte, error) {
+	switch key := signer.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, payload), nil
+	case *ecdsa.PrivateKey:
+		h := sha256.Sum256(payload)
+		return ecdsa.SignASN1(rand.Reader, key, h[:])
+	case *rsa.PrivateKey:
+		h := sha256.Sum256(payload)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", signer)
+	}
+}
+
+func verifyPayload(key crypto.PublicKey, payload, sig []byte) error {
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		h := sha256.Sum256(payload)
+		if !ecdsa.VerifyASN1(key, h[:], sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		h := sha256.Sum256(payload)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, h[:], sig)
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+func parsePublicKey(pemBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+func parsePrivateKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
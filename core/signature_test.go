@@ -0,0 +1,46 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignPayloadRoundTrip(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for name, key := range map[string]any{
+		"ecdsa":   ecKey,
+		"ed25519": edKey,
+	} {
+		key := key
+		t.Run(name, func(t *testing.T) {
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			require.NoError(t, err)
+			privPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+			signer, err := parsePrivateKey(privPEM)
+			require.NoError(t, err)
+
+			pubDER, err := x509.MarshalPKIXPublicKey(signer.Public())
+			require.NoError(t, err)
+			pub, err := parsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+			require.NoError(t, err)
+
+			payload := []byte(`{"critical":{}}`)
+			sig, err := signPayload(signer, payload)
+			require.NoError(t, err)
+			require.NoError(t, verifyPayload(pub, payload, sig))
+			require.Error(t, verifyPayload(pub, []byte(`{"critical":{"tampered":true}}`), sig))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateRender renders Go text/templates with the data and secrets given
+// to File.withTemplate and Directory.withTemplates.
+type templateRender struct {
+	Data    any
+	Delims  []string
+	Secrets map[string][]byte
+
+	// usedSecrets is set once a template read a secret, in which case what it
+	// rendered must not end up in an LLB definition.
+	usedSecrets bool
+}
+
+func (r *templateRender) render(name string, text []byte) ([]byte, error) {
+	tmpl := template.New(name).Option("missingkey=error").Funcs(r.funcs())
+	if len(r.Delims) > 0 {
+		if len(r.Delims) != 2 {
+			return nil, fmt.Errorf("delims must be the left and right delimiters, got %d values", len(r.Delims))
+		}
+		tmpl = tmpl.Delims(r.Delims[0], r.Delims[1])
+	}
+
+	tmpl, err := tmpl.Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r.Data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// funcs returns the functions available to templates, on top of the
+// text/template builtins. None of them can reach the engine's filesystem,
+// network or environment.
+func (r *templateRender) funcs() template.FuncMap {
+	return template.FuncMap{
+		"secret": func(name string) (string, error) {
+			plaintext, ok := r.Secrets[name]
+			if !ok {
+				return "", fmt.Errorf("secret %q was not given to the template", name)
+			}
+			r.usedSecrets = true
+			return string(plaintext), nil
+		},
+
+		"default": func(def, val any) any {
+			if val == nil || reflect.ValueOf(val).IsZero() {
+				return def
+			}
+			return val
+		},
+		"required": func(msg string, val any) (any, error) {
+			if val == nil || reflect.ValueOf(val).IsZero() {
+				return nil, fmt.Errorf("%s", msg)
+			}
+			return val, nil
+		},
+
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join": func(sep string, list []any) string {
+			strs := make([]string, len(list))
+			for i, v := range list {
+				strs[i] = fmt.Sprint(v)
+			}
+			return strings.Join(strs, sep)
+		},
+		"quote": func(s any) string { return fmt.Sprintf("%q", fmt.Sprint(s)) },
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"nindent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return "\n" + pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+
+		"b64enc": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec": func(s string) (string, error) {
+			dec, err := base64.StdEncoding.DecodeString(s)
+			return string(dec), err
+		},
+		"toJson": func(v any) (string, error) {
+			out, err := json.Marshal(v)
+			return string(out), err
+		},
+		"toYaml": func(v any) (string, error) {
+			out, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(out), "\n"), err
+		},
+	}
+}
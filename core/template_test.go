@@ -0,0 +1,100 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateRender(t *testing.T) {
+	data := map[string]any{
+		"name":  "app",
+		"port":  float64(8080),
+		"tags":  []any{"a", "b"},
+		"empty": "",
+		"env":   map[string]any{"DEBUG": "1"},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		tmpl     string
+		delims   []string
+		expected string
+	}{
+		{
+			name:     "data",
+			tmpl:     "{{ .name }}:{{ .port }}",
+			expected: "app:8080",
+		},
+		{
+			name:     "delims",
+			tmpl:     "[[ .name ]] {{ .name }}",
+			delims:   []string{"[[", "]]"},
+			expected: "app {{ .name }}",
+		},
+		{
+			name:     "strings",
+			tmpl:     `{{ .name | upper }} {{ "  x  " | trim }} {{ .name | replace "a" "A" }} {{ .name | quote }}`,
+			expected: `APP x App "app"`,
+		},
+		{
+			name:     "default",
+			tmpl:     `{{ .empty | default "none" }} {{ .name | default "none" }}`,
+			expected: "none app",
+		},
+		{
+			name:     "lists",
+			tmpl:     `{{ join "," .tags }} {{ range split "/" "x/y" }}{{ . }}{{ end }}`,
+			expected: "a,b xy",
+		},
+		{
+			name:     "encoding",
+			tmpl:     `{{ .name | b64enc }} {{ "YXBw" | b64dec }} {{ toJson .tags }}`,
+			expected: `YXBw app ["a","b"]`,
+		},
+		{
+			name:     "yaml",
+			tmpl:     "env:{{ toYaml .env | nindent 2 }}",
+			expected: "env:\n  DEBUG: \"1\"",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := &templateRender{Data: data, Delims: tc.delims}
+			out, err := r.render(tc.name, []byte(tc.tmpl))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(out))
+			require.False(t, r.usedSecrets)
+		})
+	}
+
+	t.Run("missing key", func(t *testing.T) {
+		r := &templateRender{Data: data}
+		_, err := r.render("missing", []byte("{{ .nope }}"))
+		require.ErrorContains(t, err, "nope")
+	})
+
+	t.Run("required", func(t *testing.T) {
+		r := &templateRender{Data: data}
+		_, err := r.render("required", []byte(`{{ required "empty must be set" .empty }}`))
+		require.ErrorContains(t, err, "empty must be set")
+	})
+
+	t.Run("bad delims", func(t *testing.T) {
+		r := &templateRender{Delims: []string{"[["}}
+		_, err := r.render("delims", []byte("x"))
+		require.ErrorContains(t, err, "got 1 values")
+	})
+
+	t.Run("secret", func(t *testing.T) {
+		r := &templateRender{Secrets: map[string][]byte{"token": []byte("s3cr3t")}}
+		out, err := r.render("secret", []byte(`token={{ secret "token" }}`))
+		require.NoError(t, err)
+		require.Equal(t, "token=s3cr3t", string(out))
+		require.True(t, r.usedSecrets)
+
+		r = &templateRender{}
+		_, err = r.render("secret", []byte(`{{ secret "token" }}`))
+		require.ErrorContains(t, err, `secret "token" was not given to the template`)
+	})
+}
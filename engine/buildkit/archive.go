@@ -0,0 +1,391 @@
+package buildkit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containerd/continuity/fs"
+	"github.com/klauspost/compress/zstd"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/moby/buildkit/snapshot"
+	bksolverpb "github.com/moby/buildkit/solver/pb"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/vito/progrock"
+)
+
+// ArchiveFormat is the format of an archive written by DirectoryToArchive.
+type ArchiveFormat string
+
+const (
+	ArchiveTar  ArchiveFormat = "tar"
+	ArchiveTgz  ArchiveFormat = "tgz"
+	ArchiveZstd ArchiveFormat = "zstd"
+	ArchiveZip  ArchiveFormat = "zip"
+)
+
+// reproducibleModTime is the modification time of every entry of an archive
+// written in reproducible mode. Zip archives can't represent times before
+// 1980, so the same epoch is used for every format.
+var reproducibleModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// DirectoryToArchive archives the given directory of the given definition
+// into a file named fileName and imports it back as a definition.
+func (c *Client) DirectoryToArchive(
+	ctx context.Context,
+	engineHostPlatform specs.Platform,
+	def *bksolverpb.Definition,
+	dirPath string,
+	fileName string,
+	format ArchiveFormat,
+	reproducible bool,
+) (*bksolverpb.Definition, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	tmpDir, err := os.MkdirTemp("", "dagger-archive")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir for archive: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = c.WithMountedDef(ctx, def, func(root string) error {
+		srcPath, err := fs.RootPath(root, dirPath)
+		if err != nil {
+			return fmt.Errorf("failed to get root path: %s", err)
+		}
+		archive, err := os.Create(filepath.Join(tmpDir, fileName))
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %s", err)
+		}
+		defer archive.Close()
+		if err := WriteArchive(archive, srcPath, format, reproducible); err != nil {
+			return err
+		}
+		return archive.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, recorder := progrock.WithGroup(ctx, "directory to archive")
+	pbDef, err := c.EngineContainerLocalImport(ctx, recorder, engineHostPlatform, tmpDir, nil, []string{fileName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import archive from engine container filesystem: %s", err)
+	}
+	return pbDef, nil
+}
+
+// UnpackZip extracts the given zip file of the given definition and imports
+// the extracted files back as a definition.
+func (c *Client) UnpackZip(
+	ctx context.Context,
+	engineHostPlatform specs.Platform,
+	def *bksolverpb.Definition,
+	filePath string,
+) (*bksolverpb.Definition, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	tmpDir, err := os.MkdirTemp("", "dagger-unpack")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir for unpack: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	destDir := filepath.Join(tmpDir, "contents")
+	err = c.WithMountedDef(ctx, def, func(root string) error {
+		srcPath, err := fs.RootPath(root, filePath)
+		if err != nil {
+			return fmt.Errorf("failed to get root path: %s", err)
+		}
+		archive, err := zip.OpenReader(srcPath)
+		if err != nil {
+			return fmt.Errorf("failed to open zip archive: %s", err)
+		}
+		defer archive.Close()
+		return ExtractZip(&archive.Reader, destDir)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, recorder := progrock.WithGroup(ctx, "unpack zip archive")
+	pbDef, err := c.EngineContainerLocalImport(ctx, recorder, engineHostPlatform, destDir, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import unpacked archive from engine container filesystem: %s", err)
+	}
+	return pbDef, nil
+}
+
+// WithMountedDef solves the given definition and calls fn with the path its
+// result is mounted read-only at.
+func (c *Client) WithMountedDef(ctx context.Context, def *bksolverpb.Definition, fn func(root string) error) error {
+	res, err := c.Solve(ctx, bkgw.SolveRequest{Definition: def, Evaluate: true})
+	if err != nil {
+		return fmt.Errorf("failed to solve: %s", err)
+	}
+	ref, err := res.SingleRef()
+	if err != nil {
+		return fmt.Errorf("failed to get single ref: %s", err)
+	}
+
+	root, err := os.MkdirTemp("", "dagger-scratch")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	mountable, err := ref.getMountable(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get mountable: %s", err)
+	}
+	if mountable == nil {
+		// scratch
+		return fn(root)
+	}
+	mounter := snapshot.LocalMounter(mountable)
+	mountPath, err := mounter.Mount()
+	if err != nil {
+		return fmt.Errorf("failed to mount: %s", err)
+	}
+	defer mounter.Unmount()
+	return fn(mountPath)
+}
+
+// WriteArchive writes the contents of the given directory to w as an archive
+// of the given format.
+//
+// Entries are always written in lexical order. In reproducible mode, their
+// timestamps are reset and their ownership is dropped, so that the same
+// contents always produce the same archive.
+func WriteArchive(w io.Writer, root string, format ArchiveFormat, reproducible bool) error {
+	switch format {
+	case ArchiveZip:
+		zw := zip.NewWriter(w)
+		if err := walkArchive(root, func(name string, info os.FileInfo, link string) error {
+			return writeZipEntry(zw, root, name, info, link, reproducible)
+		}); err != nil {
+			return err
+		}
+		return zw.Close()
+	case ArchiveTar, ArchiveTgz, ArchiveZstd:
+	default:
+		return fmt.Errorf("unsupported archive format %q", format)
+	}
+
+	var compressed io.WriteCloser
+	switch format {
+	case ArchiveTgz:
+		compressed = gzip.NewWriter(w)
+	case ArchiveZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		compressed = zw
+	}
+	if compressed != nil {
+		w = compressed
+	}
+
+	tw := tar.NewWriter(w)
+	if err := walkArchive(root, func(name string, info os.FileInfo, link string) error {
+		return writeTarEntry(tw, root, name, info, link, reproducible)
+	}); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if compressed != nil {
+		return compressed.Close()
+	}
+	return nil
+}
+
+// walkArchive calls fn with the slash-separated relative path of every entry
+// under root in lexical order, along with the target of symlinks.
+func walkArchive(root string, fn func(name string, info os.FileInfo, link string) error) error {
+	return filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(p)
+			if err != nil {
+				return err
+			}
+		}
+		return fn(filepath.ToSlash(rel), info, link)
+	})
+}
+
+func writeTarEntry(tw *tar.Writer, root, name string, info os.FileInfo, link string, reproducible bool) error {
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	hdr.Format = tar.FormatPAX
+	if reproducible {
+		hdr.ModTime = reproducibleModTime
+		hdr.AccessTime = time.Time{}
+		hdr.ChangeTime = time.Time{}
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	return copyFileTo(tw, filepath.Join(root, filepath.FromSlash(name)))
+}
+
+func writeZipEntry(zw *zip.Writer, root, name string, info os.FileInfo, link string, reproducible bool) error {
+	if !info.IsDir() && !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+		// zip has no representation for devices, fifos or sockets
+		return nil
+	}
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	} else if info.Mode().IsRegular() {
+		hdr.Method = zip.Deflate
+	}
+	if reproducible {
+		hdr.Modified = reproducibleModTime
+	}
+	entry, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		_, err := io.WriteString(entry, link)
+		return err
+	case info.Mode().IsRegular():
+		return copyFileTo(entry, filepath.Join(root, filepath.FromSlash(name)))
+	}
+	return nil
+}
+
+func copyFileTo(w io.Writer, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// ExtractZip extracts the given zip archive into dest, refusing entries that
+// would escape it.
+func ExtractZip(archive *zip.Reader, dest string) error {
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	for _, entry := range archive.File {
+		name := path.Clean("/" + strings.ReplaceAll(entry.Name, `\`, "/"))
+		if name == "/" {
+			continue
+		}
+		target, err := fs.RootPath(dest, name)
+		if err != nil {
+			return fmt.Errorf("invalid zip entry %q: %s", entry.Name, err)
+		}
+		if err := extractZipEntry(entry, target); err != nil {
+			return fmt.Errorf("failed to extract %q: %s", entry.Name, err)
+		}
+	}
+	return nil
+}
+
+func extractZipEntry(entry *zip.File, target string) error {
+	mode := entry.Mode()
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	switch {
+	case mode.IsDir():
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return err
+		}
+		if err := os.Chmod(target, mode.Perm()); err != nil {
+			return err
+		}
+	case mode&os.ModeSymlink != 0:
+		rc, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		link, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(string(link), target); err != nil {
+			return err
+		}
+		return nil
+	default:
+		perm := mode.Perm()
+		if perm == 0 {
+			perm = 0o644
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+		if err != nil {
+			return err
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		_, err = io.Copy(f, rc)
+		rc.Close()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return os.Chtimes(target, entry.Modified, entry.Modified)
+}
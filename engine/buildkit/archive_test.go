@@ -0,0 +1,127 @@
+package buildkit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteArchive(t *testing.T) {
+	writeTree := func(t *testing.T, mtime time.Time) string {
+		root := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "sub", "dir"), 0o755))
+		for name, content := range map[string]string{
+			"b.txt":             "b",
+			"a.txt":             "a",
+			"sub/dir/nested.sh": "#!/bin/sh",
+		} {
+			require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0o644))
+		}
+		require.NoError(t, os.Chmod(filepath.Join(root, "sub/dir/nested.sh"), 0o755))
+		require.NoError(t, os.Symlink("a.txt", filepath.Join(root, "link")))
+		for _, name := range []string{"a.txt", "b.txt", "sub/dir/nested.sh", "sub/dir", "sub"} {
+			require.NoError(t, os.Chtimes(filepath.Join(root, name), mtime, mtime))
+		}
+		return root
+	}
+
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveTgz, ArchiveZstd, ArchiveZip} {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			t.Run("reproducible", func(t *testing.T) {
+				var first, second bytes.Buffer
+				require.NoError(t, WriteArchive(&first, writeTree(t, time.Unix(1000, 0)), format, true))
+				require.NoError(t, WriteArchive(&second, writeTree(t, time.Unix(2000, 0)), format, true))
+				require.Equal(t, first.Bytes(), second.Bytes())
+			})
+
+			t.Run("not reproducible", func(t *testing.T) {
+				var first, second bytes.Buffer
+				require.NoError(t, WriteArchive(&first, writeTree(t, time.Unix(1000, 0)), format, false))
+				require.NoError(t, WriteArchive(&second, writeTree(t, time.Unix(2000, 0)), format, false))
+				require.NotEqual(t, first.Bytes(), second.Bytes())
+			})
+		})
+	}
+
+	t.Run("tar entries", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteArchive(&buf, writeTree(t, time.Unix(1000, 0)), ArchiveTar, true))
+
+		var names []string
+		tr := tar.NewReader(&buf)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			names = append(names, hdr.Name)
+			require.Equal(t, reproducibleModTime, hdr.ModTime.UTC())
+			switch hdr.Name {
+			case "link":
+				require.Equal(t, byte(tar.TypeSymlink), hdr.Typeflag)
+				require.Equal(t, "a.txt", hdr.Linkname)
+			case "sub/dir/nested.sh":
+				require.Equal(t, int64(0o755), hdr.Mode&0o777)
+			}
+		}
+		require.Equal(t, []string{"a.txt", "b.txt", "link", "sub/", "sub/dir/", "sub/dir/nested.sh"}, names)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		require.ErrorContains(t, WriteArchive(io.Discard, t.TempDir(), "rar", false), `unsupported archive format "rar"`)
+	})
+}
+
+func TestExtractZip(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		src := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "run.sh"), []byte("#!/bin/sh"), 0o755))
+		require.NoError(t, os.Symlink("sub/run.sh", filepath.Join(src, "link")))
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteArchive(&buf, src, ArchiveZip, false))
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+
+		dest := filepath.Join(t.TempDir(), "out")
+		require.NoError(t, ExtractZip(zr, dest))
+
+		content, err := os.ReadFile(filepath.Join(dest, "sub", "run.sh"))
+		require.NoError(t, err)
+		require.Equal(t, "#!/bin/sh", string(content))
+		info, err := os.Stat(filepath.Join(dest, "sub", "run.sh"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+		link, err := os.Readlink(filepath.Join(dest, "link"))
+		require.NoError(t, err)
+		require.Equal(t, "sub/run.sh", link)
+	})
+
+	t.Run("entries do not escape", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("../../escaped.txt")
+		require.NoError(t, err)
+		_, err = w.Write([]byte("nope"))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+
+		parent := t.TempDir()
+		dest := filepath.Join(parent, "a", "b")
+		require.NoError(t, ExtractZip(zr, dest))
+		require.NoFileExists(t, filepath.Join(parent, "escaped.txt"))
+		require.FileExists(t, filepath.Join(dest, "escaped.txt"))
+	})
+}
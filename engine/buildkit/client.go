@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/containerd/containerd/remotes/docker"
 	"github.com/dagger/dagger/auth"
 	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/engine/session"
@@ -43,12 +44,16 @@ const (
 )
 
 type Opts struct {
-	Worker                bkworker.Worker
-	SessionManager        *bksession.Manager
-	LLBSolver             *llbsolver.Solver
-	GenericSolver         *bksolver.Solver
-	SecretStore           bksecrets.SecretStore
-	AuthProvider          *auth.RegistryAuthProvider
+	Worker         bkworker.Worker
+	SessionManager *bksession.Manager
+	LLBSolver      *llbsolver.Solver
+	GenericSolver  *bksolver.Solver
+	SecretStore    bksecrets.SecretStore
+	AuthProvider   *auth.RegistryAuthProvider
+	RegistryHosts  docker.RegistryHosts
+	// RegistryMirrors are the mirrors configured in the engine config, applied
+	// by RegistryHosts, and by clients, applied to their own pulls only.
+	RegistryMirrors       *RegistryMirrors
 	PrivilegedExecEnabled bool
 	UpstreamCacheImports  []bkgw.CacheOptionsEntry
 	ProgSockPath          string
@@ -161,6 +166,8 @@ func (c *Client) Close() error {
 	}
 	c.cancel()
 
+	c.RegistryMirrors.Remove(c.ID())
+
 	c.job.Discard()
 	c.job.CloseProgress()
 
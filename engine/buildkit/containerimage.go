@@ -7,15 +7,19 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/containerd/containerd/platforms"
 	"github.com/dagger/dagger/engine"
 	bkcache "github.com/moby/buildkit/cache"
+	bkcacheconfig "github.com/moby/buildkit/cache/config"
 	bkclient "github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/exporter/containerimage/exptypes"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	bksession "github.com/moby/buildkit/session"
 	bksolverpb "github.com/moby/buildkit/solver/pb"
 	solverresult "github.com/moby/buildkit/solver/result"
+	"github.com/moby/buildkit/util/compression"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/vito/progrock"
 )
@@ -132,11 +136,39 @@ func (c *Client) ContainerImageToTarball(
 	}
 	defer cancel()
 
-	combinedResult, err := c.getContainerResult(ctx, inputByPlatform)
+	tmpDir, err := os.MkdirTemp("", "dagger-tarball")
 	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir for tarball export: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := c.exportContainerImageTarball(ctx, inputByPlatform, c.ID(), path.Join(tmpDir, fileName), opts); err != nil {
 		return nil, err
 	}
 
+	ctx, recorder := progrock.WithGroup(ctx, "container image to tarball")
+	pbDef, err := c.EngineContainerLocalImport(ctx, recorder, engineHostPlatform, tmpDir, nil, []string{fileName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import container tarball from engine container filesystem: %s", err)
+	}
+	return pbDef, nil
+}
+
+// exportContainerImageTarball exports the image tarball of the given
+// containers to the given session, which writes it to destPath in the engine
+// container's filesystem if it's the engine's own.
+func (c *Client) exportContainerImageTarball(
+	ctx context.Context,
+	inputByPlatform map[string]ContainerExport,
+	sessionID string,
+	destPath string,
+	opts map[string]string,
+) error {
+	combinedResult, err := c.getContainerResult(ctx, inputByPlatform)
+	if err != nil {
+		return err
+	}
+
 	exporterName := bkclient.ExporterDocker
 	if len(combinedResult.Refs) > 1 {
 		exporterName = bkclient.ExporterOCI
@@ -144,41 +176,91 @@ func (c *Client) ContainerImageToTarball(
 
 	exporter, err := c.Worker.Exporter(exporterName, c.SessionManager)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	expInstance, err := exporter.Resolve(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve exporter: %s", err)
+		return fmt.Errorf("failed to resolve exporter: %s", err)
 	}
 
-	tmpDir, err := os.MkdirTemp("", "dagger-tarball")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp dir for tarball export: %s", err)
-	}
-	defer os.RemoveAll(tmpDir)
-	destPath := path.Join(tmpDir, fileName)
-
 	ctx = engine.LocalExportOpts{
-		DestClientID: c.ID(),
+		DestClientID: sessionID,
 		Path:         destPath,
 		IsFileStream: true,
 	}.AppendToOutgoingContext(ctx)
 
-	_, descRef, err := expInstance.Export(ctx, combinedResult, c.ID())
+	_, descRef, err := expInstance.Export(ctx, combinedResult, sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to export: %s", err)
+		return fmt.Errorf("failed to export: %s", err)
 	}
 	if descRef != nil {
-		defer descRef.Release()
+		descRef.Release()
 	}
+	return nil
+}
 
-	ctx, recorder := progrock.WithGroup(ctx, "container image to tarball")
-	pbDef, err := c.EngineContainerLocalImport(ctx, recorder, engineHostPlatform, tmpDir, nil, []string{fileName})
+// ImageLayer describes a layer of a container's root filesystem as it would
+// appear in an exported image.
+type ImageLayer struct {
+	Descriptor  specs.Descriptor
+	Description string
+	CreatedAt   time.Time
+}
+
+// ImageLayers returns the layers the given root filesystem definition would
+// be exported as, compressing them if they haven't been already.
+func (c *Client) ImageLayers(ctx context.Context, def *bksolverpb.Definition) ([]ImageLayer, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to import container tarball from engine container filesystem: %s", err)
+		return nil, err
 	}
-	return pbDef, nil
+	defer cancel()
+
+	res, err := c.Solve(ctx, bkgw.SolveRequest{
+		Definition: def,
+		Evaluate:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cacheRes, err := ConvertToWorkerCacheResult(ctx, res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert result: %s", err)
+	}
+	ref, err := cacheRes.SingleRef()
+	if err != nil {
+		return nil, err
+	}
+	if ref == nil {
+		// scratch has no layers
+		return []ImageLayer{}, nil
+	}
+
+	ctx = withDescHandlerCacheOpts(ctx, ref)
+	remotes, err := ref.GetRemotes(ctx, true, bkcacheconfig.RefConfig{
+		Compression: compression.New(compression.Default),
+	}, false, bksession.NewGroup(c.ID()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get layers: %s", err)
+	}
+	if len(remotes) == 0 {
+		return nil, fmt.Errorf("no layers found")
+	}
+	descs := remotes[0].Descriptors
+
+	chain := ref.LayerChain()
+	defer chain.Release(context.TODO())
+
+	layers := make([]ImageLayer, len(descs))
+	for i, desc := range descs {
+		layers[i].Descriptor = desc
+		if len(chain) == len(descs) {
+			layers[i].Description = chain[i].GetDescription()
+			layers[i].CreatedAt = chain[i].GetCreatedAt()
+		}
+	}
+	return layers, nil
 }
 
 func (c *Client) getContainerResult(
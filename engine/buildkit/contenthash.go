@@ -0,0 +1,71 @@
+package buildkit
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/containerd/continuity/fs"
+	"github.com/moby/buildkit/cache/contenthash"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	bksession "github.com/moby/buildkit/session"
+	bksolverpb "github.com/moby/buildkit/solver/pb"
+	"github.com/opencontainers/go-digest"
+)
+
+// DirectoryDigest returns the content hash of the given directory of the
+// given definition, as computed by buildkit for copy cache keys: it covers
+// the names, contents, permissions and ownership of every file under it, but
+// not their timestamps.
+func (c *Client) DirectoryDigest(ctx context.Context, def *bksolverpb.Definition, dirPath string) (digest.Digest, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	ctx = withOutgoingContext(ctx)
+
+	res, err := c.Solve(ctx, bkgw.SolveRequest{Definition: def, Evaluate: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to solve: %s", err)
+	}
+	ref, err := res.SingleRef()
+	if err != nil {
+		return "", fmt.Errorf("failed to get single ref: %s", err)
+	}
+	if ref == nil {
+		// scratch
+		return digest.FromBytes(nil), nil
+	}
+	cacheRef, err := ref.CacheRef(ctx)
+	if err != nil {
+		return "", err
+	}
+	return contenthash.Checksum(ctx, cacheRef, dirPath, contenthash.ChecksumOpts{}, bksession.NewGroup(c.ID()))
+}
+
+// FileDigest returns the SHA-256 digest of the contents of the given file of
+// the given definition.
+func (c *Client) FileDigest(ctx context.Context, def *bksolverpb.Definition, filePath string) (digest.Digest, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	var dgst digest.Digest
+	err = c.WithMountedDef(ctx, def, func(root string) error {
+		mntFilePath, err := fs.RootPath(root, filePath)
+		if err != nil {
+			return fmt.Errorf("failed to get root path: %s", err)
+		}
+		f, err := os.Open(mntFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %s", err)
+		}
+		defer f.Close()
+		dgst, err = digest.SHA256.FromReader(f)
+		return err
+	})
+	return dgst, err
+}
@@ -0,0 +1,159 @@
+package buildkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/moby/buildkit/session/sshforward"
+	"google.golang.org/grpc/metadata"
+)
+
+// ContainerImageToDocker exports the given containers as an image tarball and
+// loads it into the Docker-compatible daemon listening on the given host
+// socket, as `docker load` would. The tarball is streamed to the daemon as
+// it's exported.
+func (c *Client) ContainerImageToDocker(
+	ctx context.Context,
+	socketID string,
+	inputByPlatform map[string]ContainerExport,
+	opts map[string]string,
+) error {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	if len(inputByPlatform) > 1 {
+		return errors.New("loading multi-platform images into docker is not supported")
+	}
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	sess, closeSession, err := c.newStreamSession(ctx, pw)
+	if err != nil {
+		return err
+	}
+	defer closeSession()
+
+	go func() {
+		pw.CloseWithError(c.exportContainerImageTarball(ctx, inputByPlatform, sess.ID(), "", opts))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://docker/images/load?quiet=1", pr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-tar")
+
+	resp, err := c.dockerClient(socketID).Do(req)
+	if err != nil {
+		return fmt.Errorf("docker load: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkDockerResponse(resp); err != nil {
+		return fmt.Errorf("docker load: %w", err)
+	}
+	return nil
+}
+
+// DockerImageStream returns the image tarball of the given image in the
+// Docker-compatible daemon listening on the given host socket, as `docker
+// save` would, streamed from the daemon. The caller must close it.
+func (c *Client) DockerImageStream(
+	ctx context.Context,
+	socketID string,
+	imageName string,
+) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker/images/"+url.PathEscape(imageName)+"/get", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.dockerClient(socketID).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker save: %w", err)
+	}
+
+	if err := checkDockerResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("docker save %s: %w", imageName, err)
+	}
+	return resp.Body, nil
+}
+
+// dockerClient returns an HTTP client that talks to the Docker API over the
+// given host socket, forwarded from the main client.
+func (c *Client) dockerClient(socketID string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return c.dialHostSocket(ctx, socketID)
+			},
+		},
+	}
+}
+
+// dialHostSocket opens a connection to the given host socket, proxied through
+// the main client's session.
+func (c *Client) dialHostSocket(ctx context.Context, socketID string) (net.Conn, error) {
+	ctx = metadata.AppendToOutgoingContext(context.WithoutCancel(ctx), sshforward.KeySSHID, socketID)
+	ctx, cancel := context.WithCancel(ctx)
+
+	stream, err := sshforward.NewSSHClient(c.MainClientCaller.Conn()).ForwardAgent(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to forward host socket: %w", err)
+	}
+
+	local, remote := net.Pipe()
+	go func() {
+		defer cancel()
+		sshforward.Copy(ctx, remote, stream, stream.CloseSend)
+	}()
+	return local, nil
+}
+
+// checkDockerResponse returns an error if the Docker API responded with an
+// error, either as a status code or as an error message in a JSON stream.
+func checkDockerResponse(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return errors.New(apiErr.Message)
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if resp.Header.Get("Content-Type") != "application/json" {
+		return nil
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+	}
+}
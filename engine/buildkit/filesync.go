@@ -26,6 +26,7 @@ import (
 	bkworker "github.com/moby/buildkit/worker"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/vito/progrock"
+	"golang.org/x/sys/unix"
 )
 
 func (c *Client) LocalImport(
@@ -35,6 +36,8 @@ func (c *Client) LocalImport(
 	srcPath string,
 	excludePatterns []string,
 	includePatterns []string,
+	gitIgnore bool,
+	dockerIgnore bool,
 ) (*bksolverpb.Definition, error) {
 	srcPath = path.Clean(srcPath)
 	if srcPath == ".." || strings.HasPrefix(srcPath, "../") {
@@ -54,6 +57,19 @@ func (c *Client) LocalImport(
 	localName := fmt.Sprintf("upload %s from %s (client id: %s)", srcPath, clientMetadata.ClientHostname, clientMetadata.ClientID)
 	if len(excludePatterns) > 0 {
 		localName += fmt.Sprintf(" (exclude: %s)", strings.Join(excludePatterns, ", "))
+	}
+	// the ignore files are read by the client, so that what they exclude never
+	// leaves it
+	excludePatterns = append([]string{}, excludePatterns...)
+	if gitIgnore {
+		localName += " (gitignore)"
+		excludePatterns = append(excludePatterns, engine.LocalImportGitIgnorePattern)
+	}
+	if dockerIgnore {
+		localName += " (dockerignore)"
+		excludePatterns = append(excludePatterns, engine.LocalImportDockerIgnorePattern)
+	}
+	if len(excludePatterns) > 0 {
 		localOpts = append(localOpts, llb.ExcludePatterns(excludePatterns))
 	}
 	if len(includePatterns) > 0 {
@@ -83,8 +99,16 @@ func (c *Client) LocalImport(
 
 	RecordVertexes(recorder, copyPB)
 
+	return c.DefToBlob(ctx, copyPB)
+}
+
+// DefToBlob solves the given definition and returns a new one referencing
+// its result by content digest only. It's used to keep what's in the original
+// definition, like uploaded files or secrets, out of the definitions and IDs
+// that are derived from the result.
+func (c *Client) DefToBlob(ctx context.Context, pbDef *bksolverpb.Definition) (*bksolverpb.Definition, error) {
 	res, err := c.Solve(ctx, bkgw.SolveRequest{
-		Definition: copyPB,
+		Definition: pbDef,
 		Evaluate:   true,
 	})
 	if err != nil {
@@ -172,7 +196,40 @@ func (c *Client) EngineContainerLocalImport(
 		ClientHostname: hostname,
 	})
 
-	return c.LocalImport(ctx, recorder, platform, srcPath, excludePatterns, includePatterns)
+	return c.LocalImport(ctx, recorder, platform, srcPath, excludePatterns, includePatterns, false, false)
+}
+
+// SymlinkName is the name of the symlink in definitions returned by
+// EngineContainerSymlink.
+const SymlinkName = "link"
+
+// EngineContainerSymlink returns a definition containing a symlink named
+// SymlinkName pointing to the given target.
+//
+// Buildkit has no file action to create symlinks, so it is created in the
+// engine container and imported from there, with fixed timestamps so that
+// the same target always results in the same definition.
+func (c *Client) EngineContainerSymlink(ctx context.Context, platform specs.Platform, target string) (*bksolverpb.Definition, error) {
+	tmpDir, err := os.MkdirTemp("", "dagger-symlink")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir for symlink: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	linkPath := filepath.Join(tmpDir, SymlinkName)
+	if err := os.Symlink(target, linkPath); err != nil {
+		return nil, fmt.Errorf("failed to create symlink: %s", err)
+	}
+	epoch := []unix.Timespec{{}, {}}
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, linkPath, epoch, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return nil, fmt.Errorf("failed to set symlink timestamps: %s", err)
+	}
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, tmpDir, epoch, 0); err != nil {
+		return nil, fmt.Errorf("failed to set symlink timestamps: %s", err)
+	}
+
+	ctx, recorder := progrock.WithGroup(ctx, "create symlink")
+	return c.EngineContainerLocalImport(ctx, recorder, platform, tmpDir, nil, []string{SymlinkName})
 }
 
 func (c *Client) ReadCallerHostFile(ctx context.Context, path string) ([]byte, error) {
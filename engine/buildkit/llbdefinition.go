@@ -211,6 +211,11 @@ func (dag *OpDAG) BlobDependencies() (map[digest.Digest]*ocispecs.Descriptor, er
 		if !ok {
 			return nil
 		}
+		if blobOp.GetAttrs()[blob.FileRefAttr] != "" {
+			// plain file blobs aren't in the content store, they're pulled
+			// from their registry again if they're pruned
+			return nil
+		}
 		desc, err := blobOp.OCIDescriptor()
 		if err != nil {
 			return fmt.Errorf("failed to get blob descriptor: %w", err)
@@ -0,0 +1,119 @@
+package buildkit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	bksession "github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/util/push"
+	"github.com/moby/buildkit/util/resolver"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// registryResolver returns a resolver for the given ref that authenticates
+// through this client's session, so credentials added with withRegistryAuth
+// are used before falling back to the ones configured on the client host.
+func (c *Client) registryResolver(ref string, scope string) remotes.Resolver {
+	return resolver.DefaultPool.GetResolver(c.RegistryHosts, ref, scope, c.SessionManager, bksession.NewGroup(c.ID()))
+}
+
+// ResolveRegistryDescriptor resolves the given ref to the descriptor of its
+// top-level manifest (or index) in the registry.
+func (c *Client) ResolveRegistryDescriptor(ctx context.Context, ref string) (specs.Descriptor, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+	defer cancel()
+
+	_, desc, err := c.registryResolver(ref, "pull").Resolve(ctx, ref)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+// FetchRegistryBlob reads the content of the given descriptor from the
+// repository of ref.
+func (c *Client) FetchRegistryBlob(ctx context.Context, ref string, desc specs.Descriptor) ([]byte, error) {
+	var blob bytes.Buffer
+	if err := c.FetchRegistryContent(ctx, ref, desc, &blob); err != nil {
+		return nil, err
+	}
+	return blob.Bytes(), nil
+}
+
+// FetchRegistryContent writes the content of the given descriptor from the
+// repository of ref to w, failing if it doesn't match the descriptor.
+func (c *Client) FetchRegistryContent(ctx context.Context, ref string, desc specs.Descriptor, w io.Writer) error {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	fetcher, err := c.registryResolver(ref, "pull").Fetcher(ctx, ref)
+	if err != nil {
+		return err
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	verifier := desc.Digest.Verifier()
+	n, err := io.Copy(io.MultiWriter(w, verifier), io.LimitReader(rc, desc.Size+1))
+	if err != nil {
+		return err
+	}
+	if n != desc.Size {
+		return fmt.Errorf("blob %s: expected %d bytes, got %d", desc.Digest, desc.Size, n)
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("blob %s: digest mismatch", desc.Digest)
+	}
+	return nil
+}
+
+// PushRegistryBlob uploads the given content to the repository of ref. If the
+// descriptor is a manifest and ref has a tag, the tag is updated to point to
+// it.
+func (c *Client) PushRegistryBlob(ctx context.Context, ref string, desc specs.Descriptor, blob []byte) error {
+	return c.PushRegistryContent(ctx, ref, desc, bytes.NewReader(blob))
+}
+
+// PushRegistryContent is like PushRegistryBlob, with the content read from r.
+func (c *Client) PushRegistryContent(ctx context.Context, ref string, desc specs.Descriptor, r io.Reader) error {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	pusher, err := push.Pusher(ctx, c.registryResolver(ref, "push,pull"), ref)
+	if err != nil {
+		return err
+	}
+	w, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	defer w.Close()
+
+	if err := content.Copy(ctx, w, r, desc.Size, desc.Digest); err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package buildkit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/platforms"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/distribution/reference"
+	"github.com/moby/buildkit/identity"
+	bksession "github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/util/resolver"
+	resolverconfig "github.com/moby/buildkit/util/resolver/config"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// RegistryMirror is a mirror to pull container images from instead of the
+// registry they're addressed to.
+type RegistryMirror struct {
+	// The mirrored registry, e.g. docker.io.
+	Registry string
+
+	// The address of the mirror, optionally with a path prefix, e.g.
+	// mirror.example.com/dockerhub.
+	Mirror string
+
+	// Whether to skip verifying the mirror's TLS certificate, and to fall back
+	// to plain HTTP.
+	Insecure bool
+
+	// PEM-encoded certificates of authorities to trust when connecting to the
+	// mirror, in addition to the system ones.
+	CACerts string
+
+	// Whether the mirror is configured in the engine config, as opposed to by
+	// a client.
+	Engine bool
+}
+
+// RegistryMirrors resolves the hosts to pull and push container images from
+// for each registry, as configured in the engine config, along with the
+// mirrors configured by clients.
+//
+// The mirrors configured by the clients of a server only apply to the image
+// pulls of those clients, through the hosts returned by SessionHosts: the
+// engine-wide hosts returned by Hosts only ever include the engine config's.
+// They're removed once their server is closed.
+type RegistryMirrors struct {
+	config   map[string]resolverconfig.RegistryConfig
+	certsDir string
+
+	mu      sync.RWMutex
+	session []*sessionRegistryMirror
+}
+
+type sessionRegistryMirror struct {
+	RegistryMirror
+	serverID string
+	caFile   string
+}
+
+// NewRegistryMirrors returns the registry mirrors of the given engine
+// registry config. The CA certificates of the mirrors configured by clients
+// are written to certsDir.
+func NewRegistryMirrors(config map[string]resolverconfig.RegistryConfig, certsDir string) *RegistryMirrors {
+	return &RegistryMirrors{
+		config:   config,
+		certsDir: certsDir,
+	}
+}
+
+// Hosts resolves the hosts to pull and push images from for the given
+// registry, with the mirrors configured in the engine config first.
+func (mirrors *RegistryMirrors) Hosts(host string) ([]docker.RegistryHost, error) {
+	return resolver.NewRegistryConfig(mirrors.config)(host)
+}
+
+// SessionHosts returns a function resolving the hosts to pull images from for
+// a registry like Hosts, with the mirrors configured for the given server
+// first.
+func (mirrors *RegistryMirrors) SessionHosts(serverID string) docker.RegistryHosts {
+	return resolver.NewRegistryConfig(mirrors.registryConfig(serverID))
+}
+
+// mirrored returns whether a mirror of the given registry is configured for
+// the given server.
+func (mirrors *RegistryMirrors) mirrored(serverID, registry string) bool {
+	if mirrors == nil {
+		return false
+	}
+
+	mirrors.mu.RLock()
+	defer mirrors.mu.RUnlock()
+	for _, m := range mirrors.session {
+		if m.serverID == serverID && m.Registry == registry {
+			return true
+		}
+	}
+	return false
+}
+
+// registryConfig returns the engine's registry config, with the mirrors
+// configured for the given server added to it.
+func (mirrors *RegistryMirrors) registryConfig(serverID string) map[string]resolverconfig.RegistryConfig {
+	mirrors.mu.RLock()
+	defer mirrors.mu.RUnlock()
+
+	config := make(map[string]resolverconfig.RegistryConfig, len(mirrors.config))
+	for host, regCfg := range mirrors.config {
+		config[host] = regCfg
+	}
+
+	sessionMirrors := map[string][]string{}
+	for _, m := range mirrors.session {
+		if m.serverID != serverID {
+			continue
+		}
+		sessionMirrors[m.Registry] = append(sessionMirrors[m.Registry], m.Mirror)
+
+		mirrorHost, _, _ := strings.Cut(m.Mirror, "/")
+		mirrorCfg := config[mirrorHost]
+		if m.Insecure {
+			insecure := true
+			mirrorCfg.Insecure = &insecure
+			mirrorCfg.PlainHTTP = &insecure
+		}
+		if m.caFile != "" {
+			mirrorCfg.RootCAs = append(append([]string{}, mirrorCfg.RootCAs...), m.caFile)
+		}
+		config[mirrorHost] = mirrorCfg
+	}
+	for registry, hosts := range sessionMirrors {
+		regCfg := config[registry]
+		regCfg.Mirrors = append(hosts, regCfg.Mirrors...)
+		config[registry] = regCfg
+	}
+	return config
+}
+
+// Add configures a mirror for the rest of the given server's lifetime.
+func (mirrors *RegistryMirrors) Add(serverID string, mirror RegistryMirror) error {
+	if mirrors == nil {
+		return fmt.Errorf("registry mirrors are not supported")
+	}
+
+	mirror.Registry = normalizeRegistry(mirror.Registry)
+	mirror.Mirror = strings.TrimSuffix(mirror.Mirror, "/")
+	mirror.Engine = false
+	if mirror.Registry == "" {
+		return fmt.Errorf("registry must not be empty")
+	}
+	if _, err := reference.ParseNormalizedNamed(mirror.Mirror + "/library/test"); err != nil {
+		return fmt.Errorf("invalid mirror %q: %w", mirror.Mirror, err)
+	}
+
+	mirrors.mu.Lock()
+	defer mirrors.mu.Unlock()
+
+	for _, m := range mirrors.session {
+		if m.serverID == serverID && m.Registry == mirror.Registry && m.Mirror == mirror.Mirror {
+			return nil
+		}
+	}
+
+	sessionMirror := &sessionRegistryMirror{
+		RegistryMirror: mirror,
+		serverID:       serverID,
+	}
+	if mirror.CACerts != "" {
+		if err := os.MkdirAll(mirrors.certsDir, 0o700); err != nil {
+			return fmt.Errorf("failed to create registry mirror certs dir: %w", err)
+		}
+		sessionMirror.caFile = filepath.Join(mirrors.certsDir, serverID+"-"+identity.NewID()+".crt")
+		if err := os.WriteFile(sessionMirror.caFile, []byte(mirror.CACerts), 0o600); err != nil {
+			return fmt.Errorf("failed to write registry mirror CA certs: %w", err)
+		}
+	}
+	mirrors.session = append(mirrors.session, sessionMirror)
+	return nil
+}
+
+// Remove removes the mirrors configured for the given server.
+func (mirrors *RegistryMirrors) Remove(serverID string) {
+	if mirrors == nil {
+		return
+	}
+
+	mirrors.mu.Lock()
+	defer mirrors.mu.Unlock()
+
+	kept := mirrors.session[:0]
+	for _, m := range mirrors.session {
+		if m.serverID != serverID {
+			kept = append(kept, m)
+			continue
+		}
+		if m.caFile != "" {
+			os.Remove(m.caFile)
+		}
+	}
+	mirrors.session = kept
+}
+
+// List returns the mirrors configured in the engine config, followed by the
+// ones configured for the given server, without their CA certificates.
+func (mirrors *RegistryMirrors) List(serverID string) []RegistryMirror {
+	list := []RegistryMirror{}
+	if mirrors == nil {
+		return list
+	}
+
+	registries := make([]string, 0, len(mirrors.config))
+	for registry := range mirrors.config {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+	for _, registry := range registries {
+		for _, mirror := range mirrors.config[registry].Mirrors {
+			mirrorHost, _, _ := strings.Cut(mirror, "/")
+			mirrorCfg := mirrors.config[mirrorHost]
+			list = append(list, RegistryMirror{
+				Registry: registry,
+				Mirror:   mirror,
+				Insecure: mirrorCfg.Insecure != nil && *mirrorCfg.Insecure,
+				Engine:   true,
+			})
+		}
+	}
+
+	mirrors.mu.RLock()
+	defer mirrors.mu.RUnlock()
+	for _, m := range mirrors.session {
+		if m.serverID != serverID {
+			continue
+		}
+		mirror := m.RegistryMirror
+		mirror.CACerts = ""
+		list = append(list, mirror)
+	}
+	return list
+}
+
+// normalizeRegistry returns the domain image references to the given
+// registry are normalized to, e.g. docker.io for index.docker.io.
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimSuffix(registry, "/")
+	refName, err := reference.ParseNormalizedNamed(registry + "/library/test")
+	if err != nil {
+		return registry
+	}
+	return reference.Domain(refName)
+}
+
+// AddRegistryMirror configures a registry mirror until the client is closed.
+func (c *Client) AddRegistryMirror(mirror RegistryMirror) error {
+	return c.RegistryMirrors.Add(c.ID(), mirror)
+}
+
+// ListRegistryMirrors returns the mirrors configured in the engine config,
+// followed by the ones configured by the client.
+func (c *Client) ListRegistryMirrors() []RegistryMirror {
+	return c.RegistryMirrors.List(c.ID())
+}
+
+// HasRegistryMirror returns whether the client configured a mirror of the
+// registry of the given image reference.
+func (c *Client) HasRegistryMirror(ref string) bool {
+	refName, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return false
+	}
+	return c.RegistryMirrors.mirrored(c.ID(), reference.Domain(refName))
+}
+
+// mirrorResolver returns a resolver for the given ref that pulls through the
+// mirrors configured by the client, and authenticates through its session.
+//
+// The resolver pool caches the hosts of each registry per ref name and scope,
+// so the scope is the client's own, to never share its mirrors with other
+// clients.
+func (c *Client) mirrorResolver(ref string) remotes.Resolver {
+	return resolver.DefaultPool.GetResolver(c.RegistryMirrors.SessionHosts(c.ID()), ref, "pull:"+c.ID(), c.SessionManager, bksession.NewGroup(c.ID()))
+}
+
+// ResolveMirroredImage resolves the given ref to the descriptor of its
+// top-level manifest (or index), through the mirrors configured by the client.
+func (c *Client) ResolveMirroredImage(ctx context.Context, ref string) (specs.Descriptor, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+	defer cancel()
+
+	refName, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+	ref = reference.TagNameOnly(refName).String()
+
+	_, desc, err := c.mirrorResolver(ref).Resolve(ctx, ref)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+// PullMirroredImage fetches the image of the given ref for the given platform
+// into the store, through the mirrors configured by the client, and returns
+// the descriptor of its top-level manifest (or index). The content is only
+// kept as long as the lease of the context.
+func (c *Client) PullMirroredImage(ctx context.Context, ref string, platform specs.Platform, store content.Store) (specs.Descriptor, error) {
+	ctx, cancel, err := c.withClientCloseCancel(ctx)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+	defer cancel()
+
+	rslvr := c.mirrorResolver(ref)
+	name, desc, err := rslvr.Resolve(ctx, ref)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+	fetcher, err := rslvr.Fetcher(ctx, name)
+	if err != nil {
+		return specs.Descriptor{}, err
+	}
+
+	matcher := platforms.Only(platform)
+	children := images.LimitManifests(images.FilterPlatforms(images.ChildrenHandler(store), matcher), matcher, 1)
+	if err := images.Dispatch(ctx, images.Handlers(remotes.FetchHandler(store, fetcher), children), nil, desc); err != nil {
+		return specs.Descriptor{}, fmt.Errorf("pull %s: %w", ref, err)
+	}
+	return desc, nil
+}
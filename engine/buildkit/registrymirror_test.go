@@ -0,0 +1,112 @@
+package buildkit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/containerd/containerd/remotes/docker"
+	resolverconfig "github.com/moby/buildkit/util/resolver/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryMirrors(t *testing.T) {
+	certsDir := t.TempDir()
+	mirrors := NewRegistryMirrors(map[string]resolverconfig.RegistryConfig{
+		"docker.io": {Mirrors: []string{"engine-mirror.example.com"}},
+	}, certsDir)
+
+	require.NoError(t, mirrors.Add("server1", RegistryMirror{
+		Registry: "index.docker.io",
+		Mirror:   "mirror.example.com/dockerhub/",
+		Insecure: true,
+	}))
+	require.NoError(t, mirrors.Add("server1", RegistryMirror{Registry: "docker.io", Mirror: "mirror.example.com/dockerhub"}))
+	require.NoError(t, mirrors.Add("server1", RegistryMirror{
+		Registry: "docker.io",
+		Mirror:   "other-mirror.example.com",
+		CACerts:  "-----BEGIN CERTIFICATE-----\n",
+	}))
+	require.Error(t, mirrors.Add("server1", RegistryMirror{Registry: "docker.io", Mirror: "not a mirror"}))
+
+	cfg := mirrors.registryConfig("server1")
+	require.Equal(t, []string{
+		"mirror.example.com/dockerhub",
+		"other-mirror.example.com",
+		"engine-mirror.example.com",
+	}, cfg["docker.io"].Mirrors)
+	require.True(t, *cfg["mirror.example.com"].Insecure)
+	require.Len(t, cfg["other-mirror.example.com"].RootCAs, 1)
+	caFile := cfg["other-mirror.example.com"].RootCAs[0]
+	require.FileExists(t, caFile)
+
+	hosts, err := mirrors.SessionHosts("server1")("docker.io")
+	require.NoError(t, err)
+	require.Len(t, hosts, 4)
+	require.Equal(t, "mirror.example.com", hosts[0].Host)
+	require.Equal(t, "/v2/dockerhub", hosts[0].Path)
+	require.Equal(t, "registry-1.docker.io", hosts[3].Host)
+
+	require.Equal(t, []RegistryMirror{
+		{Registry: "docker.io", Mirror: "engine-mirror.example.com", Engine: true},
+		{Registry: "docker.io", Mirror: "mirror.example.com/dockerhub", Insecure: true},
+		{Registry: "docker.io", Mirror: "other-mirror.example.com"},
+	}, mirrors.List("server1"))
+
+	mirrors.Remove("server1")
+	_, err = os.Stat(caFile)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	require.Equal(t, []string{
+		"engine-mirror.example.com",
+	}, mirrors.registryConfig("server1")["docker.io"].Mirrors)
+}
+
+func TestRegistryMirrorsSessions(t *testing.T) {
+	mirrors := NewRegistryMirrors(map[string]resolverconfig.RegistryConfig{
+		"docker.io": {Mirrors: []string{"engine-mirror.example.com"}},
+	}, t.TempDir())
+
+	require.NoError(t, mirrors.Add("server1", RegistryMirror{
+		Registry: "docker.io",
+		Mirror:   "mirror1.example.com",
+		Insecure: true,
+	}))
+	require.NoError(t, mirrors.Add("server2", RegistryMirror{
+		Registry: "docker.io",
+		Mirror:   "mirror2.example.com",
+	}))
+
+	for _, tc := range []struct {
+		serverID string
+		hosts    []string
+	}{
+		{"server1", []string{"mirror1.example.com", "engine-mirror.example.com", "registry-1.docker.io"}},
+		{"server2", []string{"mirror2.example.com", "engine-mirror.example.com", "registry-1.docker.io"}},
+		{"server3", []string{"engine-mirror.example.com", "registry-1.docker.io"}},
+	} {
+		require.Equal(t, tc.hosts, hostNames(t, mirrors.SessionHosts(tc.serverID), "docker.io"), tc.serverID)
+
+		_, insecure := mirrors.registryConfig(tc.serverID)["mirror1.example.com"]
+		require.Equal(t, tc.serverID == "server1", insecure, tc.serverID)
+
+		require.Equal(t, tc.serverID != "server3", mirrors.mirrored(tc.serverID, "docker.io"), tc.serverID)
+	}
+
+	// the engine-wide hosts never include the mirrors of a session
+	require.Equal(t, []string{"engine-mirror.example.com", "registry-1.docker.io"}, hostNames(t, mirrors.Hosts, "docker.io"))
+
+	mirrors.Remove("server2")
+	require.Equal(t,
+		[]string{"mirror1.example.com", "engine-mirror.example.com", "registry-1.docker.io"},
+		hostNames(t, mirrors.SessionHosts("server1"), "docker.io"))
+}
+
+func hostNames(t *testing.T, hosts docker.RegistryHosts, registry string) []string {
+	t.Helper()
+	resolved, err := hosts(registry)
+	require.NoError(t, err)
+	names := make([]string, len(resolved))
+	for i, h := range resolved {
+		names[i] = h.Host
+	}
+	return names
+}
@@ -11,8 +11,10 @@ import (
 	"github.com/moby/buildkit/identity"
 	bksession "github.com/moby/buildkit/session"
 	sessioncontent "github.com/moby/buildkit/session/content"
+	"github.com/moby/buildkit/session/filesync"
 	"github.com/moby/buildkit/session/secrets/secretsprovider"
 	"github.com/moby/buildkit/util/bklog"
+	"google.golang.org/grpc"
 )
 
 // OCIStoreName is the name of the OCI content store used for OCI tarball
@@ -35,6 +37,15 @@ func (c *Client) newSession(ctx context.Context) (*bksession.Session, error) {
 		"oci:" + OCIStoreName: c.Worker.ContentStore(),
 	}))
 
+	// this ctx is okay because it's from the "main client" caller, so if it's canceled
+	// then we want to shutdown anyways
+	c.runSession(ctx, sess)
+	return sess, nil
+}
+
+// runSession connects the given session to the session manager, in the
+// background until ctx is canceled.
+func (c *Client) runSession(ctx context.Context, sess *bksession.Session) {
 	clientConn, serverConn := net.Pipe()
 	dialer := func(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) { // nolint: unparam
 		go func() {
@@ -54,8 +65,6 @@ func (c *Client) newSession(ctx context.Context) (*bksession.Session, error) {
 	go func() {
 		defer clientConn.Close()
 		defer sess.Close()
-		// this ctx is okay because it's from the "main client" caller, so if it's canceled
-		// then we want to shutdown anyways
 		err := sess.Run(ctx, dialer)
 		if err != nil {
 			lg := bklog.G(ctx).WithError(err)
@@ -66,7 +75,46 @@ func (c *Client) newSession(ctx context.Context) (*bksession.Session, error) {
 			}
 		}
 	}()
-	return sess, nil
+}
+
+// newStreamSession returns a session that writes any file exported to it to
+// w, along with a function to close it. The session isn't tied to the main
+// client's, so it's closed once the export is done rather than with it.
+func (c *Client) newStreamSession(ctx context.Context, w io.Writer) (*bksession.Session, func(), error) {
+	sess, err := bksession.NewSession(ctx, identity.NewID(), "")
+	if err != nil {
+		return nil, nil, err
+	}
+	sess.Allow(&streamTarget{w: w})
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.runSession(ctx, sess)
+	return sess, cancel, nil
+}
+
+// streamTarget receives a file exported to a session and writes it to w as
+// it's received.
+type streamTarget struct {
+	w io.Writer
+}
+
+func (t *streamTarget) Register(server *grpc.Server) {
+	filesync.RegisterFileSendServer(server, t)
+}
+
+func (t *streamTarget) DiffCopy(stream filesync.FileSend_DiffCopyServer) error {
+	for {
+		msg := filesync.BytesMessage{}
+		if err := stream.RecvMsg(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if _, err := t.w.Write(msg.Data); err != nil {
+			return err
+		}
+	}
 }
 
 func (c *Client) GetSessionCaller(ctx context.Context, clientID string) (bksession.Caller, error) {
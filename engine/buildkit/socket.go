@@ -35,3 +35,12 @@ func (p *socketProxy) ForwardAgent(stream sshforward.SSH_ForwardAgentServer) err
 	}
 	return proxyStream[sshforward.BytesMessage](ctx, forwardAgentClient, stream)
 }
+
+// MountSSHSocket mounts the main client's SSH agent socket with the given ID
+// at a temporary path, returning it along with a function unmounting it.
+func (c *Client) MountSSHSocket(ctx context.Context, id string) (string, func() error, error) {
+	return sshforward.MountSSHSocket(ctx, c.MainClientCaller, sshforward.SocketOpt{
+		ID:   id,
+		Mode: 0o700,
+	})
+}
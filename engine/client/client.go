@@ -23,6 +23,7 @@ import (
 	"github.com/cenkalti/backoff/v4"
 
 	"github.com/docker/cli/cli/config"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 	"github.com/google/uuid"
 	controlapi "github.com/moby/buildkit/api/services/control"
 	bkclient "github.com/moby/buildkit/client"
@@ -85,6 +86,10 @@ type Client struct {
 
 	Recorder *progrock.Recorder
 
+	// LocalImports records the host paths imported by the engine through this
+	// client.
+	LocalImports *LocalImports
+
 	httpClient *http.Client
 	bkClient   *bkclient.Client
 	bkSession  *bksession.Session
@@ -104,7 +109,7 @@ type Client struct {
 }
 
 func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context, rerr error) {
-	c := &Client{Params: params}
+	c := &Client{Params: params, LocalImports: &LocalImports{}}
 	if c.SecretToken == "" {
 		c.SecretToken = uuid.New().String()
 	}
@@ -246,7 +251,7 @@ func Connect(ctx context.Context, params Params) (_ *Client, _ context.Context,
 
 	// filesync
 	if !c.DisableHostRW {
-		bkSession.Allow(AnyDirSource{})
+		bkSession.Allow(AnyDirSource{Imports: c.LocalImports})
 		bkSession.Allow(AnyDirTarget{})
 	}
 
@@ -554,9 +559,10 @@ func (c *Client) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	proxyReq := &http.Request{
 		Method: r.Method,
 		URL: &url.URL{
-			Scheme: "http",
-			Host:   "dagger",
-			Path:   r.URL.Path,
+			Scheme:   "http",
+			Host:     "dagger",
+			Path:     r.URL.Path,
+			RawQuery: r.URL.RawQuery,
 		},
 		Header: r.Header,
 		Body:   r.Body,
@@ -585,7 +591,10 @@ func (c *Client) Dagger() *dagger.Client {
 }
 
 // Local dir imports
-type AnyDirSource struct{}
+type AnyDirSource struct {
+	// Imports, if set, records every import served.
+	Imports *LocalImports
+}
 
 func (s AnyDirSource) Register(server *grpc.Server) {
 	filesync.RegisterFileSyncServer(server, s)
@@ -600,6 +609,7 @@ func (s AnyDirSource) DiffCopy(stream filesync.FileSync_DiffCopyServer) error {
 	if err != nil {
 		return fmt.Errorf("get local import opts: %w", err)
 	}
+	s.Imports.record(*opts)
 
 	if opts.ReadSingleFileOnly {
 		// just stream the file bytes to the caller
@@ -619,11 +629,32 @@ func (s AnyDirSource) DiffCopy(stream filesync.FileSync_DiffCopyServer) error {
 	if err != nil {
 		return err
 	}
+	excludePatterns := opts.ExcludePatterns
+	if opts.DockerIgnore {
+		dockerIgnorePatterns, err := readDockerIgnore(opts.Path)
+		if err != nil {
+			return err
+		}
+		excludePatterns = append(excludePatterns, dockerIgnorePatterns...)
+	}
+	var gitIgnore gitignore.Matcher
+	if opts.GitIgnore {
+		gitIgnore, err = readGitIgnore(opts.Path)
+		if err != nil {
+			return fmt.Errorf("read gitignore: %w", err)
+		}
+	}
 	fs, err = fsutil.NewFilterFS(fs, &fsutil.FilterOpt{
 		IncludePatterns: opts.IncludePatterns,
-		ExcludePatterns: opts.ExcludePatterns,
+		ExcludePatterns: excludePatterns,
 		FollowPaths:     opts.FollowPaths,
 		Map: func(p string, st *fstypes.Stat) fsutil.MapResult {
+			if gitIgnore != nil && gitIgnore.Match(strings.Split(p, "/"), st.IsDir()) {
+				if st.IsDir() {
+					return fsutil.MapResultSkipDir
+				}
+				return fsutil.MapResultExclude
+			}
 			st.Uid = 0
 			st.Gid = 0
 			return fsutil.MapResultKeep
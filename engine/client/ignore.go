@@ -0,0 +1,217 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// readDockerIgnore returns the patterns of the .dockerignore file of the given
+// directory, if any.
+func readDockerIgnore(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	patterns, err := ignorefile.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .dockerignore: %w", err)
+	}
+	return patterns, nil
+}
+
+// readGitIgnore returns a matcher for the paths of the given directory that
+// git ignores, according to the .git/info/exclude of its repository and to
+// the .gitignore files of the repository's directories, from its root down to
+// the given directory's subdirectories. The .git directory of the given
+// directory is always ignored.
+//
+// Ignored subdirectories aren't traversed, so that e.g. the .gitignore files
+// of dependencies under an ignored node_modules don't need to be read.
+func readGitIgnore(dir string) (gitignore.Matcher, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	root, gitDir, err := findGitRepo(dir)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	var prefix []string
+	if rel != "." {
+		prefix = strings.Split(filepath.ToSlash(rel), "/")
+	}
+
+	patterns := []gitignore.Pattern{gitignore.ParsePattern("/.git", prefix)}
+
+	if gitDir != "" {
+		excludePatterns, err := readGitIgnoreFile(filepath.Join(gitDir, "info", "exclude"), nil)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, excludePatterns...)
+	}
+
+	// the .gitignore files of the directory's parents, up to the repository's
+	// root, apply to it too
+	for i := range prefix {
+		domain := prefix[:i]
+		parentDir := filepath.Join(append([]string{root}, domain...)...)
+		filePatterns, err := readGitIgnoreFile(filepath.Join(parentDir, ".gitignore"), domain)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, filePatterns...)
+	}
+
+	var walk func(domain []string) error
+	walk = func(domain []string) error {
+		subdir := filepath.Join(append([]string{root}, domain...)...)
+
+		filePatterns, err := readGitIgnoreFile(filepath.Join(subdir, ".gitignore"), domain)
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, filePatterns...)
+
+		entries, err := os.ReadDir(subdir)
+		if err != nil {
+			return err
+		}
+		matcher := gitignore.NewMatcher(patterns)
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			entryPath := append(append([]string{}, domain...), entry.Name())
+			if matcher.Match(entryPath, true) {
+				continue
+			}
+			if err := walk(entryPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(prefix); err != nil {
+		return nil, err
+	}
+
+	return prefixMatcher{
+		prefix:  prefix,
+		matcher: gitignore.NewMatcher(patterns),
+	}, nil
+}
+
+// findGitRepo returns the root of the git repository holding the given
+// directory, along with its git directory, or the directory itself and no git
+// directory if it's not in a repository.
+func findGitRepo(dir string) (string, string, error) {
+	for root := dir; ; {
+		gitPath := filepath.Join(root, ".git")
+		info, err := os.Stat(gitPath)
+		switch {
+		case err == nil && info.IsDir():
+			return root, gitPath, nil
+		case err == nil:
+			// a worktree or a submodule, whose .git file points to its git
+			// directory
+			gitDir, err := readGitDirFile(gitPath)
+			return root, gitDir, err
+		case !errors.Is(err, os.ErrNotExist):
+			return "", "", err
+		}
+
+		parent := filepath.Dir(root)
+		if parent == root {
+			return dir, "", nil
+		}
+		root = parent
+	}
+}
+
+// readGitDirFile returns the git directory a .git file points to, or the
+// common git directory of its worktree if it's a worktree's, which holds its
+// info/exclude file.
+func readGitDirFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("invalid .git file %s", path)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+
+	commonDir, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	switch {
+	case err == nil:
+		common := strings.TrimSpace(string(commonDir))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		return common, nil
+	case errors.Is(err, os.ErrNotExist):
+		return gitDir, nil
+	default:
+		return "", err
+	}
+}
+
+// prefixMatcher matches paths relative to a subdirectory of a repository
+// against patterns relative to the repository's root.
+type prefixMatcher struct {
+	prefix  []string
+	matcher gitignore.Matcher
+}
+
+func (m prefixMatcher) Match(path []string, isDir bool) bool {
+	return m.matcher.Match(append(append([]string{}, m.prefix...), path...), isDir)
+}
+
+// readGitIgnoreFile returns the patterns of the given gitignore file, scoped to
+// the directory at the given path.
+func readGitIgnoreFile(path string, domain []string) ([]gitignore.Pattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return patterns, nil
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadGitIgnore(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":                "# comment\n\nnode_modules/\n*.log\n",
+		".git/info/exclude":         "local.txt\n",
+		"sub/.gitignore":            "generated.go\n!keep.log\n",
+		"node_modules/dep/index.js": "",
+		"sub/generated.go":          "",
+		"unrelated/generated.go":    "",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	matcher, err := readGitIgnore(dir)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		path    []string
+		isDir   bool
+		ignored bool
+	}{
+		{path: []string{".git"}, isDir: true, ignored: true},
+		{path: []string{"sub", ".git"}, isDir: true, ignored: false},
+		{path: []string{"local.txt"}, ignored: true},
+		{path: []string{"main.go"}, ignored: false},
+		{path: []string{"debug.log"}, ignored: true},
+		{path: []string{"node_modules"}, isDir: true, ignored: true},
+		{path: []string{"sub", "generated.go"}, ignored: true},
+		{path: []string{"sub", "deeper", "generated.go"}, ignored: true},
+		{path: []string{"sub", "keep.log"}, ignored: false},
+		{path: []string{"unrelated", "generated.go"}, ignored: false},
+	} {
+		require.Equal(t, tc.ignored, matcher.Match(tc.path, tc.isDir), "%s", filepath.Join(tc.path...))
+	}
+}
+
+func TestReadGitIgnoreSubdirectory(t *testing.T) {
+	repo := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":              "*.log\n/top.txt\n",
+		".git/info/exclude":       "local.txt\n",
+		"app/.gitignore":          "dist/\n",
+		"app/web/.gitignore":      "!keep.log\n",
+		"app/web/dist/index.html": "",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repo, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(repo, name), []byte(content), 0o600))
+	}
+
+	// a worktree, whose .git file points to a git directory sharing the
+	// repository's info/exclude
+	worktreeGitDir := filepath.Join(repo, ".git", "worktrees", "wt")
+	require.NoError(t, os.MkdirAll(worktreeGitDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreeGitDir, "commondir"), []byte("../..\n"), 0o600))
+	worktree := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+worktreeGitDir+"\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(worktree, "sub"), 0o755))
+
+	for _, tc := range []struct {
+		dir     string
+		path    []string
+		isDir   bool
+		ignored bool
+	}{
+		{dir: "app/web", path: []string{"debug.log"}, ignored: true},
+		{dir: "app/web", path: []string{"keep.log"}, ignored: false},
+		{dir: "app", path: []string{"debug.log"}, ignored: true},
+		{dir: "app", path: []string{"top.txt"}, ignored: false},
+		{dir: "app/web", path: []string{"dist"}, isDir: true, ignored: true},
+		{dir: "app/web", path: []string{"local.txt"}, ignored: true},
+		{dir: "app/web", path: []string{"main.go"}, ignored: false},
+	} {
+		matcher, err := readGitIgnore(filepath.Join(repo, tc.dir))
+		require.NoError(t, err)
+		require.Equal(t, tc.ignored, matcher.Match(tc.path, tc.isDir), "%s in %s", filepath.Join(tc.path...), tc.dir)
+	}
+
+	matcher, err := readGitIgnore(filepath.Join(worktree, "sub"))
+	require.NoError(t, err)
+	require.True(t, matcher.Match([]string{"local.txt"}, false))
+	require.False(t, matcher.Match([]string{"main.go"}, false))
+}
+
+func TestReadDockerIgnore(t *testing.T) {
+	dir := t.TempDir()
+
+	patterns, err := readDockerIgnore(dir)
+	require.NoError(t, err)
+	require.Empty(t, patterns)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("# comment\ndocs\n!docs/README.md\n"), 0o600))
+	patterns, err = readDockerIgnore(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs", "!docs/README.md"}, patterns)
+}
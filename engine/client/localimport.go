@@ -0,0 +1,119 @@
+package client
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/moby/patternmatcher"
+
+	"github.com/dagger/dagger/engine"
+)
+
+// LocalImports records the local dir and file imports served by a client, so
+// that e.g. watch mode knows which host paths a pipeline depends on.
+type LocalImports struct {
+	mu      sync.Mutex
+	imports []engine.LocalImportOpts
+}
+
+func (li *LocalImports) record(opts engine.LocalImportOpts) {
+	if li == nil {
+		return
+	}
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	li.imports = append(li.imports, opts)
+}
+
+// Take returns the imports recorded since the last call.
+func (li *LocalImports) Take() []engine.LocalImportOpts {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	imports := li.imports
+	li.imports = nil
+	return imports
+}
+
+// LocalImportFilter tells which paths of a local dir import are sent to the
+// engine, according to its include and exclude patterns and ignore files.
+type LocalImportFilter struct {
+	include   *patternmatcher.PatternMatcher
+	exclude   *patternmatcher.PatternMatcher
+	gitIgnore gitignore.Matcher
+}
+
+func NewLocalImportFilter(opts engine.LocalImportOpts) (*LocalImportFilter, error) {
+	f := &LocalImportFilter{}
+
+	var err error
+	if len(opts.IncludePatterns) > 0 {
+		f.include, err = patternmatcher.New(opts.IncludePatterns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	excludePatterns := opts.ExcludePatterns
+	if opts.DockerIgnore {
+		dockerIgnorePatterns, err := readDockerIgnore(opts.Path)
+		if err != nil {
+			return nil, err
+		}
+		excludePatterns = append(excludePatterns, dockerIgnorePatterns...)
+	}
+	if len(excludePatterns) > 0 {
+		f.exclude, err = patternmatcher.New(excludePatterns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.GitIgnore {
+		f.gitIgnore, err = readGitIgnore(opts.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Match reports whether the given path, relative to the imported directory,
+// is imported.
+func (f *LocalImportFilter) Match(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	if f.gitIgnore != nil && f.gitIgnore.Match(strings.Split(relPath, "/"), isDir) {
+		return false
+	}
+	if f.exclude != nil {
+		excluded, err := f.exclude.MatchesOrParentMatches(relPath)
+		if err != nil || excluded {
+			return false
+		}
+	}
+	if f.include != nil {
+		included, err := f.include.MatchesOrParentMatches(relPath)
+		if err != nil || !included {
+			return false
+		}
+	}
+	return true
+}
+
+// SkipDir reports whether nothing under the given directory, relative to the
+// imported directory, is imported.
+func (f *LocalImportFilter) SkipDir(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	if f.gitIgnore != nil && f.gitIgnore.Match(strings.Split(relPath, "/"), true) {
+		return true
+	}
+	if f.exclude != nil && !f.exclude.Exclusions() {
+		// without exclusions, nothing under an excluded directory can be
+		// included again
+		excluded, err := f.exclude.MatchesOrParentMatches(relPath)
+		return err == nil && excluded
+	}
+	return false
+}
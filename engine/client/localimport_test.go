@@ -0,0 +1,55 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/engine"
+)
+
+func TestLocalImportFilter(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("node_modules/\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("*.md\n"), 0o600))
+
+	filter, err := NewLocalImportFilter(engine.LocalImportOpts{
+		Path:            dir,
+		IncludePatterns: []string{"src", "*.md", "node_modules"},
+		ExcludePatterns: []string{"src/vendor"},
+		GitIgnore:       true,
+		DockerIgnore:    true,
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		path    string
+		isDir   bool
+		matched bool
+	}{
+		{path: "src/main.go", matched: true},
+		{path: "src/vendor/dep.go", matched: false},
+		{path: "other/main.go", matched: false},
+		{path: "README.md", matched: false},
+		{path: "node_modules/dep/index.js", matched: false},
+	} {
+		require.Equal(t, tc.matched, filter.Match(tc.path, tc.isDir), tc.path)
+	}
+
+	require.True(t, filter.SkipDir("node_modules"))
+	require.True(t, filter.SkipDir("src/vendor"))
+	require.False(t, filter.SkipDir("src"))
+}
+
+func TestLocalImportsTake(t *testing.T) {
+	imports := &LocalImports{}
+	imports.record(engine.LocalImportOpts{Path: "a"})
+	imports.record(engine.LocalImportOpts{Path: "b"})
+	require.Equal(t, []engine.LocalImportOpts{{Path: "a"}, {Path: "b"}}, imports.Take())
+	require.Empty(t, imports.Take())
+
+	var noImports *LocalImports
+	noImports.record(engine.LocalImportOpts{Path: "a"})
+}
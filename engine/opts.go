@@ -27,6 +27,16 @@ const (
 	localDirImportFollowPathsMetaKey     = "followpaths"
 )
 
+// Buildkit only forwards include and exclude patterns to the client of a local
+// dir import, so whether to respect the ignore files of the imported directory,
+// which can only be read by the client, is requested with these pseudo exclude
+// patterns. They are removed from the exclude patterns by
+// LocalImportOptsFromContext.
+const (
+	LocalImportGitIgnorePattern    = "!dagger:gitignore"
+	LocalImportDockerIgnorePattern = "!dagger:dockerignore"
+)
+
 type ClientMetadata struct {
 	// ClientID is unique to every session created by every client
 	ClientID string `json:"client_id"`
@@ -110,6 +120,11 @@ type LocalImportOpts struct {
 	FollowPaths        []string `json:"follow_paths"`
 	ReadSingleFileOnly bool     `json:"read_single_file_only"`
 	MaxFileSize        int64    `json:"max_file_size"`
+
+	// GitIgnore and DockerIgnore exclude what the .gitignore and .dockerignore
+	// files of the imported directory exclude.
+	GitIgnore    bool `json:"git_ignore"`
+	DockerIgnore bool `json:"docker_ignore"`
 }
 
 func (o LocalImportOpts) ToGRPCMD() metadata.MD {
@@ -117,11 +132,24 @@ func (o LocalImportOpts) ToGRPCMD() metadata.MD {
 	md := encodeMeta(localImportOptsMetaKey, o)
 	md[localDirImportDirNameMetaKey] = []string{o.Path}
 	md[localDirImportIncludePatternsMetaKey] = o.IncludePatterns
-	md[localDirImportExcludePatternsMetaKey] = o.ExcludePatterns
+	md[localDirImportExcludePatternsMetaKey] = o.buildkitExcludePatterns()
 	md[localDirImportFollowPathsMetaKey] = o.FollowPaths
 	return md
 }
 
+// buildkitExcludePatterns returns the exclude patterns with the pseudo
+// patterns of the ignore files to respect.
+func (o LocalImportOpts) buildkitExcludePatterns() []string {
+	patterns := append([]string{}, o.ExcludePatterns...)
+	if o.GitIgnore {
+		patterns = append(patterns, LocalImportGitIgnorePattern)
+	}
+	if o.DockerIgnore {
+		patterns = append(patterns, LocalImportDockerIgnorePattern)
+	}
+	return patterns
+}
+
 func (o LocalImportOpts) AppendToOutgoingContext(ctx context.Context) context.Context {
 	md, ok := metadata.FromOutgoingContext(ctx)
 	if !ok {
@@ -157,7 +185,16 @@ func LocalImportOptsFromContext(ctx context.Context) (*LocalImportOpts, error) {
 	}
 	opts.Path = dirNameVals[0]
 	opts.IncludePatterns = md[localDirImportIncludePatternsMetaKey]
-	opts.ExcludePatterns = md[localDirImportExcludePatternsMetaKey]
+	for _, pattern := range md[localDirImportExcludePatternsMetaKey] {
+		switch pattern {
+		case LocalImportGitIgnorePattern:
+			opts.GitIgnore = true
+		case LocalImportDockerIgnorePattern:
+			opts.DockerIgnore = true
+		default:
+			opts.ExcludePatterns = append(opts.ExcludePatterns, pattern)
+		}
+	}
 	opts.FollowPaths = md[localDirImportFollowPathsMetaKey]
 	return opts, nil
 }
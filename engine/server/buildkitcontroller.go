@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/containerd/containerd/remotes/docker"
 	"github.com/dagger/dagger/auth"
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/core/pipeline"
@@ -75,6 +76,8 @@ type BuildkitControllerOpts struct {
 	UpstreamCacheExporters map[string]remotecache.ResolveCacheExporterFunc
 	UpstreamCacheImporters map[string]remotecache.ResolveCacheImporterFunc
 	DNSConfig              *oci.DNSConfig
+	RegistryHosts          docker.RegistryHosts
+	RegistryMirrors        *buildkit.RegistryMirrors
 }
 
 func NewBuildkitController(opts BuildkitControllerOpts) (*BuildkitController, error) {
@@ -242,6 +245,8 @@ func (e *BuildkitController) Session(stream controlapi.Control_SessionServer) (r
 			GenericSolver:         e.genericSolver,
 			SecretStore:           secretStore,
 			AuthProvider:          authProvider,
+			RegistryHosts:         e.RegistryHosts,
+			RegistryMirrors:       e.RegistryMirrors,
 			PrivilegedExecEnabled: e.privilegedExecEnabled,
 			UpstreamCacheImports:  cacheImporterCfgs,
 			ProgSockPath:          progSockPath,
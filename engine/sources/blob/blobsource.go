@@ -4,16 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/moby/buildkit/cache"
 	"github.com/moby/buildkit/client/llb"
 	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/snapshot"
 	"github.com/moby/buildkit/solver"
 	"github.com/moby/buildkit/solver/llbsolver/provenance"
 	"github.com/moby/buildkit/solver/pb"
 	"github.com/moby/buildkit/source"
+	"github.com/moby/buildkit/util/resolver"
 	"github.com/opencontainers/go-digest"
 	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
 )
@@ -23,18 +31,32 @@ const (
 
 	MediaTypeAttr = "daggerBlobSourceMediaType"
 	SizeAttr      = "daggerBlobSourceSize"
+
+	// FileRefAttr marks a blob holding a plain file, like the layer of an
+	// artifact, rather than a filesystem layer. It's the ref of the registry
+	// repository to pull the blob from.
+	FileRefAttr = "daggerBlobSourceFileRef"
+
+	// FileName is the name of the file holding the blob of a FileLLB.
+	FileName = "blob"
 )
 
 type Opt struct {
 	CacheAccessor cache.Accessor
+	RegistryHosts docker.RegistryHosts
 }
 
 type blobSource struct {
 	cache cache.Accessor
+	hosts docker.RegistryHosts
 }
 
 type SourceIdentifier struct {
 	ocispecs.Descriptor
+
+	// FileRef is the ref of the registry repository to pull the blob from if
+	// it's a plain file, or "" if it's a filesystem layer.
+	FileRef string
 }
 
 func (SourceIdentifier) Scheme() string {
@@ -61,6 +83,25 @@ func LLB(desc ocispecs.Descriptor) llb.State {
 	).Output())
 }
 
+// FileLLB returns a state holding the given blob as a plain file named
+// FileName, pulled from the registry repository of ref.
+func FileLLB(desc ocispecs.Descriptor, ref string, opts ...llb.ConstraintsOpt) llb.State {
+	attrs := map[string]string{
+		MediaTypeAttr: desc.MediaType,
+		SizeAttr:      strconv.Itoa(int(desc.Size)),
+		FileRefAttr:   ref,
+	}
+	var c llb.Constraints
+	for _, opt := range opts {
+		opt.SetConstraintsOption(&c)
+	}
+	return llb.NewState(llb.NewSource(
+		fmt.Sprintf("%s://%s", BlobScheme, desc.Digest.String()),
+		attrs,
+		c,
+	).Output())
+}
+
 func IdentifierFromPB(op *pb.SourceOp) (*SourceIdentifier, error) {
 	scheme, ref, ok := strings.Cut(op.Identifier, "://")
 	if !ok {
@@ -73,6 +114,7 @@ func IdentifierFromPB(op *pb.SourceOp) (*SourceIdentifier, error) {
 func NewSource(opt Opt) (source.Source, error) {
 	bs := &blobSource{
 		cache: opt.CacheAccessor,
+		hosts: opt.RegistryHosts,
 	}
 	return bs, nil
 }
@@ -90,8 +132,11 @@ func (bs *blobSource) identifier(scheme, ref string, sourceAttrs map[string]stri
 		Digest:      digest.Digest(ref),
 		Annotations: map[string]string{},
 	}
+	var fileRef string
 	for k, v := range sourceAttrs {
 		switch k {
+		case FileRefAttr:
+			fileRef = v
 		case MediaTypeAttr:
 			desc.MediaType = v
 		case SizeAttr:
@@ -104,7 +149,7 @@ func (bs *blobSource) identifier(scheme, ref string, sourceAttrs map[string]stri
 			desc.Annotations[k] = v
 		}
 	}
-	return &SourceIdentifier{desc}, nil
+	return &SourceIdentifier{Descriptor: desc, FileRef: fileRef}, nil
 }
 
 func (bs *blobSource) Resolve(ctx context.Context, id source.Identifier, sm *session.Manager, _ solver.Vertex) (source.SourceInstance, error) {
@@ -139,10 +184,18 @@ func (bs *blobSourceInstance) CacheKey(context.Context, session.Group, int) (str
 	// Definition-based fast cache does not currently match on "random:" digests
 	// (because the exported cache loses these pieces). This requires an upstream
 	// buildkit fix.
+	if bs.id.FileRef != "" {
+		// the same blob is a different snapshot as a file than as a layer
+		return "dagger:file:" + bs.id.Digest.String(), bs.id.Digest.String(), nil, true, nil
+	}
 	return "dagger:" + bs.id.Digest.String(), bs.id.Digest.String(), nil, true, nil
 }
 
-func (bs *blobSourceInstance) Snapshot(ctx context.Context, _ session.Group) (cache.ImmutableRef, error) {
+func (bs *blobSourceInstance) Snapshot(ctx context.Context, g session.Group) (cache.ImmutableRef, error) {
+	if bs.id.FileRef != "" {
+		return bs.fileSnapshot(ctx, g)
+	}
+
 	opts := []cache.RefOption{
 		// TODO: could also include description of original blob source by passing along more metadata
 		cache.WithDescription(fmt.Sprintf("dagger blob source for %s", bs.id.Digest)),
@@ -172,3 +225,84 @@ func (bs *blobSourceInstance) Snapshot(ctx context.Context, _ session.Group) (ca
 	}
 	return ref, nil
 }
+
+// fileSnapshot pulls the blob from its registry repository into a new
+// snapshot, as a plain file named FileName.
+func (bs *blobSourceInstance) fileSnapshot(ctx context.Context, g session.Group) (_ cache.ImmutableRef, rerr error) {
+	if bs.hosts == nil {
+		return nil, fmt.Errorf("cannot pull blob %s: no registry hosts", bs.id.Digest)
+	}
+	fetcher, err := resolver.DefaultPool.GetResolver(bs.hosts, bs.id.FileRef, "pull", bs.sm, g).Fetcher(ctx, bs.id.FileRef)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := fetcher.Fetch(ctx, bs.id.Descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull blob %s: %w", bs.id.Digest, err)
+	}
+	defer rc.Close()
+
+	newRef, err := bs.cache.New(ctx, nil, g,
+		cache.CachePolicyRetain,
+		cache.WithDescription(fmt.Sprintf("dagger blob source for file %s", bs.id.Digest)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rerr != nil {
+			newRef.Release(context.TODO())
+		}
+	}()
+
+	mount, err := newRef.Mount(ctx, false, g)
+	if err != nil {
+		return nil, err
+	}
+	lm := snapshot.LocalMounter(mount)
+	dir, err := lm.Mount()
+	if err != nil {
+		return nil, err
+	}
+	err = writeBlobFile(filepath.Join(dir, FileName), rc, bs.id.Descriptor, mount.IdentityMapping())
+	if unmountErr := lm.Unmount(); err == nil {
+		err = unmountErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull blob %s: %w", bs.id.Digest, err)
+	}
+	return newRef.Commit(ctx)
+}
+
+// writeBlobFile writes the blob read from r to a file at the given path,
+// owned by root, failing if it doesn't match its descriptor.
+func writeBlobFile(filePath string, r io.Reader, desc ocispecs.Descriptor, idmap *idtools.IdentityMapping) error {
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	verifier := desc.Digest.Verifier()
+	n, err := io.Copy(io.MultiWriter(f, verifier), io.LimitReader(r, desc.Size+1))
+	if err != nil {
+		return err
+	}
+	if n != desc.Size {
+		return fmt.Errorf("expected %d bytes, got %d", desc.Size, n)
+	}
+	if !verifier.Verified() {
+		return errors.New("digest mismatch")
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if idmap != nil {
+		root := idmap.RootPair()
+		if err := os.Chown(filePath, root.UID, root.GID); err != nil {
+			return err
+		}
+	}
+	epoch := time.Unix(0, 0)
+	return os.Chtimes(filePath, epoch, epoch)
+}
@@ -0,0 +1,89 @@
+package gitdns
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Checkout controls how a git source checks out its ref. Its zero value
+// checks out the whole tree, with its submodules, and one commit of history.
+type Checkout struct {
+	// Depth is the number of commits of history to fetch: one if zero, or
+	// the whole history if negative. It only changes the checkout when the
+	// .git directory is kept.
+	Depth int `json:"depth,omitempty"`
+
+	// SparsePaths restricts the checkout to these paths.
+	SparsePaths []string `json:"sparsePaths,omitempty"`
+
+	// NoSubmodules skips checking out submodules.
+	NoSubmodules bool `json:"noSubmodules,omitempty"`
+
+	// LFS downloads the content of the files stored with Git LFS, instead of
+	// leaving their pointers.
+	LFS bool `json:"lfs,omitempty"`
+}
+
+// IsZero reports whether the checkout has the default options.
+func (c Checkout) IsZero() bool {
+	return c.Depth == 0 && len(c.SparsePaths) == 0 && !c.NoSubmodules && !c.LFS
+}
+
+// cacheKeySuffix returns what distinguishes snapshots checked out with these
+// options from those of the same commit checked out with the defaults.
+func (c Checkout) cacheKeySuffix(keepGitDir bool) string {
+	var key string
+	if c.Depth != 0 && keepGitDir {
+		key += fmt.Sprintf(";depth=%d", c.Depth)
+	}
+	if len(c.SparsePaths) > 0 {
+		paths := append([]string{}, c.SparsePaths...)
+		sort.Strings(paths)
+		key += ";sparse=" + strings.Join(paths, ",")
+	}
+	if c.NoSubmodules {
+		key += ";nosubmodules"
+	}
+	if c.LFS {
+		key += ";lfs"
+	}
+	return key
+}
+
+// depthArgs returns the fetch arguments for the given depth of history,
+// unshallowing the repository if it's shallow and the whole history is
+// needed.
+func depthArgs(depth int, isShallow bool) []string {
+	switch {
+	case depth == 0:
+		return []string{"--depth=1"}
+	case depth > 0:
+		return []string{fmt.Sprintf("--depth=%d", depth)}
+	case isShallow:
+		return []string{"--unshallow"}
+	default:
+		return nil
+	}
+}
+
+// sparseCheckoutPatterns returns the sparse-checkout patterns matching the
+// given paths of the repository.
+func sparseCheckoutPatterns(paths []string) string {
+	var patterns strings.Builder
+	for _, p := range paths {
+		patterns.WriteString(path.Join("/", p) + "\n")
+	}
+	return patterns.String()
+}
+
+// lfsFilterArgs returns the git config arguments downloading the content of
+// the files stored with Git LFS when they're checked out.
+func lfsFilterArgs() []string {
+	return []string{
+		"-c", "filter.lfs.process=git-lfs filter-process",
+		"-c", "filter.lfs.smudge=git-lfs smudge -- %f",
+		"-c", "filter.lfs.required=true",
+	}
+}
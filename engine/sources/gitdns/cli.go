@@ -24,6 +24,7 @@ type gitCLI struct {
 	sshAuthSock string   // SSH_AUTH_SOCK env value
 	knownHosts  string   // file path passed to SSH
 	auth        []string // extra auth flags passed to git
+	env         []string // extra environment variables
 
 	hostsPath  string // generated /etc/hosts from network config
 	resolvPath string // generated /etc/resolv.conf from network config
@@ -69,6 +70,12 @@ func (cli *gitCLI) withinDir(gitDir, workDir string) *gitCLI {
 	return &cp
 }
 
+func (cli *gitCLI) withEnv(env ...string) *gitCLI {
+	cp := *cli
+	cp.env = append(append([]string{}, cli.env...), env...)
+	return &cp
+}
+
 func (cli *gitCLI) run(ctx context.Context, args ...string) (_ *bytes.Buffer, err error) {
 	for {
 		stdout, stderr, flush := logs.NewLogStreams(ctx, true)
@@ -109,6 +116,7 @@ func (cli *gitCLI) run(ctx context.Context, args ...string) (_ *bytes.Buffer, er
 		if cli.sshAuthSock != "" {
 			cmd.Env = append(cmd.Env, "SSH_AUTH_SOCK="+cli.sshAuthSock)
 		}
+		cmd.Env = append(cmd.Env, cli.env...)
 		// remote git commands spawn helper processes that inherit FDs and don't
 		// handle parent death signal so exec.CommandContext can't be used
 		err := runWithStandardUmaskAndNetOverride(ctx, cmd, cli.hostsPath, cli.resolvPath)
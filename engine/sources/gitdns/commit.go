@@ -0,0 +1,177 @@
+package gitdns
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moby/buildkit/executor/oci"
+	"github.com/moby/buildkit/util/gitutil"
+	"github.com/moby/buildkit/util/urlutil"
+	"github.com/pkg/errors"
+)
+
+// Commit is a commit to create from the files of a directory, outside of any
+// git source.
+type Commit struct {
+	// Dir holds the files to commit. Files ignored by its .gitignore files
+	// aren't committed.
+	Dir string
+
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+	Date        time.Time
+
+	// Parent is the hash of the parent commit, if any, which must be in the
+	// repository at ParentGitDir.
+	Parent       string
+	ParentGitDir string
+}
+
+// CreateCommit initializes a bare repository at gitDir and creates the
+// commit in it, returning its hash. The repository borrows the objects of
+// the parent's repository, which must outlive it.
+func CreateCommit(ctx context.Context, gitDir string, commit Commit) (string, error) {
+	git, cleanup, err := newGitCLI(gitDir, "", "", "", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	if _, err := git.run(ctx, "-c", "init.defaultBranch=master", "init", "--bare"); err != nil {
+		return "", errors.Wrapf(err, "failed to init repo at %s", gitDir)
+	}
+
+	if commit.Parent != "" {
+		if commit.ParentGitDir == "" {
+			return "", errors.Errorf("no repository given for parent commit %s", commit.Parent)
+		}
+		alternates := filepath.Join(gitDir, "objects", "info", "alternates")
+		if err := os.WriteFile(alternates, []byte(filepath.Join(commit.ParentGitDir, "objects")+"\n"), 0o644); err != nil {
+			return "", err
+		}
+		// the parent's repository is usually a shallow clone, whose boundaries
+		// must be known to walk the history
+		shallow, err := os.ReadFile(filepath.Join(commit.ParentGitDir, "shallow"))
+		if err == nil {
+			err = os.WriteFile(filepath.Join(gitDir, "shallow"), shallow, 0o644)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	index := git.withinDir(gitDir, commit.Dir).withEnv("GIT_INDEX_FILE=" + filepath.Join(gitDir, "dagger-index"))
+	if _, err := index.run(ctx, "add", "--all", "."); err != nil {
+		return "", errors.Wrapf(err, "failed to add files of %s", commit.Dir)
+	}
+	treeBuf, err := index.run(ctx, "write-tree")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to write tree")
+	}
+	if err := os.Remove(filepath.Join(gitDir, "dagger-index")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	date := fmt.Sprintf("@%d +0000", commit.Date.Unix())
+	args := []string{"commit-tree", strings.TrimSpace(treeBuf.String()), "-m", commit.Message}
+	if commit.Parent != "" {
+		args = append(args, "-p", commit.Parent)
+	}
+	commitBuf, err := git.withEnv(
+		"GIT_AUTHOR_NAME="+commit.AuthorName,
+		"GIT_AUTHOR_EMAIL="+commit.AuthorEmail,
+		"GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_NAME="+commit.AuthorName,
+		"GIT_COMMITTER_EMAIL="+commit.AuthorEmail,
+		"GIT_COMMITTER_DATE="+date,
+	).run(ctx, args...)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create commit")
+	}
+
+	sha := strings.TrimSpace(commitBuf.String())
+	if !isCommitSHA(sha) {
+		return "", errors.Errorf("invalid commit sha %q", sha)
+	}
+	return sha, nil
+}
+
+// Push is a push of a commit to a branch of a remote repository.
+type Push struct {
+	Remote string
+	Commit string
+	Branch string
+
+	// Force overwrites the branch even if it isn't an ancestor of the
+	// commit.
+	Force bool
+
+	// AuthToken or AuthHeader authenticate with the remote over HTTPS, as a
+	// token or as a whole Authorization header.
+	AuthToken  []byte
+	AuthHeader []byte
+
+	// SSHAuthSock is the path of an SSH agent socket, and KnownSSHHosts the
+	// known hosts to check the remote against.
+	SSHAuthSock   string
+	KnownSSHHosts string
+
+	// DNS is the engine's DNS config, and ClientIDs the clients whose
+	// services' hostnames must resolve.
+	DNS       *oci.DNSConfig
+	ClientIDs []string
+}
+
+// PushCommit pushes a commit of the repository at gitDir. The remote must be
+// a network URL: local paths and file:// URLs are rejected, so that nothing
+// can be pushed into the engine's filesystem.
+func PushCommit(ctx context.Context, gitDir string, push Push) error {
+	if _, err := gitutil.ParseURL(push.Remote); err != nil {
+		return errors.Wrapf(err, "invalid remote %s", urlutil.RedactCredentials(push.Remote))
+	}
+
+	var auth []string
+	switch {
+	case len(push.AuthHeader) > 0:
+		auth = authArgs(push.Remote, push.AuthHeader, false)
+	case len(push.AuthToken) > 0:
+		auth = authArgs(push.Remote, push.AuthToken, true)
+	}
+
+	var knownHosts string
+	if push.KnownSSHHosts != "" {
+		var removeKnownHosts func() error
+		var err error
+		knownHosts, removeKnownHosts, err = writeKnownHosts(push.KnownSSHHosts)
+		if err != nil {
+			return err
+		}
+		defer removeKnownHosts()
+	}
+
+	var dns *oci.DNSConfig
+	if push.DNS != nil {
+		dns = clientDNSConfig(push.DNS, push.ClientIDs)
+	}
+
+	git, cleanup, err := newGitCLI(gitDir, "", push.SSHAuthSock, knownHosts, auth, dns)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	args := []string{"push"}
+	if push.Force {
+		args = append(args, "--force")
+	}
+	args = append(args, push.Remote, push.Commit+":refs/heads/"+push.Branch)
+	if _, err := git.run(ctx, args...); err != nil {
+		return errors.Wrapf(err, "failed to push to remote %s", urlutil.RedactCredentials(push.Remote))
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package gitdns
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/buildkit/util/gitutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushCommitLocalRemote(t *testing.T) {
+	dir := t.TempDir()
+	for _, remote := range []string{
+		filepath.Join(dir, "repo"),
+		"./repo",
+		"file://" + filepath.Join(dir, "repo"),
+	} {
+		err := PushCommit(context.Background(), dir, Push{
+			Remote: remote,
+			Commit: "HEAD",
+			Branch: "main",
+		})
+		require.Error(t, err, remote)
+		require.True(t, errors.Is(err, gitutil.ErrUnknownProtocol) || errors.Is(err, gitutil.ErrInvalidProtocol), remote)
+	}
+}
@@ -204,6 +204,17 @@ type gitSourceHandler struct {
 	cacheKey  string
 	sm        *session.Manager
 	auth      []string
+
+	// query is set when the snapshot must hold the result of a query instead
+	// of a checkout.
+	query *Query
+	// queryResult is the result of the query, when already computed.
+	queryResult []byte
+	// logRange is the revision range resolved for a log query.
+	logRange string
+
+	// checkout controls how the ref is checked out.
+	checkout Checkout
 }
 
 func (gs *gitSourceHandler) shaToCacheKey(sha string) string {
@@ -211,6 +222,7 @@ func (gs *gitSourceHandler) shaToCacheKey(sha string) string {
 	if gs.src.KeepGitDir {
 		key += ".git"
 	}
+	key += gs.checkout.cacheKeySuffix(gs.src.KeepGitDir)
 	if gs.src.Subdir != "" {
 		key += ":" + gs.src.Subdir
 	}
@@ -220,8 +232,10 @@ func (gs *gitSourceHandler) shaToCacheKey(sha string) string {
 // TODO(vito): this can be cleaned up if/when
 // https://github.com/moby/buildkit/pull/4035 is merged
 type DaggerGitURLHack struct {
-	Remote    string   `json:"remote"`
-	ClientIDs []string `json:"client_ids"`
+	Remote    string    `json:"remote"`
+	ClientIDs []string  `json:"client_ids"`
+	Query     *Query    `json:"query,omitempty"`
+	Checkout  *Checkout `json:"checkout,omitempty"`
 }
 
 func (gs *gitSource) Resolve(ctx context.Context, id source.Identifier, sm *session.Manager, _ solver.Vertex) (source.SourceInstance, error) {
@@ -231,6 +245,8 @@ func (gs *gitSource) Resolve(ctx context.Context, id source.Identifier, sm *sess
 	}
 
 	var clientIDs []string
+	var query *Query
+	var checkout Checkout
 	var hack DaggerGitURLHack
 	if err := buildkit.DecodeIDHack("git", gitIdentifier.Remote, &hack); err != nil {
 		// ignore error; we have to handle both scenarios because this Source
@@ -239,6 +255,10 @@ func (gs *gitSource) Resolve(ctx context.Context, id source.Identifier, sm *sess
 	} else {
 		gitIdentifier.Remote = hack.Remote
 		clientIDs = hack.ClientIDs
+		query = hack.Query
+		if hack.Checkout != nil {
+			checkout = *hack.Checkout
+		}
 	}
 
 	return &gitSourceHandler{
@@ -246,6 +266,8 @@ func (gs *gitSource) Resolve(ctx context.Context, id source.Identifier, sm *sess
 		clientIDs: clientIDs,
 		gitSource: gs,
 		sm:        sm,
+		query:     query,
+		checkout:  checkout,
 	}, nil
 }
 
@@ -291,10 +313,7 @@ func (gs *gitSourceHandler) getAuthToken(ctx context.Context, g session.Group) e
 				}
 				return err
 			}
-			if s.token {
-				dt = []byte("basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("x-access-token:%s", dt))))
-			}
-			gs.auth = []string{"-c", "http." + tokenScope(gs.src.Remote) + ".extraheader=Authorization: " + string(dt)}
+			gs.auth = authArgs(gs.src.Remote, dt, s.token)
 			break
 		}
 		return nil
@@ -344,6 +363,12 @@ func (gs *gitSourceHandler) mountKnownHosts() (string, func() error, error) {
 	if gs.src.KnownSSHHosts == "" {
 		return "", nil, errors.Errorf("no configured known hosts forwarded from the client")
 	}
+	return writeKnownHosts(gs.src.KnownSSHHosts)
+}
+
+// writeKnownHosts writes the given known hosts to a temporary file, returning
+// its path and a function removing it.
+func writeKnownHosts(contents string) (string, func() error, error) {
 	knownHosts, err := os.CreateTemp("", "")
 	if err != nil {
 		return "", nil, err
@@ -351,7 +376,7 @@ func (gs *gitSourceHandler) mountKnownHosts() (string, func() error, error) {
 	cleanup := func() error {
 		return os.Remove(knownHosts.Name())
 	}
-	_, err = knownHosts.Write([]byte(gs.src.KnownSSHHosts))
+	_, err = knownHosts.Write([]byte(contents))
 	if err != nil {
 		cleanup()
 		return "", nil, err
@@ -365,17 +390,36 @@ func (gs *gitSourceHandler) mountKnownHosts() (string, func() error, error) {
 }
 
 func (gs *gitSourceHandler) dnsConfig() *oci.DNSConfig {
+	return clientDNSConfig(gs.dns, gs.clientIDs)
+}
+
+// clientDNSConfig returns the base DNS config with the search domains of the
+// given clients, so that their services' hostnames resolve.
+func clientDNSConfig(base *oci.DNSConfig, clientIDs []string) *oci.DNSConfig {
 	clientDomains := []string{}
-	for _, clientID := range gs.clientIDs {
+	for _, clientID := range clientIDs {
 		clientDomains = append(clientDomains, network.ClientDomain(clientID))
 	}
 
-	dns := *gs.dns
+	dns := *base
 	dns.SearchDomains = append(clientDomains, dns.SearchDomains...)
 	return &dns
 }
 
+// authArgs returns the git arguments authenticating the requests to the
+// remote with the given Authorization header, or token.
+func authArgs(remote string, secret []byte, token bool) []string {
+	if token {
+		secret = []byte("basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("x-access-token:%s", secret))))
+	}
+	return []string{"-c", "http." + tokenScope(remote) + ".extraheader=Authorization: " + string(secret)}
+}
+
 func (gs *gitSourceHandler) CacheKey(ctx context.Context, g session.Group, index int) (string, string, solver.CacheOpts, bool, error) {
+	if gs.query != nil {
+		return gs.queryCacheKey(ctx, g)
+	}
+
 	remote := gs.src.Remote
 	gs.locker.Lock(remote)
 	defer gs.locker.Unlock(remote)
@@ -452,6 +496,10 @@ func (gs *gitSourceHandler) CacheKey(ctx context.Context, g session.Group, index
 }
 
 func (gs *gitSourceHandler) Snapshot(ctx context.Context, g session.Group) (out cache.ImmutableRef, retErr error) { // nolint: gocyclo
+	if gs.query != nil {
+		return gs.querySnapshot(ctx, g)
+	}
+
 	cacheKey := gs.cacheKey
 	if cacheKey == "" {
 		var err error
@@ -519,10 +567,18 @@ func (gs *gitSourceHandler) Snapshot(ctx context.Context, g session.Group) (out
 		}
 	}
 
+	// the depth of history only matters when the .git directory is kept
+	var depth int
+	if gs.src.KeepGitDir {
+		depth = gs.checkout.Depth
+	}
+	_, shallowErr := os.Lstat(filepath.Join(gitDir, "shallow"))
+	isShallow := shallowErr == nil
+
 	doFetch := true
 	if isCommitSHA(ref) {
-		// skip fetch if commit already exists
-		if _, err := git.run(ctx, "cat-file", "-e", ref+"^{commit}"); err == nil {
+		// skip fetch if commit already exists, with enough history
+		if _, err := git.run(ctx, "cat-file", "-e", ref+"^{commit}"); err == nil && (depth == 0 || !isShallow) {
 			doFetch = false
 		}
 	}
@@ -533,9 +589,10 @@ func (gs *gitSourceHandler) Snapshot(ctx context.Context, g session.Group) (out
 
 		args := []string{"fetch"}
 		if !isCommitSHA(ref) { // TODO: find a branch from ls-remote?
-			args = append(args, "--depth=1", "--no-tags")
+			args = append(args, depthArgs(depth, isShallow)...)
+			args = append(args, "--no-tags")
 		} else {
-			if _, err := os.Lstat(filepath.Join(gitDir, "shallow")); err == nil {
+			if isShallow {
 				args = append(args, "--unshallow")
 			}
 		}
@@ -623,14 +680,37 @@ func (gs *gitSourceHandler) Snapshot(ctx context.Context, g session.Group) (out
 		default:
 			pullref += ":" + pullref
 		}
-		_, err = checkoutGit.run(ctx, "fetch", "-u", "--depth=1", "origin", pullref)
+		fetchArgs := []string{"fetch", "-u"}
+		fetchArgs = append(fetchArgs, depthArgs(depth, false)...)
+		_, err = checkoutGit.run(ctx, append(fetchArgs, "origin", pullref)...)
 		if err != nil {
 			return nil, err
 		}
+		if len(gs.checkout.SparsePaths) > 0 {
+			_, err = checkoutGit.run(ctx, "config", "core.sparseCheckout", "true")
+			if err != nil {
+				return nil, err
+			}
+			sparseCheckoutPath := filepath.Join(checkoutDirGit, "info", "sparse-checkout")
+			if err := os.MkdirAll(filepath.Dir(sparseCheckoutPath), 0755); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(sparseCheckoutPath, []byte(sparseCheckoutPatterns(gs.checkout.SparsePaths)), 0644); err != nil {
+				return nil, err
+			}
+		}
 		_, err = checkoutGit.run(ctx, "checkout", "FETCH_HEAD")
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to checkout remote %s", urlutil.RedactCredentials(gs.src.Remote))
 		}
+		if gs.checkout.LFS {
+			// the LFS objects are downloaded from the actual remote, not the
+			// shared repository
+			_, err = checkoutGit.run(ctx, "-c", "remote.origin.url="+gs.src.Remote, "lfs", "pull", "origin")
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to pull LFS objects for remote %s", urlutil.RedactCredentials(gs.src.Remote))
+			}
+		}
 		_, err = checkoutGit.run(ctx, "remote", "set-url", "origin", urlutil.RedactCredentials(gs.src.Remote))
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to set remote origin to %s", urlutil.RedactCredentials(gs.src.Remote))
@@ -651,7 +731,17 @@ func (gs *gitSourceHandler) Snapshot(ctx context.Context, g session.Group) (out
 				return nil, errors.Wrapf(err, "failed to create temporary checkout dir")
 			}
 		}
-		_, err = git.withinDir(gitDir, cd).run(ctx, "checkout", ref, "--", ".")
+		var checkoutArgs []string
+		if gs.checkout.LFS {
+			checkoutArgs = append(checkoutArgs, lfsFilterArgs()...)
+		}
+		checkoutArgs = append(checkoutArgs, "checkout", ref, "--")
+		if len(gs.checkout.SparsePaths) > 0 {
+			checkoutArgs = append(checkoutArgs, gs.checkout.SparsePaths...)
+		} else {
+			checkoutArgs = append(checkoutArgs, ".")
+		}
+		_, err = git.withinDir(gitDir, cd).run(ctx, checkoutArgs...)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to checkout remote %s", urlutil.RedactCredentials(gs.src.Remote))
 		}
@@ -684,9 +774,16 @@ func (gs *gitSourceHandler) Snapshot(ctx context.Context, g session.Group) (out
 		}
 	}
 
-	_, err = git.withinDir(gitDir, checkoutDir).run(ctx, "submodule", "update", "--init", "--recursive", "--depth=1")
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to update submodules for %s", urlutil.RedactCredentials(gs.src.Remote))
+	if !gs.checkout.NoSubmodules {
+		submoduleArgs := []string{"submodule", "update", "--init", "--recursive", "--depth=1"}
+		if len(gs.checkout.SparsePaths) > 0 {
+			submoduleArgs = append(submoduleArgs, "--")
+			submoduleArgs = append(submoduleArgs, gs.checkout.SparsePaths...)
+		}
+		_, err = git.withinDir(gitDir, checkoutDir).run(ctx, submoduleArgs...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to update submodules for %s", urlutil.RedactCredentials(gs.src.Remote))
+		}
 	}
 
 	if idmap := mount.IdentityMapping(); idmap != nil {
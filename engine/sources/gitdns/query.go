@@ -0,0 +1,308 @@
+package gitdns
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/buildkit/cache"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/snapshot"
+	"github.com/moby/buildkit/solver"
+	"github.com/moby/buildkit/util/urlutil"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// Query is a read-only query on a remote repository. The snapshot of a git
+// source with a query holds its result in QueryResultFile instead of a
+// checkout of the ref.
+type Query struct {
+	// Kind is the kind of query, QueryRefs or QueryLog.
+	Kind string `json:"kind"`
+
+	// Since is a ref whose history is excluded from a log query.
+	Since string `json:"since,omitempty"`
+}
+
+const (
+	// QueryRefs lists the refs of the repository, as printed by
+	// "git ls-remote --symref". Its result is cached by its contents.
+	QueryRefs = "refs"
+
+	// QueryLog lists the commits reachable from the ref, as printed by
+	// "git log -z" with LogFormat. Its result is cached by the commits the
+	// ref and Since resolve to.
+	QueryLog = "log"
+
+	// QueryResultFile is the file holding the result of a query.
+	QueryResultFile = "result"
+
+	// LogFormat is the format of each commit listed by a log query: its
+	// hash, author name, author email, commit time and message, separated by
+	// newlines.
+	LogFormat = "%H%n%an%n%ae%n%ct%n%B"
+)
+
+// openRemote returns a git CLI for the shared repository of the remote,
+// along with a function to release everything it needs. It must be called
+// with the remote locked.
+func (gs *gitSourceHandler) openRemote(ctx context.Context, g session.Group) (_ *gitCLI, _ func(), retErr error) {
+	var cleanups []func()
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+	defer func() {
+		if retErr != nil {
+			cleanup()
+		}
+	}()
+
+	gitDir, unmountGitDir, err := gs.mountRemote(ctx, gs.src.Remote, gs.auth, g)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanups = append(cleanups, unmountGitDir)
+
+	var sock string
+	if gs.src.MountSSHSock != "" {
+		var unmountSock func() error
+		sock, unmountSock, err = gs.mountSSHAuthSock(ctx, gs.src.MountSSHSock, g)
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanups = append(cleanups, func() { unmountSock() })
+	}
+
+	var knownHosts string
+	if gs.src.KnownSSHHosts != "" {
+		var unmountKnownHosts func() error
+		knownHosts, unmountKnownHosts, err = gs.mountKnownHosts()
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanups = append(cleanups, func() { unmountKnownHosts() })
+	}
+
+	git, cleanupGit, err := newGitCLI(gitDir, "", sock, knownHosts, gs.auth, gs.dnsConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanups = append(cleanups, cleanupGit)
+
+	return git, cleanup, nil
+}
+
+func (gs *gitSourceHandler) queryCacheKey(ctx context.Context, g session.Group) (string, string, solver.CacheOpts, bool, error) {
+	remote := gs.src.Remote
+	gs.locker.Lock(remote)
+	defer gs.locker.Unlock(remote)
+
+	gs.getAuthToken(ctx, g)
+
+	git, cleanup, err := gs.openRemote(ctx, g)
+	if err != nil {
+		return "", "", nil, false, err
+	}
+	defer cleanup()
+
+	switch gs.query.Kind {
+	case QueryRefs:
+		buf, err := git.run(ctx, "ls-remote", "--symref", "origin")
+		if err != nil {
+			return "", "", nil, false, errors.Wrapf(err, "failed to list refs of remote %s", urlutil.RedactCredentials(remote))
+		}
+		gs.queryResult = buf.Bytes()
+		gs.cacheKey = QueryRefs + ":" + digest.FromBytes(gs.queryResult).String()
+		return gs.cacheKey, "", nil, true, nil
+
+	case QueryLog:
+		ref := gs.src.Ref
+		if ref == "" {
+			ref, err = getDefaultBranch(ctx, git, remote)
+			if err != nil {
+				return "", "", nil, false, err
+			}
+		}
+		sha, err := resolveRemoteRef(ctx, git, remote, ref)
+		if err != nil {
+			return "", "", nil, false, err
+		}
+		gs.logRange = sha
+		if gs.query.Since != "" {
+			since, err := resolveRemoteRef(ctx, git, remote, gs.query.Since)
+			if err != nil {
+				return "", "", nil, false, err
+			}
+			gs.logRange = since + ".." + sha
+		}
+		gs.cacheKey = QueryLog + ":" + gs.logRange
+		return gs.cacheKey, sha, nil, true, nil
+
+	default:
+		return "", "", nil, false, errors.Errorf("unknown git query %q", gs.query.Kind)
+	}
+}
+
+func (gs *gitSourceHandler) querySnapshot(ctx context.Context, g session.Group) (out cache.ImmutableRef, retErr error) {
+	if gs.cacheKey == "" {
+		if _, _, _, _, err := gs.queryCacheKey(ctx, g); err != nil {
+			return nil, err
+		}
+	}
+
+	result := gs.queryResult
+	if gs.query.Kind == QueryLog {
+		var err error
+		result, err = gs.log(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resultRef, err := gs.cache.New(ctx, nil, g, cache.WithDescription(fmt.Sprintf("git %s query for %s", gs.query.Kind, urlutil.RedactCredentials(gs.src.Remote))))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create new mutable for %s", urlutil.RedactCredentials(gs.src.Remote))
+	}
+	defer func() {
+		if retErr != nil && resultRef != nil {
+			resultRef.Release(context.TODO())
+		}
+	}()
+
+	mount, err := resultRef.Mount(ctx, false, g)
+	if err != nil {
+		return nil, err
+	}
+	lm := snapshot.LocalMounter(mount)
+	dir, err := lm.Mount()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if lm != nil {
+			lm.Unmount()
+		}
+	}()
+
+	resultPath := filepath.Join(dir, QueryResultFile)
+	if err := os.WriteFile(resultPath, result, 0o644); err != nil {
+		return nil, err
+	}
+	if idmap := mount.IdentityMapping(); idmap != nil {
+		u := idmap.RootPair()
+		if err := os.Lchown(resultPath, u.UID, u.GID); err != nil {
+			return nil, errors.Wrap(err, "failed to remap git query result")
+		}
+	}
+
+	lm.Unmount()
+	lm = nil
+
+	snap, err := resultRef.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resultRef = nil
+	return snap, nil
+}
+
+// log fetches the history of the log query's range and lists its commits.
+func (gs *gitSourceHandler) log(ctx context.Context, g session.Group) ([]byte, error) {
+	remote := gs.src.Remote
+	gs.locker.Lock(remote)
+	defer gs.locker.Unlock(remote)
+
+	gs.getAuthToken(ctx, g)
+
+	git, cleanup, err := gs.openRemote(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// the shared repository is usually a shallow clone of single refs, so
+	// fetch the whole history of the range first
+	_, shallowErr := os.Lstat(filepath.Join(git.gitDir, "shallow"))
+	isShallow := shallowErr == nil
+
+	haveAll := !isShallow
+	for _, sha := range strings.Split(gs.logRange, "..") {
+		if _, err := git.run(ctx, "cat-file", "-e", sha+"^{commit}"); err != nil {
+			haveAll = false
+		}
+	}
+
+	if !haveAll {
+		// make sure no old lock files have leaked
+		os.RemoveAll(filepath.Join(git.gitDir, "shallow.lock"))
+
+		args := []string{"fetch", "--force"}
+		if isShallow {
+			args = append(args, "--unshallow")
+		}
+		args = append(args, "origin")
+		var fetchBranches bool
+		for _, ref := range []string{gs.src.Ref, gs.query.Since} {
+			switch {
+			case ref == "":
+			case isCommitSHA(ref):
+				// commits can't always be fetched directly, so fetch every
+				// branch in the hope that one of them has it
+				fetchBranches = true
+			default:
+				args = append(args, ref)
+			}
+		}
+		if fetchBranches || gs.src.Ref == "" {
+			args = append(args, "+refs/heads/*:refs/remotes/origin/*")
+		}
+		if _, err := git.run(ctx, args...); err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch remote %s", urlutil.RedactCredentials(remote))
+		}
+	}
+
+	buf, err := git.run(ctx, "log", "-z", "--format="+LogFormat, gs.logRange)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list commits of remote %s", urlutil.RedactCredentials(remote))
+	}
+	return buf.Bytes(), nil
+}
+
+// resolveRemoteRef returns the commit the given ref of the remote points to,
+// peeling annotated tags.
+func resolveRemoteRef(ctx context.Context, git *gitCLI, remote, ref string) (string, error) {
+	if isCommitSHA(ref) {
+		return ref, nil
+	}
+
+	buf, err := git.run(ctx, "ls-remote", "origin", ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to fetch remote %s", urlutil.RedactCredentials(remote))
+	}
+
+	var sha, name string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		lineSHA, lineName, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		switch {
+		case sha == "":
+			sha, name = lineSHA, lineName
+		case lineName == name+"^{}":
+			sha = lineSHA
+		}
+	}
+	if sha == "" {
+		return "", errors.Errorf("repository does not contain ref %s", ref)
+	}
+	if !isCommitSHA(sha) {
+		return "", errors.Errorf("invalid commit sha %q", sha)
+	}
+	return sha, nil
+}
@@ -14,8 +14,22 @@ import (
 const AttrNetConfig = "gitdns.netconfig"
 
 // Git is a helper mimicking the llb.Git function, but with the ability to
-// set additional attributes.
-func State(url, ref string, clientIDs []string, opts ...llb.GitOption) llb.State {
+// set additional attributes and to control the checkout.
+func State(url, ref string, clientIDs []string, checkout Checkout, opts ...llb.GitOption) llb.State {
+	var checkoutOpt *Checkout
+	if !checkout.IsZero() {
+		checkoutOpt = &checkout
+	}
+	return state(url, ref, clientIDs, nil, checkoutOpt, opts...)
+}
+
+// QueryState is like State, but the resulting snapshot holds the result of
+// the given query on the repository instead of a checkout of the ref.
+func QueryState(url, ref string, clientIDs []string, query Query, opts ...llb.GitOption) llb.State {
+	return state(url, ref, clientIDs, &query, nil, opts...)
+}
+
+func state(url, ref string, clientIDs []string, query *Query, checkout *Checkout, opts ...llb.GitOption) llb.State {
 	remote, err := gitutil.ParseURL(url)
 	if errors.Is(err, gitutil.ErrUnknownProtocol) {
 		url = "https://" + url
@@ -44,6 +58,8 @@ func State(url, ref string, clientIDs []string, opts ...llb.GitOption) llb.State
 	hack, err := buildkit.EncodeIDHack(DaggerGitURLHack{
 		Remote:    url,
 		ClientIDs: clientIDs,
+		Query:     query,
+		Checkout:  checkout,
 	})
 	if err != nil {
 		panic(err)
@@ -22,6 +22,7 @@ import (
 	"github.com/moby/buildkit/cache"
 	"github.com/moby/buildkit/executor/oci"
 	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/secrets"
 	"github.com/moby/buildkit/snapshot"
 	"github.com/moby/buildkit/solver"
 	"github.com/moby/buildkit/solver/pb"
@@ -112,6 +113,11 @@ type httpSourceHandler struct {
 	refID     string
 	cacheKey  digest.Digest
 	sm        *session.Manager
+
+	// headers are sent with every request, once their secrets are resolved
+	// into resolvedHeaders.
+	headers         []Header
+	resolvedHeaders http.Header
 }
 
 // TODO(vito): this can be cleaned up if/when
@@ -119,6 +125,7 @@ type httpSourceHandler struct {
 type DaggerHTTPURLHack struct {
 	URL       string   `json:"url"`
 	ClientIDs []string `json:"client_ids"`
+	Headers   []Header `json:"headers,omitempty"`
 }
 
 func (hs *httpSource) Resolve(ctx context.Context, id source.Identifier, sm *session.Manager, _ solver.Vertex) (source.SourceInstance, error) {
@@ -128,6 +135,7 @@ func (hs *httpSource) Resolve(ctx context.Context, id source.Identifier, sm *ses
 	}
 
 	var clientIDs []string
+	var headers []Header
 	var hack DaggerHTTPURLHack
 	if err := buildkit.DecodeIDHack(srctypes.HTTPSScheme, httpIdentifier.URL, &hack); err != nil {
 		// ignore error; we have to handle both scenarios because this Source
@@ -136,6 +144,7 @@ func (hs *httpSource) Resolve(ctx context.Context, id source.Identifier, sm *ses
 	} else {
 		httpIdentifier.URL = hack.URL
 		clientIDs = hack.ClientIDs
+		headers = hack.Headers
 	}
 
 	return &httpSourceHandler{
@@ -143,9 +152,40 @@ func (hs *httpSource) Resolve(ctx context.Context, id source.Identifier, sm *ses
 		clientIDs:  clientIDs,
 		httpSource: hs,
 		sm:         sm,
+		headers:    headers,
 	}, nil
 }
 
+// setHeaders sets the headers of the source on the request, reading the
+// values of the secret ones from the session.
+func (hs *httpSourceHandler) setHeaders(ctx context.Context, g session.Group, req *http.Request) error {
+	if hs.resolvedHeaders == nil {
+		resolved := http.Header{}
+		for _, h := range hs.headers {
+			if h.Secret == "" {
+				resolved.Add(h.Name, h.Value)
+				continue
+			}
+			err := hs.sm.Any(ctx, g, func(ctx context.Context, _ string, caller session.Caller) error {
+				dt, err := secrets.GetSecret(ctx, caller, h.Secret)
+				if err != nil {
+					return err
+				}
+				resolved.Add(h.Name, string(dt))
+				return nil
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to read secret for header %s", h.Name)
+			}
+		}
+		hs.resolvedHeaders = resolved
+	}
+	for name, values := range hs.resolvedHeaders {
+		req.Header[name] = append([]string{}, values...)
+	}
+	return nil
+}
+
 func (hs *httpSourceHandler) client(g session.Group) *http.Client {
 	clientDomains := []string{}
 	for _, clientID := range hs.clientIDs {
@@ -218,6 +258,9 @@ func (hs *httpSourceHandler) CacheKey(ctx context.Context, g session.Group, inde
 		return "", "", nil, false, err
 	}
 	req = req.WithContext(ctx)
+	if err := hs.setHeaders(ctx, g, req); err != nil {
+		return "", "", nil, false, err
+	}
 	m := map[string]cacheRefMetadata{}
 
 	// If we request a single ETag in 'If-None-Match', some servers omit the
@@ -438,13 +481,19 @@ func (hs *httpSourceHandler) save(ctx context.Context, resp *http.Response, s se
 		if err := md.setETag(respETag); err != nil {
 			return nil, "", err
 		}
-		uh, err := hs.urlHash()
-		if err != nil {
-			return nil, "", err
-		}
-		if err := md.setHTTPChecksum(uh, dgst); err != nil {
-			return nil, "", err
-		}
+	}
+
+	// the checksum is recorded even without an ETag, so that downloads with a
+	// known checksum can reuse it without any request
+	uh, err := hs.urlHash()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := md.setHTTPChecksum(uh, dgst); err != nil {
+		return nil, "", err
+	}
+	if err := md.setHTTPFileKey(hs.formatCacheKey(getFileName(hs.src.URL, hs.src.Filename, resp), dgst, "")); err != nil {
+		return nil, "", err
 	}
 
 	if modTime := resp.Header.Get("Last-Modified"); modTime != "" {
@@ -464,11 +513,24 @@ func (hs *httpSourceHandler) Snapshot(ctx context.Context, g session.Group) (cac
 		}
 	}
 
+	if hs.src.Checksum != "" {
+		ref, err := hs.checksumRef(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ref != nil {
+			return ref, nil
+		}
+	}
+
 	req, err := http.NewRequest("GET", hs.src.URL, nil)
 	if err != nil {
 		return nil, err
 	}
 	req = req.WithContext(ctx)
+	if err := hs.setHeaders(ctx, g, req); err != nil {
+		return nil, err
+	}
 
 	client := hs.client(g)
 
@@ -479,6 +541,9 @@ func (hs *httpSourceHandler) Snapshot(ctx context.Context, g session.Group) (cac
 	defer func() {
 		_ = resp.Body.Close()
 	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return nil, errors.Errorf("invalid response status %d", resp.StatusCode)
+	}
 
 	ref, dgst, err := hs.save(ctx, resp, g)
 	if err != nil {
@@ -486,12 +551,37 @@ func (hs *httpSourceHandler) Snapshot(ctx context.Context, g session.Group) (cac
 	}
 	if dgst != hs.cacheKey {
 		ref.Release(context.TODO())
-		return nil, errors.Errorf("digest mismatch %s: %s", dgst, hs.cacheKey)
+		return nil, errors.Errorf("checksum mismatch for %s: expected %s, got %s", hs.src.URL, hs.cacheKey, dgst)
 	}
 
 	return ref, nil
 }
 
+// checksumRef returns a previous download of the URL with the expected
+// checksum, if there's one in the cache that was also saved with the same
+// filename and permissions.
+func (hs *httpSourceHandler) checksumRef(ctx context.Context) (cache.ImmutableRef, error) {
+	uh, err := hs.urlHash()
+	if err != nil {
+		return nil, err
+	}
+	fileKey := hs.formatCacheKey(getFileName(hs.src.URL, hs.src.Filename, nil), hs.src.Checksum, "")
+	mds, err := searchHTTPURLDigest(ctx, hs.cache, uh)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to search metadata for %s", uh)
+	}
+	for _, md := range mds {
+		if md.getHTTPChecksum() != hs.src.Checksum || md.getHTTPFileKey() != fileKey {
+			continue
+		}
+		ref, err := hs.cache.Get(ctx, md.ID(), nil)
+		if err == nil {
+			return ref, nil
+		}
+	}
+	return nil, nil
+}
+
 func getFileName(urlStr, manualFilename string, resp *http.Response) string {
 	if manualFilename != "" {
 		return manualFilename
@@ -535,6 +625,7 @@ type cacheRefMetadata struct {
 const keyHTTPChecksum = "http.checksum"
 const keyETag = "etag"
 const keyModTime = "http.modtime"
+const keyHTTPFileKey = "http.filekey"
 
 func (md cacheRefMetadata) getHTTPChecksum() digest.Digest {
 	return digest.Digest(md.GetString(keyHTTPChecksum))
@@ -560,6 +651,14 @@ func (md cacheRefMetadata) setHTTPModTime(s string) error {
 	return md.SetString(keyModTime, s, "")
 }
 
+func (md cacheRefMetadata) getHTTPFileKey() digest.Digest {
+	return digest.Digest(md.GetString(keyHTTPFileKey))
+}
+
+func (md cacheRefMetadata) setHTTPFileKey(d digest.Digest) error {
+	return md.SetString(keyHTTPFileKey, d.String(), "")
+}
+
 func etagValue(v string) string {
 	// remove weak for direct comparison
 	return strings.TrimPrefix(v, "W/")
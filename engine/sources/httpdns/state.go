@@ -10,12 +10,21 @@ import (
 
 const AttrNetConfig = "httpdns.netconfig"
 
+// Header is a header sent with the request, whose value is either given or
+// read from a secret. Only the secret's name is recorded, never its value.
+type Header struct {
+	Name   string `json:"name"`
+	Value  string `json:"value,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
 // State is a helper mimicking the llb.HTTP function, but with the ability to
-// set additional attributes.
-func State(url string, clientIDs []string, opts ...llb.HTTPOption) llb.State {
+// set additional attributes and to send headers.
+func State(url string, clientIDs []string, headers []Header, opts ...llb.HTTPOption) llb.State {
 	hack, err := buildkit.EncodeIDHack(DaggerHTTPURLHack{
 		URL:       url,
 		ClientIDs: clientIDs,
+		Headers:   headers,
 	})
 	if err != nil {
 		panic(err)
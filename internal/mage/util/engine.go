@@ -227,7 +227,7 @@ func devEngineContainer(c *dagger.Client, arch string, version string, opts ...D
 		WithExec([]string{
 			"apk", "add", "--no-cache",
 			// for Buildkit
-			"git", "openssh", "pigz", "xz",
+			"git", "git-lfs", "openssh", "pigz", "xz",
 			// for CNI
 			"iptables", "ip6tables", "dnsmasq",
 		}).
@@ -275,7 +275,7 @@ func devEngineContainerWithGPUSupport(c *dagger.Client, arch string, version str
 		WithExec([]string{"apt-get", "update"}).
 		WithExec([]string{
 			"apt-get", "install", "-y",
-			"iptables", "git", "dnsmasq-base", "network-manager",
+			"iptables", "git", "git-lfs", "dnsmasq-base", "network-manager",
 			"gpg", "curl",
 		}).
 		WithFile("/usr/local/bin/runc", runcBin(c, arch), dagger.ContainerWithFileOpts{
@@ -72,7 +72,7 @@ func Connect(ctx context.Context, opts ...ClientOpt) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	gql := errorWrappedClient{graphql.NewClient("http://"+conn.Host()+"/query", conn)}
+	gql := errorWrappedClient{graphql.NewClient("http://"+conn.Host()+"/query", conn), conn}
 
 	c := &Client{
 		c:    gql,
@@ -158,6 +158,10 @@ type Response struct {
 
 type errorWrappedClient struct {
 	graphql.Client
+
+	// conn is used for the requests made outside of GraphQL, like streaming
+	// file contents.
+	conn engineconn.EngineConn
 }
 
 func (c errorWrappedClient) MakeRequest(ctx context.Context, req *graphql.Request, resp *graphql.Response) error {
@@ -113,6 +113,9 @@ type JSON string
 // A reference to a Module.
 type ModuleID string
 
+// An OCI artifact identifier.
+type OCIArtifactID string
+
 // The platform config OS and architecture in a Container.
 //
 // The format is [os]/[platform]/[version] (e.g., "darwin/arm64/v7", "windows/amd64", "linux/arm64").
@@ -135,6 +138,15 @@ type TypeDefID string
 // A Null Void is used as a placeholder for resolvers that do not return anything.
 type Void string
 
+// An annotation of the manifest of an OCI artifact.
+type ArtifactAnnotation struct {
+	// Name of the annotation (e.g., "org.opencontainers.image.source").
+	Name string `json:"name"`
+
+	// Value of the annotation.
+	Value string `json:"value"`
+}
+
 // Key value object that represents a build argument.
 type BuildArg struct {
 	// The build argument name.
@@ -144,6 +156,25 @@ type BuildArg struct {
 	Value string `json:"value"`
 }
 
+// A header sent with an HTTP request.
+type HTTPHeader struct {
+	// The header's name.
+	Name string `json:"name"`
+
+	// A secret holding the header's value, instead of the value itself (e.g.,
+	// for an Authorization header).
+	Secret SecretID `json:"secret"`
+
+	// The header's value.
+	Value string `json:"value"`
+}
+
+// Keys to verify image signatures against.
+type ImageVerification struct {
+	// PEM-encoded public keys. The image must be signed by at least one of them.
+	PublicKeys []string `json:"publicKeys"`
+}
+
 // Key value object that represents a Pipeline label.
 type PipelineLabel struct {
 	// Label name.
@@ -498,6 +529,21 @@ func (r *Container) Export(ctx context.Context, path string, opts ...ContainerEx
 	return response, q.Execute(ctx, r.c)
 }
 
+// Loads the container's image into a Docker daemon, as `docker load` would.
+//
+// Return true on success.
+func (r *Container) ExportToDocker(ctx context.Context, tag string, socket *Socket) (bool, error) {
+	assertNotNil("socket", socket)
+	q := r.q.Select("exportToDocker")
+	q = q.Arg("tag", tag)
+	q = q.Arg("socket", socket)
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Retrieves the list of exposed ports.
 //
 // This includes ports already exposed by the image, even if not
@@ -548,9 +594,23 @@ func (r *Container) File(path string) *File {
 	}
 }
 
+// ContainerFromOpts contains options for Container.From
+type ContainerFromOpts struct {
+	// Verify that the image is signed by one of the given keys before pulling it.
+	//
+	// Signatures are looked up in the registry following the cosign layout.
+	Verify ImageVerification
+}
+
 // Initializes this container from a pulled base image.
-func (r *Container) From(address string) *Container {
+func (r *Container) From(address string, opts ...ContainerFromOpts) *Container {
 	q := r.q.Select("from")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `verify` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Verify) {
+			q = q.Arg("verify", opts[i].Verify)
+		}
+	}
 	q = q.Arg("address", address)
 
 	return &Container{
@@ -559,6 +619,20 @@ func (r *Container) From(address string) *Container {
 	}
 }
 
+// Initializes this container from an image in a Docker daemon, without going
+// through a registry.
+func (r *Container) FromDocker(tag string, socket *Socket) *Container {
+	assertNotNil("socket", socket)
+	q := r.q.Select("fromDocker")
+	q = q.Arg("tag", tag)
+	q = q.Arg("socket", socket)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // A unique identifier for this container.
 func (r *Container) ID(ctx context.Context) (ContainerID, error) {
 	if r.id != nil {
@@ -687,6 +761,41 @@ func (r *Container) Labels(ctx context.Context) ([]Label, error) {
 	return convert(response), nil
 }
 
+// Retrieves the layers of this container's root filesystem, as they would be
+// published. Mounts are not included.
+func (r *Container) Layers(ctx context.Context) ([]ContainerLayer, error) {
+	q := r.q.Select("layers")
+
+	q = q.Select("createdBy digest size")
+
+	type layers struct {
+		CreatedBy string
+		Digest    string
+		Size      int
+	}
+
+	convert := func(fields []layers) []ContainerLayer {
+		out := []ContainerLayer{}
+
+		for i := range fields {
+			val := ContainerLayer{createdBy: &fields[i].CreatedBy, digest: &fields[i].Digest, size: &fields[i].Size}
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []layers
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Retrieves the list of paths where a directory is mounted.
 func (r *Container) Mounts(ctx context.Context) ([]string, error) {
 	q := r.q.Select("mounts")
@@ -754,6 +863,11 @@ type ContainerPublishOpts struct {
 	// is largely compatible with most recent registries, but Docker may be needed for older
 	// registries without OCI support.
 	MediaTypes ImageMediaTypes
+	// Sign the published image with the given PEM-encoded private key.
+	//
+	// The signature is pushed next to the image following the cosign layout,
+	// so that it can be verified with `cosign verify`.
+	SignWith *Secret
 }
 
 // Publishes this container as a new image to the specified address.
@@ -778,6 +892,10 @@ func (r *Container) Publish(ctx context.Context, address string, opts ...Contain
 		if !querybuilder.IsZeroValue(opts[i].MediaTypes) {
 			q = q.Arg("mediaTypes", opts[i].MediaTypes)
 		}
+		// `signWith` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SignWith) {
+			q = q.Arg("signWith", opts[i].SignWith)
+		}
 	}
 	q = q.Arg("address", address)
 
@@ -887,6 +1005,41 @@ func (r *Container) WithDefaultArgs(opts ...ContainerWithDefaultArgsOpts) *Conta
 	}
 }
 
+// ContainerWithDirectoriesOpts contains options for Container.WithDirectories
+type ContainerWithDirectoriesOpts struct {
+	// What to do when more than one directory has a file at the same path.
+	Conflict MergeConflict
+	// A user:group to set for the directories and their contents.
+	//
+	// The user and group can either be an ID (1000:1000) or a name (foo:bar).
+	//
+	// If the group is omitted, it defaults to the same as the user.
+	Owner string
+}
+
+// Retrieves this container plus directories merged together and written at
+// the given path, as with mergeDirectories.
+func (r *Container) WithDirectories(path string, directories []*Directory, opts ...ContainerWithDirectoriesOpts) *Container {
+	q := r.q.Select("withDirectories")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `conflict` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Conflict) {
+			q = q.Arg("conflict", opts[i].Conflict)
+		}
+		// `owner` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Owner) {
+			q = q.Arg("owner", opts[i].Owner)
+		}
+	}
+	q = q.Arg("path", path)
+	q = q.Arg("directories", directories)
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // ContainerWithDirectoryOpts contains options for Container.WithDirectory
 type ContainerWithDirectoryOpts struct {
 	// Patterns to exclude in the written directory (e.g., ["node_modules/**", ".gitignore", ".git/"]).
@@ -1116,6 +1269,20 @@ func (r *Container) WithLabel(name string, value string) *Container {
 	}
 }
 
+// Retrieves this container with a layer boundary at its current state.
+//
+// All operations performed since the previous boundary are squashed into a
+// single layer, so that subsequent operations can be grouped by calling
+// withLayerBoundary again once they are done.
+func (r *Container) WithLayerBoundary() *Container {
+	q := r.q.Select("withLayerBoundary")
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // ContainerWithMountedCacheOpts contains options for Container.WithMountedCache
 type ContainerWithMountedCacheOpts struct {
 	// Identifier of the directory to use as the cache volume's root.
@@ -1335,6 +1502,29 @@ func (r *Container) WithRootfs(directory *Directory) *Container {
 	}
 }
 
+// ContainerWithSquashedLayersOpts contains options for Container.WithSquashedLayers
+type ContainerWithSquashedLayersOpts struct {
+	// Only squash the layers added on top of this container's root filesystem,
+	// keeping its own layers as-is.
+	From *Container
+}
+
+// Retrieves this container with its root filesystem squashed into a single layer.
+func (r *Container) WithSquashedLayers(opts ...ContainerWithSquashedLayersOpts) *Container {
+	q := r.q.Select("withSquashedLayers")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `from` optional argument
+		if !querybuilder.IsZeroValue(opts[i].From) {
+			q = q.Arg("from", opts[i].From)
+		}
+	}
+
+	return &Container{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves this container plus an env variable containing the given secret.
 func (r *Container) WithSecretVariable(name string, secret *Secret) *Container {
 	assertNotNil("secret", secret)
@@ -1523,14 +1713,65 @@ func (r *Container) Workdir(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// A layer of a container's root filesystem.
+type ContainerLayer struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	createdBy *string
+	digest    *string
+	size      *int
+}
+
+// The operation that created the layer.
+func (r *ContainerLayer) CreatedBy(ctx context.Context) (string, error) {
+	if r.createdBy != nil {
+		return *r.createdBy, nil
+	}
+	q := r.q.Select("createdBy")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The digest of the compressed layer blob.
+func (r *ContainerLayer) Digest(ctx context.Context) (string, error) {
+	if r.digest != nil {
+		return *r.digest, nil
+	}
+	q := r.q.Select("digest")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The size of the compressed layer blob, in bytes.
+func (r *ContainerLayer) Size(ctx context.Context) (int, error) {
+	if r.size != nil {
+		return *r.size, nil
+	}
+	q := r.q.Select("size")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // A directory.
 type Directory struct {
 	q *querybuilder.Selection
 	c graphql.Client
 
-	export *bool
-	id     *DirectoryID
-	sync   *DirectoryID
+	digest          *string
+	export          *bool
+	id              *DirectoryID
+	publishArtifact *string
+	sync            *DirectoryID
 }
 type WithDirectoryFunc func(r *Directory) *Directory
 
@@ -1541,6 +1782,66 @@ func (r *Directory) With(f WithDirectoryFunc) *Directory {
 	return f(r)
 }
 
+// DirectoryAsArchiveOpts contains options for Directory.AsArchive
+type DirectoryAsArchiveOpts struct {
+	// Sort the entries and reset their timestamps and ownership, so that the
+	// same contents always produce the same archive.
+	Reproducible bool
+}
+
+// Returns a file containing this directory archived in the given format.
+func (r *Directory) AsArchive(format ArchiveFormat, opts ...DirectoryAsArchiveOpts) *File {
+	q := r.q.Select("asArchive")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `reproducible` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Reproducible) {
+			q = q.Arg("reproducible", opts[i].Reproducible)
+		}
+	}
+	q = q.Arg("format", format)
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
+}
+
+// DirectoryAsGitCommitOpts contains options for Directory.AsGitCommit
+type DirectoryAsGitCommitOpts struct {
+	// Parent of the commit (e.g., the branch to push to). The commit has no
+	// parent by default.
+	Parent *GitRef
+	// Date of the commit, in RFC 3339 format (e.g., "2023-11-14T22:13:20Z").
+	// Defaults to the Unix epoch, so that the same files always make the same
+	// commit.
+	Date string
+}
+
+// Creates a git commit of this directory's files, to be pushed with
+// GitRef.push.
+//
+// Files ignored by the directory's .gitignore files aren't committed.
+func (r *Directory) AsGitCommit(message string, author string, opts ...DirectoryAsGitCommitOpts) *GitRef {
+	q := r.q.Select("asGitCommit")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `parent` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Parent) {
+			q = q.Arg("parent", opts[i].Parent)
+		}
+		// `date` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Date) {
+			q = q.Arg("date", opts[i].Date)
+		}
+	}
+	q = q.Arg("message", message)
+	q = q.Arg("author", author)
+
+	return &GitRef{
+		q: q,
+		c: r.c,
+	}
+}
+
 // DirectoryAsModuleOpts contains options for Directory.AsModule
 type DirectoryAsModuleOpts struct {
 	// An optional subpath of the directory which contains the module's source
@@ -1584,6 +1885,23 @@ func (r *Directory) Diff(other *Directory) *Directory {
 	}
 }
 
+// Returns a hash of the contents of this directory.
+//
+// It covers the names, contents, permissions and ownership of its files, but
+// not their timestamps nor how they were produced, so it only changes when the
+// contents do.
+func (r *Directory) Digest(ctx context.Context) (string, error) {
+	if r.digest != nil {
+		return *r.digest, nil
+	}
+	q := r.q.Select("digest")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Retrieves a directory at the given path.
 func (r *Directory) Directory(path string) *Directory {
 	q := r.q.Select("directory")
@@ -1649,6 +1967,8 @@ func (r *Directory) DockerBuild(opts ...DirectoryDockerBuildOpts) *Container {
 type DirectoryEntriesOpts struct {
 	// Location of the directory to look at (e.g., "/src").
 	Path string
+	// List the entries of every subdirectory too, relative to the given path.
+	Recursive bool
 }
 
 // Returns a list of files and directories at the given path.
@@ -1659,6 +1979,10 @@ func (r *Directory) Entries(ctx context.Context, opts ...DirectoryEntriesOpts) (
 		if !querybuilder.IsZeroValue(opts[i].Path) {
 			q = q.Arg("path", opts[i].Path)
 		}
+		// `recursive` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Recursive) {
+			q = q.Arg("recursive", opts[i].Recursive)
+		}
 	}
 
 	var response []string
@@ -1667,6 +1991,63 @@ func (r *Directory) Entries(ctx context.Context, opts ...DirectoryEntriesOpts) (
 	return response, q.Execute(ctx, r.c)
 }
 
+// DirectoryEntryInfosOpts contains options for Directory.EntryInfos
+type DirectoryEntryInfosOpts struct {
+	// Location of the directory to look at (e.g., "/src").
+	Path string
+	// List the entries of every subdirectory too, relative to the given path.
+	Recursive bool
+}
+
+// Returns the files and directories at the given path, along with their metadata.
+func (r *Directory) EntryInfos(ctx context.Context, opts ...DirectoryEntryInfosOpts) ([]FileInfo, error) {
+	q := r.q.Select("entryInfos")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `path` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Path) {
+			q = q.Arg("path", opts[i].Path)
+		}
+		// `recursive` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Recursive) {
+			q = q.Arg("recursive", opts[i].Recursive)
+		}
+	}
+
+	q = q.Select("gid kind modTime path permissions size symlinkTarget uid")
+
+	type entryInfos struct {
+		Gid           int
+		Kind          FileKind
+		ModTime       int
+		Path          string
+		Permissions   int
+		Size          int
+		SymlinkTarget string
+		Uid           int
+	}
+
+	convert := func(fields []entryInfos) []FileInfo {
+		out := []FileInfo{}
+
+		for i := range fields {
+			val := FileInfo{gid: &fields[i].Gid, kind: &fields[i].Kind, modTime: &fields[i].ModTime, path: &fields[i].Path, permissions: &fields[i].Permissions, size: &fields[i].Size, symlinkTarget: &fields[i].SymlinkTarget, uid: &fields[i].Uid}
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []entryInfos
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Writes the contents of the directory to a path on the host.
 func (r *Directory) Export(ctx context.Context, path string) (bool, error) {
 	if r.export != nil {
@@ -1772,53 +2153,160 @@ func (r *Directory) Pipeline(name string, opts ...DirectoryPipelineOpts) *Direct
 	}
 }
 
-// Force evaluation in the engine.
-func (r *Directory) Sync(ctx context.Context) (*Directory, error) {
-	q := r.q.Select("sync")
+// DirectoryPublishArtifactOpts contains options for Directory.PublishArtifact
+type DirectoryPublishArtifactOpts struct {
+	// Media type of the artifact's layers.
+	//
+	// Defaults to "application/vnd.oci.image.layer.v1.tar", like ORAS.
+	MediaType string
+	// Annotations of the artifact's manifest.
+	Annotations []ArtifactAnnotation
+}
 
-	return r, q.Execute(ctx, r.c)
+// Publishes the files of this directory as an OCI artifact, one layer per
+// file, titled with the file's path.
+//
+// Returns the artifact's address pinned to the digest of its manifest.
+func (r *Directory) PublishArtifact(ctx context.Context, address string, artifactType string, opts ...DirectoryPublishArtifactOpts) (string, error) {
+	if r.publishArtifact != nil {
+		return *r.publishArtifact, nil
+	}
+	q := r.q.Select("publishArtifact")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `mediaType` optional argument
+		if !querybuilder.IsZeroValue(opts[i].MediaType) {
+			q = q.Arg("mediaType", opts[i].MediaType)
+		}
+		// `annotations` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Annotations) {
+			q = q.Arg("annotations", opts[i].Annotations)
+		}
+	}
+	q = q.Arg("address", address)
+	q = q.Arg("artifactType", artifactType)
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
 }
 
-// DirectoryWithDirectoryOpts contains options for Directory.WithDirectory
-type DirectoryWithDirectoryOpts struct {
-	// Exclude artifacts that match the given pattern (e.g., ["node_modules/", ".git*"]).
-	Exclude []string
-	// Include only artifacts that match the given pattern (e.g., ["app/", "package.*"]).
+// DirectorySearchOpts contains options for Directory.Search
+type DirectorySearchOpts struct {
+	// Treat the pattern as an RE2 regular expression.
+	Regex bool
+	// Files and directories to search in (e.g., ["src", "README.md"]). Defaults to the whole directory.
+	Paths []string
+	// Patterns of the files to search (e.g., ["**/*.go"]).
 	Include []string
+	// Patterns of the files and directories to skip (e.g., ["vendor/", "**/*_test.go"]).
+	Exclude []string
 }
 
-// Retrieves this directory plus a directory written at the given path.
-func (r *Directory) WithDirectory(path string, directory *Directory, opts ...DirectoryWithDirectoryOpts) *Directory {
-	assertNotNil("directory", directory)
-	q := r.q.Select("withDirectory")
+// Searches the contents of the directory's files, like grep, and returns the
+// matching lines.
+//
+// Binary files are skipped. Files are read directly, without running a
+// container.
+func (r *Directory) Search(ctx context.Context, pattern string, opts ...DirectorySearchOpts) ([]SearchMatch, error) {
+	q := r.q.Select("search")
 	for i := len(opts) - 1; i >= 0; i-- {
-		// `exclude` optional argument
-		if !querybuilder.IsZeroValue(opts[i].Exclude) {
-			q = q.Arg("exclude", opts[i].Exclude)
+		// `regex` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Regex) {
+			q = q.Arg("regex", opts[i].Regex)
+		}
+		// `paths` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Paths) {
+			q = q.Arg("paths", opts[i].Paths)
 		}
 		// `include` optional argument
 		if !querybuilder.IsZeroValue(opts[i].Include) {
 			q = q.Arg("include", opts[i].Include)
 		}
+		// `exclude` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Exclude) {
+			q = q.Arg("exclude", opts[i].Exclude)
+		}
 	}
-	q = q.Arg("path", path)
-	q = q.Arg("directory", directory)
+	q = q.Arg("pattern", pattern)
 
-	return &Directory{
-		q: q,
-		c: r.c,
+	q = q.Select("line lineNumber path")
+
+	type search struct {
+		Line       string
+		LineNumber int
+		Path       string
 	}
-}
 
-// DirectoryWithFileOpts contains options for Directory.WithFile
-type DirectoryWithFileOpts struct {
-	// Permission given to the copied file (e.g., 0600).
-	//
-	// Default: 0644.
-	Permissions int
-}
+	convert := func(fields []search) []SearchMatch {
+		out := []SearchMatch{}
 
-// Retrieves this directory plus the contents of the given file copied to the given path.
+		for i := range fields {
+			val := SearchMatch{line: &fields[i].Line, lineNumber: &fields[i].LineNumber, path: &fields[i].Path}
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []search
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
+// Force evaluation in the engine.
+func (r *Directory) Sync(ctx context.Context) (*Directory, error) {
+	q := r.q.Select("sync")
+
+	return r, q.Execute(ctx, r.c)
+}
+
+// DirectoryWithDirectoryOpts contains options for Directory.WithDirectory
+type DirectoryWithDirectoryOpts struct {
+	// Exclude artifacts that match the given pattern (e.g., ["node_modules/", ".git*"]).
+	Exclude []string
+	// Include only artifacts that match the given pattern (e.g., ["app/", "package.*"]).
+	Include []string
+}
+
+// Retrieves this directory plus a directory written at the given path.
+func (r *Directory) WithDirectory(path string, directory *Directory, opts ...DirectoryWithDirectoryOpts) *Directory {
+	assertNotNil("directory", directory)
+	q := r.q.Select("withDirectory")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `exclude` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Exclude) {
+			q = q.Arg("exclude", opts[i].Exclude)
+		}
+		// `include` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Include) {
+			q = q.Arg("include", opts[i].Include)
+		}
+	}
+	q = q.Arg("path", path)
+	q = q.Arg("directory", directory)
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
+// DirectoryWithFileOpts contains options for Directory.WithFile
+type DirectoryWithFileOpts struct {
+	// Permission given to the copied file (e.g., 0600).
+	//
+	// Default: 0644.
+	Permissions int
+}
+
+// Retrieves this directory plus the contents of the given file copied to the given path.
 func (r *Directory) WithFile(path string, source *File, opts ...DirectoryWithFileOpts) *Directory {
 	assertNotNil("source", source)
 	q := r.q.Select("withFile")
@@ -1888,6 +2376,147 @@ func (r *Directory) WithNewFile(path string, contents string, opts ...DirectoryW
 	}
 }
 
+// DirectoryWithOwnerOpts contains options for Directory.WithOwner
+type DirectoryWithOwnerOpts struct {
+	// Change the ownership of everything under the directory too.
+	Recursive bool
+}
+
+// Retrieves this directory with the ownership of the given file or directory changed.
+func (r *Directory) WithOwner(uid int, gid int, path string, opts ...DirectoryWithOwnerOpts) *Directory {
+	q := r.q.Select("withOwner")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `recursive` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Recursive) {
+			q = q.Arg("recursive", opts[i].Recursive)
+		}
+	}
+	q = q.Arg("uid", uid)
+	q = q.Arg("gid", gid)
+	q = q.Arg("path", path)
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
+// DirectoryWithPatchOpts contains options for Directory.WithPatch
+type DirectoryWithPatchOpts struct {
+	// Contents of the patch.
+	Patch string
+	// File containing the patch, instead of its contents.
+	PatchFile *File
+	// Number of leading path components to remove from the file names of the
+	// patch, like "patch -p" (e.g., 1 for the "a/" and "b/" prefixes of "git diff").
+	Strip int
+}
+
+// Retrieves this directory with a unified diff applied, as produced by
+// "diff -u" or "git diff".
+//
+// Fails without applying anything if any hunk of the patch doesn't apply,
+// reporting every rejected hunk.
+func (r *Directory) WithPatch(opts ...DirectoryWithPatchOpts) *Directory {
+	q := r.q.Select("withPatch")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `patch` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Patch) {
+			q = q.Arg("patch", opts[i].Patch)
+		}
+		// `patchFile` optional argument
+		if !querybuilder.IsZeroValue(opts[i].PatchFile) {
+			q = q.Arg("patchFile", opts[i].PatchFile)
+		}
+		// `strip` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Strip) {
+			q = q.Arg("strip", opts[i].Strip)
+		}
+	}
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
+// DirectoryWithPermissionsOpts contains options for Directory.WithPermissions
+type DirectoryWithPermissionsOpts struct {
+	// Change the permissions of everything under the directory too.
+	Recursive bool
+}
+
+// Retrieves this directory with the permissions of the given file or directory changed.
+func (r *Directory) WithPermissions(path string, permissions int, opts ...DirectoryWithPermissionsOpts) *Directory {
+	q := r.q.Select("withPermissions")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `recursive` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Recursive) {
+			q = q.Arg("recursive", opts[i].Recursive)
+		}
+	}
+	q = q.Arg("path", path)
+	q = q.Arg("permissions", permissions)
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Retrieves this directory plus a symlink at the given path pointing to the given target.
+//
+// Anything already at that path is replaced.
+func (r *Directory) WithSymlink(target string, linkName string) *Directory {
+	q := r.q.Select("withSymlink")
+	q = q.Arg("target", target)
+	q = q.Arg("linkName", linkName)
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
+// DirectoryWithTemplatesOpts contains options for Directory.WithTemplates
+type DirectoryWithTemplatesOpts struct {
+	// Data to render the template with, available as "." (e.g., {"name": "app"}).
+	Data JSON
+	// Left and right delimiters of the template actions. Defaults to "{{" and "}}".
+	Delims []string
+	// Secrets the template can read by name (e.g., {{ secret "token" }}). The
+	// rendered result is kept out of IDs and logs when it uses them.
+	Secrets []*Secret
+}
+
+// Retrieves this directory with the files matching the given pattern rendered
+// in place as Go text/templates.
+//
+// Templates can use the same functions as in File.withTemplate.
+func (r *Directory) WithTemplates(pattern string, opts ...DirectoryWithTemplatesOpts) *Directory {
+	q := r.q.Select("withTemplates")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `data` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Data) {
+			q = q.Arg("data", opts[i].Data)
+		}
+		// `delims` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Delims) {
+			q = q.Arg("delims", opts[i].Delims)
+		}
+		// `secrets` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Secrets) {
+			q = q.Arg("secrets", opts[i].Secrets)
+		}
+	}
+	q = q.Arg("pattern", pattern)
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves this directory with all file/dir timestamps set to the given time.
 func (r *Directory) WithTimestamps(timestamp int) *Directory {
 	q := r.q.Select("withTimestamps")
@@ -2008,11 +2637,15 @@ type File struct {
 	q *querybuilder.Selection
 	c graphql.Client
 
-	contents *string
-	export   *bool
-	id       *FileID
-	size     *int
-	sync     *FileID
+	contents    *string
+	digest      *string
+	export      *bool
+	id          *FileID
+	name        *string
+	permissions *int
+	read        *string
+	size        *int
+	sync        *FileID
 }
 type WithFileFunc func(r *File) *File
 
@@ -2024,6 +2657,10 @@ func (r *File) With(f WithFileFunc) *File {
 }
 
 // Retrieves the contents of the file.
+//
+// Files larger than 128MB can't be retrieved this way; read them in chunks
+// with `read` instead, or download them from the `/file?id=<FileID>` endpoint
+// of the session.
 func (r *File) Contents(ctx context.Context) (string, error) {
 	if r.contents != nil {
 		return *r.contents, nil
@@ -2036,6 +2673,22 @@ func (r *File) Contents(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// Returns the SHA-256 digest of the contents of the file.
+//
+// It doesn't depend on how the file was produced, so it only changes when its
+// contents do.
+func (r *File) Digest(ctx context.Context) (string, error) {
+	if r.digest != nil {
+		return *r.digest, nil
+	}
+	q := r.q.Select("digest")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // FileExportOpts contains options for File.Export
 type FileExportOpts struct {
 	// If allowParentDirPath is true, the path argument can be a directory path, in which case
@@ -2103,6 +2756,67 @@ func (r *File) MarshalJSON() ([]byte, error) {
 	return json.Marshal(id)
 }
 
+// Retrieves the name of the file.
+func (r *File) Name(ctx context.Context) (string, error) {
+	if r.name != nil {
+		return *r.name, nil
+	}
+	q := r.q.Select("name")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Retrieves the Unix permission bits of the file (e.g., 0o644).
+func (r *File) Permissions(ctx context.Context) (int, error) {
+	if r.permissions != nil {
+		return *r.permissions, nil
+	}
+	q := r.q.Select("permissions")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// FileReadOpts contains options for File.Read
+type FileReadOpts struct {
+	// Position of the first byte to read.
+	Offset int
+	// Maximum number of bytes to read. Defaults to, and is capped at, around
+	// 4MB.
+	Length int
+}
+
+// Reads a chunk of the contents of the file, encoded in base64.
+//
+// Fewer bytes than requested are returned at the end of the file, and none
+// past it.
+func (r *File) Read(ctx context.Context, opts ...FileReadOpts) (string, error) {
+	if r.read != nil {
+		return *r.read, nil
+	}
+	q := r.q.Select("read")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `offset` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Offset) {
+			q = q.Arg("offset", opts[i].Offset)
+		}
+		// `length` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Length) {
+			q = q.Arg("length", opts[i].Length)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Gets the size of the file, in bytes.
 func (r *File) Size(ctx context.Context) (int, error) {
 	if r.size != nil {
@@ -2123,6 +2837,112 @@ func (r *File) Sync(ctx context.Context) (*File, error) {
 	return r, q.Execute(ctx, r.c)
 }
 
+// FileUnpackOpts contains options for File.Unpack
+type FileUnpackOpts struct {
+	// Format of the archive. Detected from its contents if not set.
+	Format ArchiveFormat
+}
+
+// Returns a directory containing the extracted contents of this archive.
+func (r *File) Unpack(opts ...FileUnpackOpts) *Directory {
+	q := r.q.Select("unpack")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `format` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Format) {
+			q = q.Arg("format", opts[i].Format)
+		}
+	}
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Retrieves this file with its permissions changed.
+func (r *File) WithPermissions(permissions int) *File {
+	q := r.q.Select("withPermissions")
+	q = q.Arg("permissions", permissions)
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
+}
+
+// FileWithReplacedOpts contains options for File.WithReplaced
+type FileWithReplacedOpts struct {
+	// Replace every occurrence instead of only the first one.
+	All bool
+	// Treat search as a regular expression (RE2 syntax).
+	Regex bool
+}
+
+// Retrieves this file with occurrences of a string replaced.
+//
+// Fails if the string doesn't occur in the file.
+func (r *File) WithReplaced(search string, replace string, opts ...FileWithReplacedOpts) *File {
+	q := r.q.Select("withReplaced")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `all` optional argument
+		if !querybuilder.IsZeroValue(opts[i].All) {
+			q = q.Arg("all", opts[i].All)
+		}
+		// `regex` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Regex) {
+			q = q.Arg("regex", opts[i].Regex)
+		}
+	}
+	q = q.Arg("search", search)
+	q = q.Arg("replace", replace)
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
+}
+
+// FileWithTemplateOpts contains options for File.WithTemplate
+type FileWithTemplateOpts struct {
+	// Data to render the template with, available as "." (e.g., {"name": "app"}).
+	Data JSON
+	// Left and right delimiters of the template actions. Defaults to "{{" and "}}".
+	Delims []string
+	// Secrets the template can read by name (e.g., {{ secret "token" }}). The
+	// rendered result is kept out of IDs and logs when it uses them.
+	Secrets []*Secret
+}
+
+// Retrieves this file rendered as a Go text/template.
+//
+// On top of the text/template builtins, templates can use a safe subset of
+// functions: default, required, upper, lower, trim, trimPrefix, trimSuffix,
+// replace, contains, hasPrefix, hasSuffix, split, join, quote, indent,
+// nindent, b64enc, b64dec, toJson, toYaml and secret. Referring to missing
+// data is an error.
+func (r *File) WithTemplate(opts ...FileWithTemplateOpts) *File {
+	q := r.q.Select("withTemplate")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `data` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Data) {
+			q = q.Arg("data", opts[i].Data)
+		}
+		// `delims` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Delims) {
+			q = q.Arg("delims", opts[i].Delims)
+		}
+		// `secrets` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Secrets) {
+			q = q.Arg("secrets", opts[i].Secrets)
+		}
+	}
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves this file with its created/modified timestamps set to the given time.
 func (r *File) WithTimestamps(timestamp int) *File {
 	q := r.q.Select("withTimestamps")
@@ -2134,6 +2954,125 @@ func (r *File) WithTimestamps(timestamp int) *File {
 	}
 }
 
+// Metadata of a directory entry.
+type FileInfo struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	gid           *int
+	kind          *FileKind
+	modTime       *int
+	path          *string
+	permissions   *int
+	size          *int
+	symlinkTarget *string
+	uid           *int
+}
+
+// Group ID owning the entry.
+func (r *FileInfo) Gid(ctx context.Context) (int, error) {
+	if r.gid != nil {
+		return *r.gid, nil
+	}
+	q := r.q.Select("gid")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Kind of the entry.
+func (r *FileInfo) Kind(ctx context.Context) (FileKind, error) {
+	if r.kind != nil {
+		return *r.kind, nil
+	}
+	q := r.q.Select("kind")
+
+	var response FileKind
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Modification time of the entry, in seconds following Unix epoch.
+func (r *FileInfo) ModTime(ctx context.Context) (int, error) {
+	if r.modTime != nil {
+		return *r.modTime, nil
+	}
+	q := r.q.Select("modTime")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Path of the entry, relative to the listed directory.
+func (r *FileInfo) Path(ctx context.Context) (string, error) {
+	if r.path != nil {
+		return *r.path, nil
+	}
+	q := r.q.Select("path")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Unix permission bits of the entry (e.g., 0o644).
+func (r *FileInfo) Permissions(ctx context.Context) (int, error) {
+	if r.permissions != nil {
+		return *r.permissions, nil
+	}
+	q := r.q.Select("permissions")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Size of the entry, in bytes.
+func (r *FileInfo) Size(ctx context.Context) (int, error) {
+	if r.size != nil {
+		return *r.size, nil
+	}
+	q := r.q.Select("size")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Target of the entry if it is a symlink.
+func (r *FileInfo) SymlinkTarget(ctx context.Context) (string, error) {
+	if r.symlinkTarget != nil {
+		return *r.symlinkTarget, nil
+	}
+	q := r.q.Select("symlinkTarget")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// User ID owning the entry.
+func (r *FileInfo) Uid(ctx context.Context) (int, error) {
+	if r.uid != nil {
+		return *r.uid, nil
+	}
+	q := r.q.Select("uid")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Function represents a resolver provided by a Module.
 //
 // A function always evaluates against a parent object and is given a set of
@@ -2649,6 +3588,97 @@ func (r *GeneratedCode) WithVCSIgnoredPaths(paths []string) *GeneratedCode {
 	}
 }
 
+// A commit listed by GitRef.log.
+type GitLogEntry struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	authorEmail *string
+	authorName  *string
+	commit      *string
+	date        *string
+	message     *string
+	subject     *string
+}
+
+// The email of the commit's author.
+func (r *GitLogEntry) AuthorEmail(ctx context.Context) (string, error) {
+	if r.authorEmail != nil {
+		return *r.authorEmail, nil
+	}
+	q := r.q.Select("authorEmail")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The name of the commit's author.
+func (r *GitLogEntry) AuthorName(ctx context.Context) (string, error) {
+	if r.authorName != nil {
+		return *r.authorName, nil
+	}
+	q := r.q.Select("authorName")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The commit's hash.
+func (r *GitLogEntry) Commit(ctx context.Context) (string, error) {
+	if r.commit != nil {
+		return *r.commit, nil
+	}
+	q := r.q.Select("commit")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The commit's time, in RFC 3339 format (e.g., "2023-11-14T22:13:20Z").
+func (r *GitLogEntry) Date(ctx context.Context) (string, error) {
+	if r.date != nil {
+		return *r.date, nil
+	}
+	q := r.q.Select("date")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The commit's whole message.
+func (r *GitLogEntry) Message(ctx context.Context) (string, error) {
+	if r.message != nil {
+		return *r.message, nil
+	}
+	q := r.q.Select("message")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The first line of the commit's message.
+func (r *GitLogEntry) Subject(ctx context.Context) (string, error) {
+	if r.subject != nil {
+		return *r.subject, nil
+	}
+	q := r.q.Select("subject")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // A git ref (tag, branch or commit).
 type GitRef struct {
 	q *querybuilder.Selection
@@ -2656,6 +3686,7 @@ type GitRef struct {
 
 	commit *string
 	id     *GitRefID
+	push   *string
 }
 
 // The resolved commit id at this ref.
@@ -2711,11 +3742,140 @@ func (r *GitRef) MarshalJSON() ([]byte, error) {
 	return json.Marshal(id)
 }
 
+// GitRefLogOpts contains options for GitRef.Log
+type GitRefLogOpts struct {
+	// Exclude the commits reachable from this ref (e.g., the previous release's
+	// tag), like "git log <since>..<ref>".
+	Since string
+}
+
+// Lists the commits reachable from this ref, newest first.
+func (r *GitRef) Log(ctx context.Context, opts ...GitRefLogOpts) ([]GitLogEntry, error) {
+	q := r.q.Select("log")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `since` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Since) {
+			q = q.Arg("since", opts[i].Since)
+		}
+	}
+
+	q = q.Select("authorEmail authorName commit date message subject")
+
+	type log struct {
+		AuthorEmail string
+		AuthorName  string
+		Commit      string
+		Date        string
+		Message     string
+		Subject     string
+	}
+
+	convert := func(fields []log) []GitLogEntry {
+		out := []GitLogEntry{}
+
+		for i := range fields {
+			val := GitLogEntry{authorEmail: &fields[i].AuthorEmail, authorName: &fields[i].AuthorName, commit: &fields[i].Commit, date: &fields[i].Date, message: &fields[i].Message, subject: &fields[i].Subject}
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []log
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
+// GitRefPushOpts contains options for GitRef.Push
+type GitRefPushOpts struct {
+	// Overwrite the branch even if it isn't an ancestor of the commit.
+	Force bool
+	// Secret used as a token to authenticate over HTTPS.
+	HTTPAuthToken *Secret
+	// Secret used as the whole Authorization header to authenticate over HTTPS.
+	HTTPAuthHeader *Secret
+	// SSH agent socket used to authenticate over SSH.
+	SSHAuthSocket *Socket
+	// Known hosts to check the remote against over SSH.
+	SSHKnownHosts string
+	// A service which must be started before the remote is pushed to.
+	ExperimentalServiceHost *Service
+}
+
+// Pushes the commit at this ref to a branch of a remote repository,
+// returning the commit's hash.
+func (r *GitRef) Push(ctx context.Context, remote string, branch string, opts ...GitRefPushOpts) (string, error) {
+	if r.push != nil {
+		return *r.push, nil
+	}
+	q := r.q.Select("push")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `force` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Force) {
+			q = q.Arg("force", opts[i].Force)
+		}
+		// `httpAuthToken` optional argument
+		if !querybuilder.IsZeroValue(opts[i].HTTPAuthToken) {
+			q = q.Arg("httpAuthToken", opts[i].HTTPAuthToken)
+		}
+		// `httpAuthHeader` optional argument
+		if !querybuilder.IsZeroValue(opts[i].HTTPAuthHeader) {
+			q = q.Arg("httpAuthHeader", opts[i].HTTPAuthHeader)
+		}
+		// `sshAuthSocket` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SSHAuthSocket) {
+			q = q.Arg("sshAuthSocket", opts[i].SSHAuthSocket)
+		}
+		// `sshKnownHosts` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SSHKnownHosts) {
+			q = q.Arg("sshKnownHosts", opts[i].SSHKnownHosts)
+		}
+		// `experimentalServiceHost` optional argument
+		if !querybuilder.IsZeroValue(opts[i].ExperimentalServiceHost) {
+			q = q.Arg("experimentalServiceHost", opts[i].ExperimentalServiceHost)
+		}
+	}
+	q = q.Arg("remote", remote)
+	q = q.Arg("branch", branch)
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The names of the tags pointing to the commit at this ref.
+func (r *GitRef) TagsPointingAt(ctx context.Context) ([]string, error) {
+	q := r.q.Select("tagsPointingAt")
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // GitRefTreeOpts contains options for GitRef.Tree
 type GitRefTreeOpts struct {
 	SSHKnownHosts string
 
 	SSHAuthSocket *Socket
+	// Number of commits of history to fetch into the .git directory, when it's
+	// kept. A negative depth fetches the whole history.
+	Depth int
+	// Only check out these paths, relative to the root of the repository
+	// (e.g., ["services/api", "go.mod"]).
+	SparsePaths []string
+	// Check out the repository's submodules.
+	Submodules bool
+	// Download the content of the files stored with Git LFS instead of leaving
+	// their pointers.
+	LFS bool
 }
 
 // The filesystem tree at this ref.
@@ -2730,6 +3890,22 @@ func (r *GitRef) Tree(opts ...GitRefTreeOpts) *Directory {
 		if !querybuilder.IsZeroValue(opts[i].SSHAuthSocket) {
 			q = q.Arg("sshAuthSocket", opts[i].SSHAuthSocket)
 		}
+		// `depth` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Depth) {
+			q = q.Arg("depth", opts[i].Depth)
+		}
+		// `sparsePaths` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SparsePaths) {
+			q = q.Arg("sparsePaths", opts[i].SparsePaths)
+		}
+		// `submodules` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Submodules) {
+			q = q.Arg("submodules", opts[i].Submodules)
+		}
+		// `lfs` optional argument
+		if !querybuilder.IsZeroValue(opts[i].LFS) {
+			q = q.Arg("lfs", opts[i].LFS)
+		}
 	}
 
 	return &Directory{
@@ -2757,6 +3933,28 @@ func (r *GitRepository) Branch(name string) *GitRef {
 	}
 }
 
+// GitRepositoryBranchesOpts contains options for GitRepository.Branches
+type GitRepositoryBranchesOpts struct {
+	// Only list the branches matching one of these glob patterns (e.g., "release/*").
+	Patterns []string
+}
+
+// Lists the names of the repository's branches.
+func (r *GitRepository) Branches(ctx context.Context, opts ...GitRepositoryBranchesOpts) ([]string, error) {
+	q := r.q.Select("branches")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `patterns` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Patterns) {
+			q = q.Arg("patterns", opts[i].Patterns)
+		}
+	}
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Returns details on one commit.
 func (r *GitRepository) Commit(id string) *GitRef {
 	q := r.q.Select("commit")
@@ -2768,6 +3966,16 @@ func (r *GitRepository) Commit(id string) *GitRef {
 	}
 }
 
+// Returns details on the default branch, which HEAD points to.
+func (r *GitRepository) Head() *GitRef {
+	q := r.q.Select("head")
+
+	return &GitRef{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Retrieves the content-addressed identifier of the git repository.
 func (r *GitRepository) ID(ctx context.Context) (GitRepositoryID, error) {
 	if r.id != nil {
@@ -2819,6 +4027,28 @@ func (r *GitRepository) Tag(name string) *GitRef {
 	}
 }
 
+// GitRepositoryTagsOpts contains options for GitRepository.Tags
+type GitRepositoryTagsOpts struct {
+	// Only list the tags matching one of these glob patterns (e.g., "v*").
+	Patterns []string
+}
+
+// Lists the names of the repository's tags.
+func (r *GitRepository) Tags(ctx context.Context, opts ...GitRepositoryTagsOpts) ([]string, error) {
+	q := r.q.Select("tags")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `patterns` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Patterns) {
+			q = q.Arg("patterns", opts[i].Patterns)
+		}
+	}
+
+	var response []string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Information about the host execution environment.
 type Host struct {
 	q *querybuilder.Selection
@@ -2831,6 +4061,16 @@ type HostDirectoryOpts struct {
 	Exclude []string
 	// Include only artifacts that match the given pattern (e.g., ["app/", "package.*"]).
 	Include []string
+	// Exclude artifacts ignored by git, along with the .git directory: by the
+	// .gitignore files of the directory, of its subdirectories and of its parents
+	// up to the root of its repository, and by the repository's .git/info/exclude.
+	//
+	// Evaluated on the host, so ignored artifacts are never uploaded.
+	Gitignore bool
+	// Exclude artifacts ignored by the .dockerignore file of the directory.
+	//
+	// Evaluated on the host, so ignored artifacts are never uploaded.
+	Dockerignore bool
 }
 
 // Accesses a directory on the host.
@@ -2845,6 +4085,14 @@ func (r *Host) Directory(path string, opts ...HostDirectoryOpts) *Directory {
 		if !querybuilder.IsZeroValue(opts[i].Include) {
 			q = q.Arg("include", opts[i].Include)
 		}
+		// `gitignore` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Gitignore) {
+			q = q.Arg("gitignore", opts[i].Gitignore)
+		}
+		// `dockerignore` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Dockerignore) {
+			q = q.Arg("dockerignore", opts[i].Dockerignore)
+		}
 	}
 	q = q.Arg("path", path)
 
@@ -3322,6 +4570,94 @@ func (r *ModuleConfig) SDK(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// An artifact stored in an OCI registry, whose layers are files.
+type OCIArtifact struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	address *string
+	id      *OCIArtifactID
+}
+
+// The fully qualified address of the artifact.
+func (r *OCIArtifact) Address(ctx context.Context) (string, error) {
+	if r.address != nil {
+		return *r.address, nil
+	}
+	q := r.q.Select("address")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// OCIArtifactFilesOpts contains options for OCIArtifact.Files
+type OCIArtifactFilesOpts struct {
+	// Only retrieve the files of the layers with these media types (e.g.,
+	// ["application/vnd.cncf.helm.chart.content.v1.tar+gzip"]).
+	MediaTypes []string
+}
+
+// Retrieves the files of the artifact's layers, named after their
+// "org.opencontainers.image.title" annotation.
+//
+// Layers without a title are skipped.
+func (r *OCIArtifact) Files(opts ...OCIArtifactFilesOpts) *Directory {
+	q := r.q.Select("files")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `mediaTypes` optional argument
+		if !querybuilder.IsZeroValue(opts[i].MediaTypes) {
+			q = q.Arg("mediaTypes", opts[i].MediaTypes)
+		}
+	}
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Retrieves the content-addressed identifier of the artifact.
+func (r *OCIArtifact) ID(ctx context.Context) (OCIArtifactID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.q.Select("id")
+
+	var response OCIArtifactID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *OCIArtifact) XXX_GraphQLType() string {
+	return "OCIArtifact"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *OCIArtifact) XXX_GraphQLIDType() string {
+	return "OCIArtifactID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *OCIArtifact) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *OCIArtifact) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
 // A definition of a custom object defined in a Module.
 type ObjectTypeDef struct {
 	q *querybuilder.Selection
@@ -3644,6 +4980,12 @@ type GitOpts struct {
 	SSHKnownHosts string
 	// Set SSH auth socket
 	SSHAuthSocket *Socket
+	// Secret holding a token to authenticate with over HTTPS (e.g., a GitHub
+	// personal access token).
+	HTTPAuthToken *Secret
+	// Secret holding a whole Authorization header to authenticate with over HTTPS
+	// (e.g., "Bearer <token>").
+	HTTPAuthHeader *Secret
 	// A service which must be started before the repo is fetched.
 	ExperimentalServiceHost *Service
 }
@@ -3664,6 +5006,14 @@ func (r *Client) Git(url string, opts ...GitOpts) *GitRepository {
 		if !querybuilder.IsZeroValue(opts[i].SSHAuthSocket) {
 			q = q.Arg("sshAuthSocket", opts[i].SSHAuthSocket)
 		}
+		// `httpAuthToken` optional argument
+		if !querybuilder.IsZeroValue(opts[i].HTTPAuthToken) {
+			q = q.Arg("httpAuthToken", opts[i].HTTPAuthToken)
+		}
+		// `httpAuthHeader` optional argument
+		if !querybuilder.IsZeroValue(opts[i].HTTPAuthHeader) {
+			q = q.Arg("httpAuthHeader", opts[i].HTTPAuthHeader)
+		}
 		// `experimentalServiceHost` optional argument
 		if !querybuilder.IsZeroValue(opts[i].ExperimentalServiceHost) {
 			q = q.Arg("experimentalServiceHost", opts[i].ExperimentalServiceHost)
@@ -3689,6 +5039,16 @@ func (r *Client) Host() *Host {
 
 // HTTPOpts contains options for Client.HTTP
 type HTTPOpts struct {
+	// Expected checksum of the content (e.g., "sha256:..."). The download fails
+	// if it doesn't match, and a previous download with this checksum is reused
+	// without any request.
+	Checksum string
+	// Headers to send with the request (e.g., to authenticate).
+	Headers []HTTPHeader
+	// Name of the downloaded file. Defaults to a name derived from the URL.
+	Filename string
+	// Permissions of the downloaded file (e.g., 0755). Defaults to 0600.
+	Permissions int
 	// A service which must be started before the URL is fetched.
 	ExperimentalServiceHost *Service
 }
@@ -3697,6 +5057,22 @@ type HTTPOpts struct {
 func (r *Client) HTTP(url string, opts ...HTTPOpts) *File {
 	q := r.q.Select("http")
 	for i := len(opts) - 1; i >= 0; i-- {
+		// `checksum` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Checksum) {
+			q = q.Arg("checksum", opts[i].Checksum)
+		}
+		// `headers` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Headers) {
+			q = q.Arg("headers", opts[i].Headers)
+		}
+		// `filename` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Filename) {
+			q = q.Arg("filename", opts[i].Filename)
+		}
+		// `permissions` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Permissions) {
+			q = q.Arg("permissions", opts[i].Permissions)
+		}
 		// `experimentalServiceHost` optional argument
 		if !querybuilder.IsZeroValue(opts[i].ExperimentalServiceHost) {
 			q = q.Arg("experimentalServiceHost", opts[i].ExperimentalServiceHost)
@@ -3809,6 +5185,34 @@ func (r *Client) LoadGitRepositoryFromID(id GitRepositoryID) *GitRepository {
 	}
 }
 
+// LoadLockfileOpts contains options for Client.LoadLockfile
+type LoadLockfileOpts struct {
+	// Fail to resolve references that are not pinned in the lockfile instead of pinning them.
+	Strict bool
+}
+
+// Loads a lockfile (dagger.lock) pinning container image tags and git refs to
+// the digests and commits they resolved to.
+//
+// For the rest of the session, Container.from, GitRepository.branch and
+// GitRepository.tag resolve references through the lockfile, recording pins
+// for the ones it doesn't have yet.
+func (r *Client) LoadLockfile(ctx context.Context, contents string, opts ...LoadLockfileOpts) (Void, error) {
+	q := r.q.Select("loadLockfile")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `strict` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Strict) {
+			q = q.Arg("strict", opts[i].Strict)
+		}
+	}
+	q = q.Arg("contents", contents)
+
+	var response Void
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // Load a module by ID.
 func (r *Client) LoadModuleFromID(id ModuleID) *Module {
 	q := r.q.Select("loadModuleFromID")
@@ -3820,6 +5224,17 @@ func (r *Client) LoadModuleFromID(id ModuleID) *Module {
 	}
 }
 
+// Load an OCI artifact from its ID.
+func (r *Client) LoadOCIArtifactFromID(id OCIArtifactID) *OCIArtifact {
+	q := r.q.Select("loadOCIArtifactFromID")
+	q = q.Arg("id", id)
+
+	return &OCIArtifact{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Load a Secret from its ID.
 func (r *Client) LoadSecretFromID(id SecretID) *Secret {
 	q := r.q.Select("loadSecretFromID")
@@ -3864,6 +5279,54 @@ func (r *Client) LoadTypeDefFromID(id TypeDefID) *TypeDef {
 	}
 }
 
+// LockfileOpts contains options for Client.Lockfile
+type LockfileOpts struct {
+	// Re-resolve every pinned reference to its current digest or commit first.
+	Update bool
+}
+
+// Retrieves the contents of the lockfile loaded in this session, including any pin recorded since.
+func (r *Client) Lockfile(ctx context.Context, opts ...LockfileOpts) (string, error) {
+	q := r.q.Select("lockfile")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `update` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Update) {
+			q = q.Arg("update", opts[i].Update)
+		}
+	}
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// MergeDirectoriesOpts contains options for Client.MergeDirectories
+type MergeDirectoriesOpts struct {
+	// What to do when more than one directory has a file at the same path.
+	Conflict MergeConflict
+}
+
+// Merges directories into a new one, in a single operation.
+//
+// This is cheaper than chaining Directory.withDirectory calls: the inputs are
+// merged lazily, without being copied, and cached independently.
+func (r *Client) MergeDirectories(directories []*Directory, opts ...MergeDirectoriesOpts) *Directory {
+	q := r.q.Select("mergeDirectories")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `conflict` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Conflict) {
+			q = q.Arg("conflict", opts[i].Conflict)
+		}
+	}
+	q = q.Arg("directories", directories)
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Create a new module.
 func (r *Client) Module() *Module {
 	q := r.q.Select("module")
@@ -3897,6 +5360,18 @@ func (r *Client) ModuleConfig(sourceDirectory *Directory, opts ...ModuleConfigOp
 	}
 }
 
+// Queries an artifact stored in an OCI registry, whose layers are files
+// (e.g., a Helm chart or a WASM module pushed with ORAS).
+func (r *Client) OCIArtifact(address string) *OCIArtifact {
+	q := r.q.Select("ociArtifact")
+	q = q.Arg("address", address)
+
+	return &OCIArtifact{
+		q: q,
+		c: r.c,
+	}
+}
+
 // PipelineOpts contains options for Client.Pipeline
 type PipelineOpts struct {
 	// Pipeline description.
@@ -3926,6 +5401,41 @@ func (r *Client) Pipeline(name string, opts ...PipelineOpts) *Client {
 	}
 }
 
+// The registry mirrors configured in the engine and for this session.
+func (r *Client) RegistryMirrors(ctx context.Context) ([]RegistryMirror, error) {
+	q := r.q.Select("registryMirrors")
+
+	q = q.Select("engine insecure mirror registry")
+
+	type registryMirrors struct {
+		Engine   bool
+		Insecure bool
+		Mirror   string
+		Registry string
+	}
+
+	convert := func(fields []registryMirrors) []RegistryMirror {
+		out := []RegistryMirror{}
+
+		for i := range fields {
+			val := RegistryMirror{engine: &fields[i].Engine, insecure: &fields[i].Insecure, mirror: &fields[i].Mirror, registry: &fields[i].Registry}
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []registryMirrors
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Loads a secret from its ID.
 //
 // Deprecated: Use LoadSecretFromID instead
@@ -3985,6 +5495,154 @@ func (r *Client) TypeDef() *TypeDef {
 	}
 }
 
+// WithRegistryMirrorOpts contains options for Client.WithRegistryMirror
+type WithRegistryMirrorOpts struct {
+	// Skip verifying the mirror's TLS certificate, and fall back to plain HTTP.
+	Insecure bool
+	// PEM-encoded certificates of authorities to trust when connecting to the mirror, in addition to the system ones.
+	CaCerts string
+}
+
+// Configures a mirror to pull container images from instead of the given
+// registry, for the rest of the session.
+//
+// Mirrors are tried in the order they are added, before the ones configured in
+// the engine, falling back to the registry itself. They only apply to the
+// images pulled by Container.from in this session: neither other sessions nor
+// Dockerfile builds use them.
+func (r *Client) WithRegistryMirror(registry string, mirror string, opts ...WithRegistryMirrorOpts) *Client {
+	q := r.q.Select("withRegistryMirror")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `insecure` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Insecure) {
+			q = q.Arg("insecure", opts[i].Insecure)
+		}
+		// `caCerts` optional argument
+		if !querybuilder.IsZeroValue(opts[i].CaCerts) {
+			q = q.Arg("caCerts", opts[i].CaCerts)
+		}
+	}
+	q = q.Arg("registry", registry)
+	q = q.Arg("mirror", mirror)
+
+	return &Client{
+		q: q,
+		c: r.c,
+	}
+}
+
+// A mirror to pull container images from instead of the registry they are addressed to.
+type RegistryMirror struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	engine   *bool
+	insecure *bool
+	mirror   *string
+	registry *string
+}
+
+// Whether the mirror is configured in the engine, as opposed to for this session.
+func (r *RegistryMirror) Engine(ctx context.Context) (bool, error) {
+	if r.engine != nil {
+		return *r.engine, nil
+	}
+	q := r.q.Select("engine")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Whether the mirror's TLS certificate is not verified.
+func (r *RegistryMirror) Insecure(ctx context.Context) (bool, error) {
+	if r.insecure != nil {
+		return *r.insecure, nil
+	}
+	q := r.q.Select("insecure")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The address of the mirror.
+func (r *RegistryMirror) Mirror(ctx context.Context) (string, error) {
+	if r.mirror != nil {
+		return *r.mirror, nil
+	}
+	q := r.q.Select("mirror")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The mirrored registry.
+func (r *RegistryMirror) Registry(ctx context.Context) (string, error) {
+	if r.registry != nil {
+		return *r.registry, nil
+	}
+	q := r.q.Select("registry")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// A line of a file matching Directory.search.
+type SearchMatch struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	line       *string
+	lineNumber *int
+	path       *string
+}
+
+// Contents of the matching line, without its line ending.
+func (r *SearchMatch) Line(ctx context.Context) (string, error) {
+	if r.line != nil {
+		return *r.line, nil
+	}
+	q := r.q.Select("line")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Number of the matching line in the file, starting at 1.
+func (r *SearchMatch) LineNumber(ctx context.Context) (int, error) {
+	if r.lineNumber != nil {
+		return *r.lineNumber, nil
+	}
+	q := r.q.Select("lineNumber")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Path of the file, relative to the searched directory.
+func (r *SearchMatch) Path(ctx context.Context) (string, error) {
+	if r.path != nil {
+		return *r.path, nil
+	}
+	q := r.q.Select("path")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // A reference to a secret value, which can be handled more safely than the value itself.
 type Secret struct {
 	q *querybuilder.Selection
@@ -4457,6 +6115,24 @@ func (r *TypeDef) WithOptional(optional bool) *TypeDef {
 	}
 }
 
+type ArchiveFormat string
+
+func (ArchiveFormat) IsEnum() {}
+
+const (
+	// Uncompressed tarball
+	Tar ArchiveFormat = "TAR"
+
+	// Zstandard-compressed tarball
+	TarZstd ArchiveFormat = "TAR_ZSTD"
+
+	// Gzip-compressed tarball
+	Tgz ArchiveFormat = "TGZ"
+
+	// Zip archive
+	Zip ArchiveFormat = "ZIP"
+)
+
 type CacheSharingMode string
 
 func (CacheSharingMode) IsEnum() {}
@@ -4473,6 +6149,24 @@ const (
 	Shared CacheSharingMode = "SHARED"
 )
 
+type FileKind string
+
+func (FileKind) IsEnum() {}
+
+const (
+	// Directory
+	EntryDirectory FileKind = "ENTRY_DIRECTORY"
+
+	// Regular file
+	EntryFile FileKind = "ENTRY_FILE"
+
+	// Any other kind of entry, such as a device or a named pipe
+	EntryOther FileKind = "ENTRY_OTHER"
+
+	// Symbolic link
+	EntrySymlink FileKind = "ENTRY_SYMLINK"
+)
+
 type ImageLayerCompression string
 
 func (ImageLayerCompression) IsEnum() {}
@@ -4497,6 +6191,18 @@ const (
 	Ocimediatypes ImageMediaTypes = "OCIMediaTypes"
 )
 
+type MergeConflict string
+
+func (MergeConflict) IsEnum() {}
+
+const (
+	// Fail, listing the conflicting paths. The directories are evaluated to detect conflicts.
+	MergeError MergeConflict = "MERGE_ERROR"
+
+	// Keep the file of the last directory that has it.
+	MergeLastWins MergeConflict = "MERGE_LAST_WINS"
+)
+
 type NetworkProtocol string
 
 func (NetworkProtocol) IsEnum() {}
@@ -0,0 +1,46 @@
+package dagger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Reader returns a reader streaming the contents of the file from the
+// engine, so that files of any size can be read without loading them in
+// memory, unlike with Contents.
+//
+// The reader must be closed once done.
+func (r *File) Reader(ctx context.Context) (io.ReadCloser, error) {
+	c, ok := r.c.(errorWrappedClient)
+	if !ok {
+		return nil, fmt.Errorf("streaming file contents is not supported by this client")
+	}
+
+	id, err := r.ID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ID is sent in the body, since it can be too large for a URL
+	form := url.Values{"id": {string(id)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+c.conn.Host()+"/file", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.conn.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("read file: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}